@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/report"
+)
+
+// join remonta um backup dividido com -volume-size ("dbbackup join
+// [-o destino] <arquivo>.volumes.json"), validando o SHA-256 de cada volume, e
+// retorna o código de saída do processo.
+func join(args []string, stderr io.Writer) int {
+	fset := flag.NewFlagSet("join", flag.ContinueOnError)
+	fset.SetOutput(stderr)
+	output := fset.String("o", "", "Arquivo remontado (padrão: nome do arquivo original, no diretório do manifesto)")
+	fset.Usage = func() {
+		fmt.Fprintln(stderr, "Uso: dbbackup join [-o destino] <arquivo>"+archive.VolumeManifestSuffix)
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return report.ExitConfig
+	}
+	if fset.NArg() != 1 || !archive.IsVolumeManifest(fset.Arg(0)) {
+		fset.Usage()
+		return report.ExitConfig
+	}
+	manifestPath := fset.Arg(0)
+
+	set, err := archive.ReadVolumeManifest(manifestPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Erro: %v\n", err)
+		return report.ExitFailure
+	}
+	dst := *output
+	if dst == "" {
+		dst = filepath.Join(filepath.Dir(manifestPath), filepath.Base(set.Archive))
+	}
+	// JoinVolumes sobrescreveria o destino, que pode ser um backup existente
+	if _, err := os.Stat(dst); !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(stderr, "Erro: o destino %s já existe\n", dst)
+		return report.ExitFailure
+	}
+
+	if err := archive.JoinVolumes(manifestPath, dst); err != nil {
+		fmt.Fprintf(stderr, "Erro ao remontar %s: %v\n", manifestPath, err)
+		return report.ExitFailure
+	}
+	fmt.Fprintf(stderr, "%d volumes remontados em %s (%d bytes)\n", len(set.Volumes), dst, set.TotalSize)
+	return report.ExitOK
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeVolumes divide content em volumes de 4 bytes e retorna o manifesto.
+func writeVolumes(t *testing.T, dir string, content []byte) string {
+	t.Helper()
+	vw, err := archive.NewVolumeWriter(dir, "SCM_20250407_164500.zip", 4)
+	require.NoError(t, err)
+	_, err = vw.Write(content)
+	require.NoError(t, err)
+	set, err := vw.Close()
+	require.NoError(t, err)
+	manifestPath, err := archive.WriteVolumeManifest(dir, set)
+	require.NoError(t, err)
+	return manifestPath
+}
+
+func TestJoin(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("conteúdo do backup dividido")
+	manifestPath := writeVolumes(t, dir, content)
+
+	var stderr bytes.Buffer
+	require.Equal(t, report.ExitOK, join([]string{manifestPath}, &stderr), stderr.String())
+	got, err := os.ReadFile(filepath.Join(dir, "SCM_20250407_164500.zip"))
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+
+	// Não sobrescreve um arquivo existente
+	stderr.Reset()
+	assert.Equal(t, report.ExitFailure, join([]string{manifestPath}, &stderr))
+	assert.Contains(t, stderr.String(), "já existe")
+
+	dst := filepath.Join(t.TempDir(), "SCM.zip")
+	require.Equal(t, report.ExitOK, join([]string{"-o", dst, manifestPath}, &stderr))
+	got, err = os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestJoin_Corrupted(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := writeVolumes(t, dir, []byte("abcdefgh"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "SCM_20250407_164500.zip.002"), []byte("xxxx"), 0640))
+
+	var stderr bytes.Buffer
+	assert.Equal(t, report.ExitFailure, join([]string{manifestPath}, &stderr))
+	assert.Contains(t, stderr.String(), "checksum")
+	assert.NoFileExists(t, filepath.Join(dir, "SCM_20250407_164500.zip"))
+}
+
+func TestJoin_Usage(t *testing.T) {
+	var stderr bytes.Buffer
+	assert.Equal(t, report.ExitConfig, join(nil, &stderr))
+	assert.Equal(t, report.ExitConfig, join([]string{"SCM.zip.001"}, &stderr))
+	assert.Contains(t, stderr.String(), "Uso: dbbackup join")
+}
+
+func TestJoin_UnsafeManifest(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "SCM.zip.volumes.json")
+	require.NoError(t, os.WriteFile(manifestPath, []byte(`{"archive":"SCM.zip","volumes":[{"name":"../../etc/passwd","size":1}]}`), 0640))
+
+	var stderr bytes.Buffer
+	assert.Equal(t, report.ExitFailure, join([]string{manifestPath}, &stderr))
+	assert.Contains(t, stderr.String(), "nome de volume inválido")
+	assert.NoFileExists(t, filepath.Join(dir, "SCM.zip"))
+}
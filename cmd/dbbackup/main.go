@@ -1,32 +1,52 @@
 package main
 
 import (
-	"archive/zip"
-	"database/sql"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"log/slog"
+	"net/url"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/backup"
 	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/config"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/gdrive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/hooks"
 	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/logger"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/report"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/version"
 	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/whatsapp"
-	_ "github.com/denisenkom/go-mssqldb" // Driver SQL Server (import anônimo)
+	_ "github.com/microsoft/go-mssqldb" // Driver SQL Server (import anônimo)
 )
 
+// notifyFunc envia um aviso sobre o backup de database.
+type notifyFunc func(database, msg string)
+
 func main() {
+	// "dbbackup join <arquivo>.volumes.json" não usa os flags do backup
+	if len(os.Args) > 1 && os.Args[1] == "join" {
+		os.Exit(join(os.Args[2:], os.Stderr))
+	}
+
 	cfg, err := config.NewDBBackupConfig()
 	if err != nil {
 		log.Printf("Erro ao carregar a configuração: %v", err)
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(report.ExitConfig)
 	}
 
-	flag.Parse()
+	// "dbbackup serve [flags]" executa os backups agendados em -schedule-file
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "serve" {
+		cfg.Serve = true
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(args)
 
 	l, logFile, err := logger.Setup(cfg.LogDir, cfg.LogLevel)
 	if err != nil {
@@ -37,161 +57,108 @@ func main() {
 	// --- Validação Simples dos Flags ---
 	config.ValidateBackupFlags(cfg)
 
+	// A senha (e sua forma escapada na connection string) nunca deve aparecer nos
+	// logs, nas notificações nem no relatório
+	redactor := newRedactor(cfg)
+	l = logger.WithRedactor(l, redactor)
+
 	// --- Inicializar WhatsApp Client ---
 	whatsappClient, err := whatsapp.ConfigWhatsappApi()
 	if err != nil {
 		l.Error("Erro ao configurar cliente WhatsApp", slog.Any("error", err))
 		// Não saímos aqui pois o backup ainda pode funcionar sem WhatsApp
 	}
-
-	// --- Construção da Connection String ---
-	connString := fmt.Sprintf("sqlserver://%s:%s@%s?database=%s", cfg.User, cfg.Password, cfg.Server, cfg.Database)
-
-	fmt.Println(connString)
-
-	// --- Conectar ao Banco ---
-	l.Info("Conectando ao servidor SQL Server...", slog.String("server", cfg.Server))
-	db, err := sql.Open("sqlserver", connString)
-	if err != nil {
-		l.Error("Erro ao preparar conexão", slog.Any("error", err))
+	notify := func(database, msg string) {
 		if whatsappClient != nil {
-			whatsappClient.Send("Admin", cfg.Database, time.Now().Format("02/01/2006 15:04:05"), fmt.Sprintf("Erro ao preparar conexão: %v", err))
+			whatsappClient.Send("Admin", database, time.Now().Format("02/01/2006 15:04:05"), redactor.Redact(msg))
 		}
-		os.Exit(1)
 	}
-	defer db.Close()
 
-	// Verifica a conexão
-	err = db.Ping()
-	if err != nil {
-		l.Error("Erro ao conectar ao banco de dados", slog.Any("error", err))
-		if whatsappClient != nil {
-			whatsappClient.Send("Admin", cfg.Database, time.Now().Format("02/01/2006 15:04:05"), fmt.Sprintf("Erro ao conectar ao banco de dados: %v", err))
+	// SIGINT/SIGTERM cancelam a fase em andamento, encerram o backup no servidor
+	// e removem os arquivos parciais
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// No modo -stream o arquivo final vai direto para o Google Drive
+	var upload backup.Uploader
+	if cfg.Stream {
+		drive, err := gdrive.NewDriveUploader(ctx, l, cfg.CredentialsFile, cfg.TokenFile)
+		if err != nil {
+			l.Error("Falha ao inicializar o Google Drive para o modo -stream", slog.Any("error", err))
+			os.Exit(report.ExitConfig)
 		}
-		os.Exit(1)
+		upload = drive
 	}
-	l.Info("Conexão estabelecida com sucesso.")
-
-	// --- Preparar Comando de Backup ---
-	timestamp := time.Now().Format("20060102_150405") // Formato YYYYMMDD_HHMMSS
-	bakFilename := fmt.Sprintf("%s_%s.bak", cfg.Database, timestamp)
-	// IMPORTANTE: Este path é no *servidor SQL Server*
-	bakFilePathOnServer := filepath.Join(cfg.BackupDir, bakFilename)
-	// Substitui barras para o formato Windows, caso Join use barra normal
-	bakFilePathOnServer = filepath.ToSlash(bakFilePathOnServer)
-	// "BACKUP DATABASE [SCM] TO DISK = '%backup_dir%\SCM_%data_completa%_%horario%.bak'"
-	backupSQL := fmt.Sprintf("BACKUP DATABASE [%s] TO DISK = '%s\\%s_%s.bak'",
-		cfg.Database, cfg.BackupDir, cfg.Database, timestamp)
-
-	l.Info("Backup SQL", slog.String("sql", backupSQL))
-
-	l.Info("Preparando para executar backup",
-		slog.String("database", cfg.Database),
-		slog.String("backup_path_on_server", bakFilePathOnServer))
-	l.Debug("Comando SQL de Backup", slog.String("sql", backupSQL))
-
-	// --- Executar Backup ---
-	_, err = db.Exec(backupSQL)
-	if err != nil {
-		l.Error("Erro ao executar o comando de backup", slog.Any("error", err))
-		if whatsappClient != nil {
-			whatsappClient.Send("Admin", cfg.Database, time.Now().Format("02/01/2006 15:04:05"), fmt.Sprintf("Erro ao executar o comando de backup: %v", err))
-		}
-		os.Exit(1)
-	}
-	l.Info("Comando de backup executado com sucesso no servidor.")
-
-	// --- Preparar Arquivo Zip ---
-	finalZipFilename := fmt.Sprintf("%s_%s.zip", cfg.Database, timestamp)
-	tempZipFilename := fmt.Sprintf("%s_%s.tmp", cfg.Database, timestamp) // Nome temporário
-	finalZipPathLocal := filepath.Join(cfg.ZipDir, finalZipFilename)
-	tempZipPathLocal := filepath.Join(cfg.ZipDir, tempZipFilename) // Caminho temporário
 
-	l.Info("Criando arquivo zip temporário", slog.String("path", tempZipPathLocal))
-	zipFile, err := os.Create(tempZipPathLocal) // Cria com nome .tmp
-	if err != nil {
-		l.Error("Erro ao criar arquivo zip temporário", slog.String("path", tempZipPathLocal), slog.Any("error", err))
-		if whatsappClient != nil {
-			whatsappClient.Send("Admin", cfg.Database, time.Now().Format("02/01/2006 15:04:05"), fmt.Sprintf("Erro ao criar arquivo zip temporário: %v", err))
-		}
-		os.Exit(1)
+	if cfg.Serve {
+		os.Exit(serve(ctx, cfg, l, notify, upload))
 	}
-	// Defer o fechamento ANTES do rename e ANTES do zipWriter.Close()
-	defer zipFile.Close()
 
-	zipWriter := zip.NewWriter(zipFile)
-	// Defer o fechamento do Writer ANTES do rename
-	// defer zipWriter.Close() // Movido para depois da cópia
-
-	// --- Abrir o Arquivo .bak (Acessando o path do servidor) ---
-	l.Info("Abrindo arquivo de backup do servidor", slog.String("path", bakFilePathOnServer))
-	bakFile, err := os.Open(bakFilePathOnServer)
-	if err != nil {
-		l.Error("Erro ao abrir arquivo .bak. Verifique o caminho e as permissões.",
-			slog.String("path", bakFilePathOnServer),
-			slog.Any("error", err))
-		if whatsappClient != nil {
-			whatsappClient.Send("Admin", cfg.Database, time.Now().Format("02/01/2006 15:04:05"), fmt.Sprintf("Erro ao abrir arquivo .bak: %v", err))
-		}
-		os.Exit(1)
+	l.Info("Iniciando backup", slog.String("server", cfg.Server), slog.String("auth", cfg.Auth), slog.String("encrypt", cfg.Encrypt))
+	job := newJob(cfg, l, notify, upload, cfg.Database, cfg.BackupType, cfg.Source)
+	_, err = job.Run(ctx)
+	if err != nil && !errors.Is(err, backup.ErrSkipped) {
+		notify(cfg.Database, fmt.Sprintf("Erro no backup: %v", err))
 	}
-	defer bakFile.Close()
-
-	// --- Criar Entrada no Zip e Copiar Dados ---
-	l.Info("Adicionando arquivo ao zip", slog.String("filename_in_zip", bakFilename))
-	zipEntryWriter, err := zipWriter.Create(bakFilename)
+	writeReport(l, cfg.ReportFile, job.Report)
 	if err != nil {
-		l.Error("Erro ao criar entrada no zip", slog.Any("error", err))
-		if whatsappClient != nil {
-			whatsappClient.Send("Admin", cfg.Database, time.Now().Format("02/01/2006 15:04:05"), fmt.Sprintf("Erro ao criar entrada no zip: %v", err))
-		}
-		os.Exit(1)
+		os.Exit(job.Report.ExitCode)
 	}
+}
 
-	l.Info("Copiando dados do backup para o arquivo zip...")
-	bytesCopied, err := io.Copy(zipEntryWriter, bakFile)
-	if err != nil {
-		l.Error("Erro ao copiar dados do .bak para o .zip", slog.Any("error", err))
-		if whatsappClient != nil {
-			whatsappClient.Send("Admin", cfg.Database, time.Now().Format("02/01/2006 15:04:05"), fmt.Sprintf("Erro ao copiar dados do .bak para o .zip: %v", err))
-		}
-		os.Exit(1)
+// newJob monta o backup de database a partir dos flags. source é a origem dos
+// engines sqlite e files; upload, o destino do modo -stream (nil sem ele).
+func newJob(cfg *config.DbBackupConfig, l *slog.Logger, notify notifyFunc, upload backup.Uploader, database, backupType, source string) *backup.Job {
+	opts := cfg.BackupOptions()
+	opts.Database = database
+	opts.BackupType = backupType
+	connOpts := cfg.ConnOptions()
+	connOpts.Database = database
+
+	rep := report.New(version.Version, cfg.Server, database)
+	rep.Redact = newRedactor(cfg).Redact
+
+	job := &backup.Job{
+		Options: opts,
+		Connect: func(ctx context.Context) (backup.Executor, error) {
+			return backup.OpenSQL(connOpts)
+		},
+		Hooks:  &hooks.Runner{Hooks: cfg.Hooks(), Timeout: cfg.HookTimeout, Fatal: cfg.HookFatal, Logger: l},
+		Logger: l,
+		Report: rep,
+		Notify: func(msg string) { notify(database, msg) },
+		Upload: upload,
 	}
-	l.Info("Dados copiados para o arquivo zip", slog.Int64("bytes_copied", bytesCopied))
-
-	// --- Fechar o Zip Writer (IMPORTANTE: Fecha antes de renomear) ---
-	l.Debug("Fechando zip writer...")
-	err = zipWriter.Close() // Fecha explicitamente para garantir que tudo foi escrito
-	if err != nil {
-		l.Error("Erro ao fechar o zip writer", slog.String("path", tempZipPathLocal), slog.Any("error", err))
-		// Tenta remover o arquivo temporário incompleto
-		_ = os.Remove(tempZipPathLocal)
-		if whatsappClient != nil {
-			whatsappClient.Send("Admin", cfg.Database, time.Now().Format("02/01/2006 15:04:05"), fmt.Sprintf("Erro ao finalizar arquivo zip: %v", err))
-		}
-		os.Exit(1)
+	switch cfg.Engine {
+	case backup.EnginePostgres:
+		pgOpts := cfg.PostgresOptions()
+		pgOpts.Conn.Database = database
+		job.Engine = backup.NewPostgres(pgOpts, opts, l)
+	case backup.EngineMySQL:
+		myOpts := cfg.MySQLOptions()
+		myOpts.Conn.Database = database
+		job.Engine = backup.NewMySQL(myOpts, opts, l)
+	case backup.EngineSQLite:
+		job.Engine = backup.NewSQLite(cfg.SQLiteOptions(source), opts, l)
+	case backup.EngineFiles:
+		job.Engine = backup.NewFiles(cfg.FilesOptions(source), opts, l)
 	}
-	l.Debug("Zip writer fechado.")
+	return job
+}
 
-	// --- Fechar o arquivo .tmp (opcional aqui, mas boa prática) ---
-	// O defer zipFile.Close() já faz isso, mas fechar explicitamente antes do rename pode ser mais claro
-	zipFile.Close()
+// newRedactor mascara a senha de cfg e suas formas escapadas na connection string.
+func newRedactor(cfg *config.DbBackupConfig) *logger.Redactor {
+	return logger.NewRedactor(cfg.Password, url.PathEscape(cfg.Password), url.QueryEscape(cfg.Password))
+}
 
-	// --- Renomear o Arquivo Temporário para Final ---
-	l.Info("Renomeando arquivo temporário para final", slog.String("from", tempZipPathLocal), slog.String("to", finalZipPathLocal))
-	err = os.Rename(tempZipPathLocal, finalZipPathLocal)
-	if err != nil {
-		l.Error("Erro ao renomear arquivo .tmp para .zip", slog.String("from", tempZipPathLocal), slog.String("to", finalZipPathLocal), slog.Any("error", err))
-		// Tenta remover o arquivo temporário se a renomeação falhar
-		_ = os.Remove(tempZipPathLocal)
-		if whatsappClient != nil {
-			whatsappClient.Send("Admin", cfg.Database, time.Now().Format("02/01/2006 15:04:05"), fmt.Sprintf("Erro ao renomear arquivo zip final: %v", err))
-		}
-		os.Exit(1)
+// writeReport grava o relatório da execução, se -report-file foi informado.
+func writeReport(l *slog.Logger, path string, r *report.Report) {
+	if path == "" {
+		return
 	}
-
-	// --- Finalização ---
-	l.Info("Backup concluído e zipado com sucesso",
-		slog.String("database", cfg.Database),
-		slog.String("zip_file", finalZipPathLocal)) // Loga o nome final
+	if err := r.Write(path, os.Stdout); err != nil {
+		l.Error("Erro ao gravar relatório da execução", slog.String("path", path), slog.Any("error", err))
+		return
+	}
+	l.Info("Relatório da execução gravado", slog.String("path", path))
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/backup"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/config"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/report"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/scheduler"
+)
+
+// serve executa os agendamentos de -schedule-file até receber SIGINT/SIGTERM
+// e retorna o código de saída do processo.
+func serve(ctx context.Context, cfg *config.DbBackupConfig, l *slog.Logger, notify notifyFunc, upload backup.Uploader) int {
+	state, err := scheduler.LoadState(cfg.StateFile)
+	if err != nil {
+		l.Error("Erro ao carregar estado do agendador", slog.Any("error", err))
+		return report.ExitConfig
+	}
+
+	board := newStatusBoard()
+	if cfg.StatusAddr != "" {
+		go serveStatus(ctx, l, cfg.StatusAddr, board)
+	}
+
+	s := &scheduler.Scheduler{
+		Entries: cfg.Schedules,
+		State:   state,
+		CatchUp: cfg.CatchUp,
+		Jitter:  cfg.Jitter,
+		Logger:  l,
+		Job: func(ctx context.Context, e scheduler.Entry) error {
+			jl := l.With(slog.String("schedule", e.Name))
+			job := newJob(cfg, jl, notify, upload, e.Database, e.Type, cmp.Or(e.Source, cfg.Source))
+			progress, done := board.track(e)
+			defer done()
+			job.Progress = progress
+			_, err := job.Run(ctx)
+			writeReport(jl, jobReportPath(cfg.ReportFile, e.Name), job.Report)
+			if errors.Is(err, backup.ErrSkipped) {
+				return nil
+			}
+			// Backups interrompidos pelo encerramento do serviço não são notificados
+			if err != nil && ctx.Err() == nil {
+				notify(e.Database, fmt.Sprintf("Erro no backup agendado %s: %v", e.Name, err))
+			}
+			return err
+		},
+	}
+
+	l.Info("Iniciando dbbackup em modo serviço",
+		slog.String("server", cfg.Server),
+		slog.Int("schedules", len(cfg.Schedules)),
+		slog.String("state_file", cfg.StateFile))
+	if err := s.Run(ctx); err != nil {
+		l.Error("Erro no agendador", slog.Any("error", err))
+		return report.ExitFailure
+	}
+	l.Info("dbbackup encerrado")
+	return report.ExitOK
+}
+
+// jobReportPath retorna o arquivo do relatório do agendamento name: o nome é
+// inserido antes da extensão de path (ex: run.json -> run.SCM-full.json), para
+// que backups simultâneos não gravem no mesmo arquivo. "-" e vazio não mudam.
+func jobReportPath(path, name string) string {
+	if path == "" || path == "-" {
+		return path
+	}
+	name = strings.NewReplacer("/", "_", `\`, "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_").Replace(name)
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + name + ext
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobReportPath(t *testing.T) {
+	dir := filepath.Join("relatorios", "dbbackup")
+	assert.Equal(t, filepath.Join(dir, "run.SCM-full.json"), jobReportPath(filepath.Join(dir, "run.json"), "SCM-full"))
+	assert.Equal(t, filepath.Join(dir, "run.SCM-log"), jobReportPath(filepath.Join(dir, "run"), "SCM-log"))
+	assert.Equal(t, "run.a_b_c_d.json", jobReportPath("run.json", `a/b\c:d`))
+	assert.Equal(t, "-", jobReportPath("-", "SCM-full"))
+	assert.Empty(t, jobReportPath("", "SCM-full"))
+}
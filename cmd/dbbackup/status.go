@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mssql"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/scheduler"
+)
+
+// statusBoard guarda os backups agendados em andamento e o progresso de cada
+// um, exposto em /status com -status-addr.
+type statusBoard struct {
+	started time.Time
+
+	mu      sync.Mutex
+	running map[string]*runningJob // Por nome do agendamento
+}
+
+type runningJob struct {
+	entry    scheduler.Entry
+	started  time.Time
+	progress *mssql.ProgressTracker
+}
+
+// jobStatus é um backup em andamento na resposta de /status.
+type jobStatus struct {
+	Schedule  string          `json:"schedule"`
+	Database  string          `json:"database"`
+	Type      string          `json:"type"`
+	StartedAt time.Time       `json:"started_at"`
+	Progress  *progressStatus `json:"progress,omitempty"` // Apenas durante o comando BACKUP do SQL Server
+}
+
+type progressStatus struct {
+	SessionID        int       `json:"session_id"`
+	PercentComplete  float64   `json:"percent_complete"`
+	ElapsedSeconds   int64     `json:"elapsed_seconds"`
+	RemainingSeconds int64     `json:"estimated_remaining_seconds"`
+	LastMessage      string    `json:"last_message,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func newStatusBoard() *statusBoard {
+	return &statusBoard{started: time.Now(), running: make(map[string]*runningJob)}
+}
+
+// track registra o início do backup de e e retorna o ProgressTracker do job e
+// a função que o remove ao final.
+func (b *statusBoard) track(e scheduler.Entry) (*mssql.ProgressTracker, func()) {
+	job := &runningJob{entry: e, started: time.Now(), progress: &mssql.ProgressTracker{}}
+	b.mu.Lock()
+	b.running[e.Name] = job
+	b.mu.Unlock()
+	return job.progress, func() {
+		b.mu.Lock()
+		delete(b.running, e.Name)
+		b.mu.Unlock()
+	}
+}
+
+// jobs retorna os backups em andamento, ordenados pelo início.
+func (b *statusBoard) jobs() []jobStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	jobs := make([]jobStatus, 0, len(b.running))
+	for _, j := range b.running {
+		st := jobStatus{Schedule: j.entry.Name, Database: j.entry.Database, Type: j.entry.Type, StartedAt: j.started}
+		if p, active := j.progress.Snapshot(); active {
+			st.Progress = &progressStatus{
+				SessionID:        p.SessionID,
+				PercentComplete:  p.PercentComplete,
+				ElapsedSeconds:   int64(p.Elapsed.Seconds()),
+				RemainingSeconds: int64(p.EstimatedRemaining.Seconds()),
+				LastMessage:      p.LastMessage,
+				UpdatedAt:        p.UpdatedAt,
+			}
+		}
+		jobs = append(jobs, st)
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		if !jobs[i].StartedAt.Equal(jobs[k].StartedAt) {
+			return jobs[i].StartedAt.Before(jobs[k].StartedAt)
+		}
+		return jobs[i].Schedule < jobs[k].Schedule
+	})
+	return jobs
+}
+
+// ServeHTTP responde GET /status com os backups em andamento em JSON.
+func (b *statusBoard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/status" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		StartedAt time.Time   `json:"started_at"`
+		Running   []jobStatus `json:"running"`
+	}{b.started, b.jobs()})
+}
+
+// serveStatus atende /status em addr até ctx ser cancelado. Falhas do
+// servidor HTTP são registradas, mas não interrompem os backups.
+func serveStatus(ctx context.Context, l *slog.Logger, addr string, board *statusBoard) {
+	srv := &http.Server{Addr: addr, Handler: board, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	l.Info("Status dos backups disponível", slog.String("url", "http://"+addr+"/status"))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		l.Error("Erro no servidor de status", slog.String("addr", addr), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getStatus(t *testing.T, board *statusBoard) (running []map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	board.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var body struct {
+		Running []map[string]any `json:"running"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return body.Running
+}
+
+func TestStatusBoard(t *testing.T) {
+	board := newStatusBoard()
+	assert.Empty(t, getStatus(t, board))
+
+	progress, done := board.track(scheduler.Entry{Name: "scm-full", Database: "SCM", Type: "full"})
+	require.NotNil(t, progress)
+	_, doneLog := board.track(scheduler.Entry{Name: "app-log", Database: "APP", Type: "log"})
+
+	running := map[string]map[string]any{}
+	for _, j := range getStatus(t, board) {
+		running[j["schedule"].(string)] = j
+	}
+	require.Len(t, running, 2)
+	assert.Equal(t, "SCM", running["scm-full"]["database"])
+	assert.Equal(t, "full", running["scm-full"]["type"])
+	assert.NotContains(t, running["scm-full"], "progress", "sem comando BACKUP em andamento")
+	assert.Equal(t, "log", running["app-log"]["type"])
+
+	done()
+	doneLog()
+	assert.Empty(t, getStatus(t, board))
+}
+
+func TestStatusBoard_Routes(t *testing.T) {
+	board := newStatusBoard()
+
+	rec := httptest.NewRecorder()
+	board.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	board.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -72,8 +72,36 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Destinos por subdiretório: outra pasta e/ou outra conta do Google Drive
+	for i := range cfg.Routes {
+		r := &cfg.Routes[i]
+		routeUploader := uploader
+		if r.CredentialsFile != "" {
+			routeUploader, err = gdrive.NewDriveUploader(ctx, l, r.CredentialsFile, r.TokenFile)
+			if err != nil {
+				l.Error("Falha ao inicializar Google Drive Uploader da rota", slog.String("route", r.String()), slog.Any("error", err))
+				os.Exit(1)
+			}
+		}
+		if r.Folder != "" {
+			routeUploader, err = routeUploader.InFolder(ctx, r.Folder)
+			if err != nil {
+				l.Error("Falha ao preparar a pasta da rota no Google Drive", slog.String("route", r.String()), slog.String("folder", r.Folder), slog.Any("error", err))
+				os.Exit(1)
+			}
+		}
+		r.Uploader = routeUploader
+		l.Info("Rota configurada", slog.String("route", r.String()), slog.String("folder", r.Folder), slog.Bool("own_account", r.CredentialsFile != ""))
+	}
+
 	// Setup e Run Folder Watcher
-	folderWatcher := watcher.NewFolderWatcher(l, uploader, cfg.WatchDir)
+	folderWatcher := watcher.NewFolderWatcher(l, uploader, cfg.WatchDir, watcher.Options{
+		Mode:         cfg.WatchMode,
+		PollInterval: cfg.PollInterval,
+		Recursive:    cfg.Recursive,
+		Routes:       cfg.Routes,
+		Filter:       cfg.Filter,
+	})
 
 	// Executa o watcher. Ele bloqueará até o contexto ser cancelado.
 	if err := folderWatcher.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
@@ -0,0 +1,251 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+)
+
+// Format identifica o formato de compressão usado no arquivo final do backup.
+type Format string
+
+const (
+	FormatZip  Format = "zip"
+	FormatZstd Format = "zstd"
+	FormatGzip Format = "gzip"
+	FormatNone Format = "none"
+)
+
+// DefaultLevel indica que o nível padrão de cada formato deve ser usado.
+const DefaultLevel = -1
+
+// Extensions lista as extensões de arquivos finais produzidos pelos formatos suportados.
+var Extensions = []string{".zip", ".zst", ".gz", ".tar", ".bak"}
+
+// Options configura a compressão do arquivo final.
+type Options struct {
+	Format      Format
+	Level       int // DefaultLevel usa o nível padrão do formato
+	Concurrency int // Número de goroutines de compressão (0 = número de CPUs)
+}
+
+// ParseFormat converte o valor do flag -compression em um Format válido.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatZip, FormatZstd, FormatGzip, FormatNone:
+		return f, nil
+	default:
+		return "", fmt.Errorf("formato de compressão inválido '%s' (use zip, zstd, gzip ou none)", s)
+	}
+}
+
+// Validate verifica se o nível de compressão é aceito pelo formato escolhido.
+func (o Options) Validate() error {
+	if o.Concurrency < 0 {
+		return fmt.Errorf("número de threads de compressão inválido: %d", o.Concurrency)
+	}
+	if o.Level == DefaultLevel {
+		return nil
+	}
+	switch o.Format {
+	case FormatZip, FormatGzip:
+		if o.Level < 0 || o.Level > 9 {
+			return fmt.Errorf("nível de compressão %d inválido para %s (use 0 a 9)", o.Level, o.Format)
+		}
+	case FormatZstd:
+		if o.Level < 1 || o.Level > 22 {
+			return fmt.Errorf("nível de compressão %d inválido para zstd (use 1 a 22)", o.Level)
+		}
+	}
+	return nil
+}
+
+// FileName retorna o nome do arquivo final. base é o prefixo sem extensão
+// (ex: DB_20250101_120000) e entries os nomes das entradas que serão gravadas.
+// Formatos de fluxo único com uma entrada mantêm o nome dela (ex: DB.bak.zst);
+// com várias entradas, ou uma entrada em subdiretório, as agrupam em um tar
+// (ex: DB.tar.zst).
+func (o Options) FileName(base string, entries []string) string {
+	if o.Format == FormatZip || o.Format == "" {
+		return base + ".zip"
+	}
+	name := base + ".tar"
+	if len(entries) == 1 && !inDir(entries[0]) {
+		name = entries[0]
+	}
+	switch o.Format {
+	case FormatZstd:
+		return name + ".zst"
+	case FormatGzip:
+		return name + ".gz"
+	default:
+		return name
+	}
+}
+
+// Container indica se o arquivo final com essa quantidade de entradas é um
+// contêiner (zip ou tar) capaz de receber entradas adicionais, como o manifesto.
+func (o Options) Container(entries int) bool {
+	return o.Format == FormatZip || o.Format == "" || entries > 1
+}
+
+// inDir indica se a entrada name está em um subdiretório e por isso precisa
+// de um tar, mesmo sozinha, para manter o caminho.
+func inDir(name string) bool {
+	return strings.Contains(name, "/")
+}
+
+func (o Options) concurrency() int {
+	if o.Concurrency > 0 {
+		return o.Concurrency
+	}
+	return runtime.NumCPU()
+}
+
+// Writer grava entradas no arquivo final usando o formato configurado.
+// Formatos de fluxo único (zstd, gzip, none) agrupam várias entradas em um tar.
+type Writer struct {
+	opts    Options
+	dst     io.Writer
+	entries int
+	zw      *zip.Writer
+	tw      *tar.Writer
+	stream  io.WriteCloser
+	added   int
+}
+
+// NewWriter cria um Writer que grava em dst no formato definido em opts.
+// entries é a quantidade de entradas que serão adicionadas com Add.
+func NewWriter(dst io.Writer, opts Options, entries int) (*Writer, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if entries < 1 {
+		return nil, fmt.Errorf("quantidade de entradas inválida: %d", entries)
+	}
+
+	w := &Writer{opts: opts, dst: dst, entries: entries}
+	if opts.Format == FormatZip {
+		level := flate.DefaultCompression
+		if opts.Level != DefaultLevel {
+			level = opts.Level
+		}
+		w.zw = zip.NewWriter(dst)
+		// O deflate do klauspost/compress é bem mais rápido que o da stdlib
+		w.zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+	}
+	return w, nil
+}
+
+// Add comprime o conteúdo de src como uma entrada chamada name e retorna
+// a quantidade de bytes lidos de src. size é o tamanho do conteúdo, obrigatório
+// apenas quando as entradas são agrupadas em tar (use -1 se desconhecido).
+func (w *Writer) Add(name string, size int64, src io.Reader) (int64, error) {
+	if w.added >= w.entries {
+		return 0, fmt.Errorf("arquivo já recebeu as %d entradas previstas", w.entries)
+	}
+	w.added++
+
+	if w.zw != nil {
+		entry, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return 0, fmt.Errorf("criar entrada %s no zip falhou: %w", name, err)
+		}
+		return io.Copy(entry, src)
+	}
+
+	if w.stream == nil {
+		grouped := w.entries > 1 || inDir(name)
+		streamName := name
+		if grouped {
+			streamName = ""
+		}
+		stream, err := w.newStream(streamName)
+		if err != nil {
+			return 0, err
+		}
+		w.stream = stream
+		if grouped {
+			w.tw = tar.NewWriter(stream)
+		}
+	}
+
+	if w.tw == nil {
+		return io.Copy(w.stream, src)
+	}
+
+	if size < 0 {
+		return 0, fmt.Errorf("tamanho da entrada %s é obrigatório para arquivos tar", name)
+	}
+	hdr := &tar.Header{Name: name, Mode: 0640, Size: size, ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return 0, fmt.Errorf("criar entrada %s no tar falhou: %w", name, err)
+	}
+	return io.Copy(w.tw, src)
+}
+
+// newStream cria o compressor de fluxo único para o formato configurado.
+func (w *Writer) newStream(name string) (io.WriteCloser, error) {
+	switch w.opts.Format {
+	case FormatZstd:
+		encOpts := []zstd.EOption{zstd.WithEncoderConcurrency(w.opts.concurrency())}
+		if w.opts.Level != DefaultLevel {
+			encOpts = append(encOpts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(w.opts.Level)))
+		}
+		enc, err := zstd.NewWriter(w.dst, encOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("criar compressor zstd falhou: %w", err)
+		}
+		return enc, nil
+	case FormatGzip:
+		level := pgzip.DefaultCompression
+		if w.opts.Level != DefaultLevel {
+			level = w.opts.Level
+		}
+		gz, err := pgzip.NewWriterLevel(w.dst, level)
+		if err != nil {
+			return nil, fmt.Errorf("criar compressor gzip falhou: %w", err)
+		}
+		if err := gz.SetConcurrency(1<<20, w.opts.concurrency()); err != nil {
+			return nil, fmt.Errorf("configurar concorrência do gzip falhou: %w", err)
+		}
+		gz.Name = name
+		return gz, nil
+	default:
+		return nopWriteCloser{w.dst}, nil
+	}
+}
+
+// Close finaliza o arquivo, gravando rodapés e dados pendentes do compressor.
+// Não fecha o io.Writer de destino.
+func (w *Writer) Close() error {
+	if w.zw != nil {
+		return w.zw.Close()
+	}
+	if w.stream == nil {
+		return errors.New("nenhuma entrada foi adicionada ao arquivo")
+	}
+	if w.tw != nil {
+		if err := w.tw.Close(); err != nil {
+			return fmt.Errorf("finalizar tar falhou: %w", err)
+		}
+	}
+	return w.stream.Close()
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
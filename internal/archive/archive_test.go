@@ -0,0 +1,199 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Format
+		wantErr bool
+	}{
+		{input: "zip", want: FormatZip},
+		{input: "ZSTD", want: FormatZstd},
+		{input: " gzip ", want: FormatGzip},
+		{input: "none", want: FormatNone},
+		{input: "rar", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseFormat(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	assert.NoError(t, Options{Format: FormatZip, Level: DefaultLevel}.Validate())
+	assert.NoError(t, Options{Format: FormatZip, Level: 9}.Validate())
+	assert.Error(t, Options{Format: FormatZip, Level: 10}.Validate())
+	assert.NoError(t, Options{Format: FormatZstd, Level: 19}.Validate())
+	assert.Error(t, Options{Format: FormatZstd, Level: 0}.Validate())
+	assert.Error(t, Options{Format: FormatGzip, Level: 5, Concurrency: -1}.Validate())
+	assert.NoError(t, Options{Format: FormatNone, Level: 3}.Validate())
+}
+
+func TestOptions_FileName(t *testing.T) {
+	const base = "SCM_20250407_164500"
+	single := []string{base + ".bak"}
+	stripes := []string{base + "_1of2.bak", base + "_2of2.bak"}
+
+	assert.Equal(t, "SCM_20250407_164500.zip", Options{Format: FormatZip}.FileName(base, single))
+	assert.Equal(t, "SCM_20250407_164500.bak.zst", Options{Format: FormatZstd}.FileName(base, single))
+	assert.Equal(t, "SCM_20250407_164500.bak.gz", Options{Format: FormatGzip}.FileName(base, single))
+	assert.Equal(t, "SCM_20250407_164500.bak", Options{Format: FormatNone}.FileName(base, single))
+
+	assert.Equal(t, "SCM_20250407_164500.zip", Options{Format: FormatZip}.FileName(base, stripes))
+	assert.Equal(t, "SCM_20250407_164500.tar.zst", Options{Format: FormatZstd}.FileName(base, stripes))
+	assert.Equal(t, "SCM_20250407_164500.tar.gz", Options{Format: FormatGzip}.FileName(base, stripes))
+	assert.Equal(t, "SCM_20250407_164500.tar", Options{Format: FormatNone}.FileName(base, stripes))
+
+	// Uma entrada em subdiretório é agrupada em tar para manter o caminho
+	inDir := []string{base + "/laudos/a.pdf"}
+	assert.Equal(t, "SCM_20250407_164500.tar.zst", Options{Format: FormatZstd}.FileName(base, inDir))
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	content := strings.Repeat("conteúdo de backup ", 50000)
+
+	decoders := map[Format]func(t *testing.T, data []byte) []byte{
+		FormatZip: func(t *testing.T, data []byte) []byte {
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			require.NoError(t, err)
+			require.Len(t, zr.File, 1)
+			assert.Equal(t, "db.bak", zr.File[0].Name)
+			rc, err := zr.File[0].Open()
+			require.NoError(t, err)
+			defer rc.Close()
+			out, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			return out
+		},
+		FormatZstd: func(t *testing.T, data []byte) []byte {
+			dec, err := zstd.NewReader(bytes.NewReader(data))
+			require.NoError(t, err)
+			defer dec.Close()
+			out, err := io.ReadAll(dec)
+			require.NoError(t, err)
+			return out
+		},
+		FormatGzip: func(t *testing.T, data []byte) []byte {
+			gz, err := gzip.NewReader(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, "db.bak", gz.Name)
+			out, err := io.ReadAll(gz)
+			require.NoError(t, err)
+			return out
+		},
+		FormatNone: func(t *testing.T, data []byte) []byte {
+			return data
+		},
+	}
+
+	for format, decode := range decoders {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, Options{Format: format, Level: DefaultLevel, Concurrency: 2}, 1)
+			require.NoError(t, err)
+
+			n, err := w.Add("db.bak", -1, strings.NewReader(content))
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(content)), n)
+			require.NoError(t, w.Close())
+
+			assert.Equal(t, content, string(decode(t, buf.Bytes())))
+		})
+	}
+}
+
+func TestWriter_MultipleEntriesUseTar(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Options{Format: FormatZstd, Level: 3}, 2)
+	require.NoError(t, err)
+
+	_, err = w.Add("a.bak", 5, strings.NewReader("aaaaa"))
+	require.NoError(t, err)
+	_, err = w.Add("b.bak", 3, strings.NewReader("bbb"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	dec, err := zstd.NewReader(&buf)
+	require.NoError(t, err)
+	defer dec.Close()
+
+	tr := tar.NewReader(dec)
+	got := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		got[hdr.Name] = string(data)
+	}
+	assert.Equal(t, map[string]string{"a.bak": "aaaaa", "b.bak": "bbb"}, got)
+}
+
+func TestWriter_SingleEntryInDirUsesTar(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Options{Format: FormatNone}, 1)
+	require.NoError(t, err)
+	_, err = w.Add("docs/a.pdf", 3, strings.NewReader("pdf"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	hdr, err := tar.NewReader(&buf).Next()
+	require.NoError(t, err)
+	assert.Equal(t, "docs/a.pdf", hdr.Name)
+}
+
+func TestWriter_RejectsExtraEntriesAndUnknownTarSize(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Options{Format: FormatGzip, Level: DefaultLevel}, 2)
+	require.NoError(t, err)
+
+	_, err = w.Add("a.bak", -1, strings.NewReader("a"))
+	assert.Error(t, err, "tar exige o tamanho da entrada")
+
+	single, err := NewWriter(&buf, Options{Format: FormatNone, Level: DefaultLevel}, 1)
+	require.NoError(t, err)
+	_, err = single.Add("a.bak", 1, strings.NewReader("a"))
+	require.NoError(t, err)
+	_, err = single.Add("b.bak", 1, strings.NewReader("b"))
+	assert.Error(t, err)
+}
+
+func TestContextReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewContextReader(ctx, strings.NewReader("abcdef"))
+
+	buf := make([]byte, 3)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	cancel()
+	_, err = r.Read(buf)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,34 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// HashingWriter repassa os dados para o io.Writer de destino calculando o
+// SHA-256 e contando os bytes gravados.
+type HashingWriter struct {
+	w    io.Writer
+	h    hash.Hash
+	size int64
+}
+
+// NewHashingWriter cria um HashingWriter que grava em w.
+func NewHashingWriter(w io.Writer) *HashingWriter {
+	return &HashingWriter{w: w, h: sha256.New()}
+}
+
+func (hw *HashingWriter) Write(p []byte) (int, error) {
+	n, err := hw.w.Write(p)
+	hw.h.Write(p[:n])
+	hw.size += int64(n)
+	return n, err
+}
+
+// Size retorna a quantidade de bytes gravados.
+func (hw *HashingWriter) Size() int64 { return hw.size }
+
+// SHA256 retorna o checksum em hexadecimal dos bytes gravados até o momento.
+func (hw *HashingWriter) SHA256() string { return hex.EncodeToString(hw.h.Sum(nil)) }
@@ -0,0 +1,25 @@
+package archive
+
+import (
+	"context"
+	"io"
+)
+
+// contextReader interrompe a leitura quando o contexto é cancelado.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// NewContextReader retorna um io.Reader que falha com ctx.Err() assim que ctx
+// for cancelado, permitindo interromper a compressão de arquivos grandes.
+func NewContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -0,0 +1,306 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// VolumeManifestSuffix é o sufixo do manifesto gravado ao final de um conjunto de volumes.
+// O manifesto só é criado depois que todos os volumes foram gravados, então sua
+// presença indica que o conjunto está completo.
+const VolumeManifestSuffix = ".volumes.json"
+
+var volumePartRe = regexp.MustCompile(`\.\d{3,}$`)
+
+// Volume descreve uma parte de um arquivo dividido.
+type Volume struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// VolumeSet é o conteúdo do manifesto de um arquivo dividido em volumes.
+type VolumeSet struct {
+	Archive    string    `json:"archive"`     // Nome do arquivo lógico (ex: DB_20250101_120000.zip)
+	VolumeSize int64     `json:"volume_size"` // Tamanho máximo de cada volume em bytes
+	TotalSize  int64     `json:"total_size"`
+	CreatedAt  time.Time `json:"created_at"`
+	Volumes    []Volume  `json:"volumes"`
+}
+
+// VolumeManifestName retorna o nome do manifesto de volumes de um arquivo.
+func VolumeManifestName(archiveName string) string {
+	return archiveName + VolumeManifestSuffix
+}
+
+// IsVolumeManifest indica se o caminho é um manifesto de volumes.
+func IsVolumeManifest(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), VolumeManifestSuffix)
+}
+
+// IsVolumePart indica se o caminho parece ser uma parte de volume (ex: DB.zip.001).
+func IsVolumePart(path string) bool {
+	return volumePartRe.MatchString(path)
+}
+
+// VolumeWriter é um io.Writer que divide o que recebe em volumes de tamanho fixo
+// chamados <archive>.001, <archive>.002, ... no diretório informado.
+type VolumeWriter struct {
+	dir        string
+	archive    string
+	volumeSize int64
+
+	cur     *os.File
+	curSize int64
+	curHash hash.Hash
+	set     VolumeSet
+}
+
+// NewVolumeWriter cria um VolumeWriter para o arquivo lógico archiveName em dir.
+func NewVolumeWriter(dir, archiveName string, volumeSize int64) (*VolumeWriter, error) {
+	if volumeSize <= 0 {
+		return nil, fmt.Errorf("tamanho de volume inválido: %d", volumeSize)
+	}
+	return &VolumeWriter{
+		dir:        dir,
+		archive:    archiveName,
+		volumeSize: volumeSize,
+		set: VolumeSet{
+			Archive:    archiveName,
+			VolumeSize: volumeSize,
+			CreatedAt:  time.Now(),
+		},
+	}, nil
+}
+
+// Write grava p abrindo novos volumes sempre que o atual atinge o tamanho máximo.
+func (vw *VolumeWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if vw.cur == nil || vw.curSize >= vw.volumeSize {
+			if err := vw.rotate(); err != nil {
+				return written, err
+			}
+		}
+		chunk := p
+		if remaining := vw.volumeSize - vw.curSize; int64(len(chunk)) > remaining {
+			chunk = chunk[:remaining]
+		}
+		n, err := vw.cur.Write(chunk)
+		vw.curHash.Write(chunk[:n])
+		vw.curSize += int64(n)
+		written += n
+		if err != nil {
+			return written, fmt.Errorf("gravar volume %s falhou: %w", vw.cur.Name(), err)
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// rotate fecha o volume atual (se houver) e abre o próximo.
+func (vw *VolumeWriter) rotate() error {
+	if err := vw.closeCurrent(); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s.%03d", vw.archive, len(vw.set.Volumes)+1)
+	f, err := os.Create(filepath.Join(vw.dir, name))
+	if err != nil {
+		return fmt.Errorf("criar volume %s falhou: %w", name, err)
+	}
+	vw.cur = f
+	vw.curSize = 0
+	vw.curHash = sha256.New()
+	vw.set.Volumes = append(vw.set.Volumes, Volume{Name: name})
+	return nil
+}
+
+func (vw *VolumeWriter) closeCurrent() error {
+	if vw.cur == nil {
+		return nil
+	}
+	last := &vw.set.Volumes[len(vw.set.Volumes)-1]
+	last.Size = vw.curSize
+	last.SHA256 = hex.EncodeToString(vw.curHash.Sum(nil))
+	vw.set.TotalSize += vw.curSize
+
+	err := vw.cur.Close()
+	vw.cur = nil
+	if err != nil {
+		return fmt.Errorf("fechar volume %s falhou: %w", last.Name, err)
+	}
+	return nil
+}
+
+// Close fecha o último volume e retorna a descrição do conjunto. O manifesto
+// deve ser gravado em seguida com WriteVolumeManifest, pois é ele que sinaliza
+// que o conjunto está completo.
+func (vw *VolumeWriter) Close() (*VolumeSet, error) {
+	if vw.cur == nil && len(vw.set.Volumes) == 0 {
+		// Garante ao menos um volume mesmo para arquivos vazios
+		if err := vw.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := vw.closeCurrent(); err != nil {
+		return nil, err
+	}
+	return &vw.set, nil
+}
+
+// Dir retorna o diretório onde os volumes são gravados.
+func (vw *VolumeWriter) Dir() string { return vw.dir }
+
+// Abort fecha e remove todos os volumes já gravados.
+func (vw *VolumeWriter) Abort() {
+	if vw.cur != nil {
+		_ = vw.cur.Close()
+		vw.cur = nil
+	}
+	for _, v := range vw.set.Volumes {
+		_ = os.Remove(filepath.Join(vw.dir, v.Name))
+	}
+}
+
+// WriteVolumeManifest grava o manifesto do conjunto em dir de forma atômica
+// (arquivo temporário + rename) e retorna o caminho final.
+func WriteVolumeManifest(dir string, set *VolumeSet) (string, error) {
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("serializar manifesto de volumes falhou: %w", err)
+	}
+
+	finalPath := filepath.Join(dir, VolumeManifestName(set.Archive))
+	tempPath := finalPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0640); err != nil {
+		return "", fmt.Errorf("gravar manifesto de volumes %s falhou: %w", tempPath, err)
+	}
+	if err := os.Rename(tempPath, finalPath); err != nil {
+		_ = os.Remove(tempPath)
+		return "", fmt.Errorf("renomear manifesto de volumes para %s falhou: %w", finalPath, err)
+	}
+	return finalPath, nil
+}
+
+// ReadVolumeManifest lê o manifesto de volumes em path.
+func ReadVolumeManifest(path string) (*VolumeSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ler manifesto de volumes %s falhou: %w", path, err)
+	}
+	set := &VolumeSet{}
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("decodificar manifesto de volumes %s falhou: %w", path, err)
+	}
+	if len(set.Volumes) == 0 {
+		return nil, fmt.Errorf("manifesto de volumes %s não lista nenhum volume", path)
+	}
+	if err := set.validateNames(); err != nil {
+		return nil, fmt.Errorf("manifesto de volumes %s: %w", path, err)
+	}
+	return set, nil
+}
+
+// validateNames confere que o arquivo e os volumes do conjunto são nomes
+// simples (sem diretórios) e que cada volume pertence ao arquivo: um manifesto
+// adulterado não pode apontar para arquivos fora do seu diretório.
+func (set *VolumeSet) validateNames() error {
+	if !plainName(set.Archive) {
+		return fmt.Errorf("nome de arquivo inválido '%s'", set.Archive)
+	}
+	for _, v := range set.Volumes {
+		if !plainName(v.Name) || !strings.HasPrefix(v.Name, set.Archive+".") {
+			return fmt.Errorf("nome de volume inválido '%s' para o arquivo %s", v.Name, set.Archive)
+		}
+	}
+	return nil
+}
+
+// plainName indica se name é um nome de arquivo sem componentes de diretório.
+func plainName(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		!strings.ContainsAny(name, `/\:`) && filepath.Base(name) == name && !filepath.IsAbs(name)
+}
+
+// VolumePaths retorna os caminhos dos volumes de um conjunto, relativos ao
+// diretório do manifesto, verificando se todos existem com o tamanho esperado.
+func VolumePaths(manifestPath string, set *VolumeSet) ([]string, error) {
+	if err := set.validateNames(); err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(manifestPath)
+	paths := make([]string, len(set.Volumes))
+	for i, v := range set.Volumes {
+		p := filepath.Join(dir, v.Name)
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("volume %s ausente: %w", v.Name, err)
+		}
+		if info.Size() != v.Size {
+			return nil, fmt.Errorf("volume %s com tamanho %d, esperado %d", v.Name, info.Size(), v.Size)
+		}
+		paths[i] = p
+	}
+	return paths, nil
+}
+
+// JoinVolumes remonta o arquivo original descrito pelo manifesto em dstPath,
+// validando o SHA-256 de cada volume.
+func JoinVolumes(manifestPath, dstPath string) error {
+	set, err := ReadVolumeManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	paths, err := VolumePaths(manifestPath, set)
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("criar %s falhou: %w", dstPath, err)
+	}
+
+	joinErr := func() error {
+		for i, p := range paths {
+			if err := copyVolume(dst, p, set.Volumes[i].SHA256); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if closeErr := dst.Close(); joinErr == nil {
+		joinErr = closeErr
+	}
+	if joinErr != nil {
+		_ = os.Remove(dstPath)
+		return joinErr
+	}
+	return nil
+}
+
+func copyVolume(dst io.Writer, path, wantSHA256 string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("abrir volume %s falhou: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dst, h), f); err != nil {
+		return fmt.Errorf("copiar volume %s falhou: %w", path, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, wantSHA256) {
+		return fmt.Errorf("checksum do volume %s não confere", filepath.Base(path))
+	}
+	return nil
+}
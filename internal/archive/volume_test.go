@@ -0,0 +1,139 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVolumeWriter_SplitAndJoin(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("0123456789"), 2505) // 25050 bytes
+
+	vw, err := NewVolumeWriter(dir, "DB_20250407_164500.zip", 10000)
+	require.NoError(t, err)
+
+	// Escreve em blocos que não coincidem com o tamanho do volume
+	for off := 0; off < len(content); off += 3333 {
+		end := min(off+3333, len(content))
+		_, err := vw.Write(content[off:end])
+		require.NoError(t, err)
+	}
+
+	set, err := vw.Close()
+	require.NoError(t, err)
+	manifestPath, err := WriteVolumeManifest(dir, set)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "DB_20250407_164500.zip.volumes.json"), manifestPath)
+	assert.True(t, IsVolumeManifest(manifestPath))
+
+	set, err = ReadVolumeManifest(manifestPath)
+	require.NoError(t, err)
+	require.Len(t, set.Volumes, 3)
+	assert.Equal(t, "DB_20250407_164500.zip.001", set.Volumes[0].Name)
+	assert.Equal(t, int64(10000), set.Volumes[0].Size)
+	assert.Equal(t, int64(5050), set.Volumes[2].Size)
+	assert.Equal(t, int64(len(content)), set.TotalSize)
+	assert.True(t, IsVolumePart(set.Volumes[2].Name))
+
+	joined := filepath.Join(dir, "joined.zip")
+	require.NoError(t, JoinVolumes(manifestPath, joined))
+	got, err := os.ReadFile(joined)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestJoinVolumes_DetectsCorruption(t *testing.T) {
+	dir := t.TempDir()
+
+	vw, err := NewVolumeWriter(dir, "DB.zip", 4)
+	require.NoError(t, err)
+	_, err = vw.Write([]byte("abcdefgh"))
+	require.NoError(t, err)
+	set, err := vw.Close()
+	require.NoError(t, err)
+	manifestPath, err := WriteVolumeManifest(dir, set)
+	require.NoError(t, err)
+
+	// Mesmo tamanho, conteúdo diferente
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "DB.zip.002"), []byte("xxxx"), 0640))
+
+	joined := filepath.Join(dir, "joined.zip")
+	err = JoinVolumes(manifestPath, joined)
+	assert.ErrorContains(t, err, "checksum")
+	assert.NoFileExists(t, joined)
+}
+
+func TestVolumePaths_MissingVolume(t *testing.T) {
+	dir := t.TempDir()
+
+	vw, err := NewVolumeWriter(dir, "DB.zip", 4)
+	require.NoError(t, err)
+	_, err = vw.Write([]byte("abcdefgh"))
+	require.NoError(t, err)
+	set, err := vw.Close()
+	require.NoError(t, err)
+	manifestPath, err := WriteVolumeManifest(dir, set)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "DB.zip.001")))
+
+	set, err = ReadVolumeManifest(manifestPath)
+	require.NoError(t, err)
+	_, err = VolumePaths(manifestPath, set)
+	assert.Error(t, err)
+}
+
+func TestVolumeWriter_Abort(t *testing.T) {
+	dir := t.TempDir()
+
+	vw, err := NewVolumeWriter(dir, "DB.zip", 4)
+	require.NoError(t, err)
+	_, err = vw.Write([]byte("abcdefghij"))
+	require.NoError(t, err)
+	vw.Abort()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestReadVolumeManifest_RejectsUnsafeNames(t *testing.T) {
+	for name, volume := range map[string]string{
+		"diretório acima": "../../etc/x",
+		"absoluto":        "/etc/passwd",
+		"subdiretório":    "sub/DB.zip.001",
+		"barra invertida": `..\DB.zip.001`,
+		"drive":           "C:DB.zip.001",
+		"ponto":           ".",
+		"pontos":          "..",
+		"outro arquivo":   "OUTRO.zip.001",
+		"sem sufixo":      "DB.zip",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			set := &VolumeSet{Archive: "DB.zip", Volumes: []Volume{{Name: volume, Size: 1}}}
+			manifestPath, err := WriteVolumeManifest(dir, set)
+			require.NoError(t, err)
+
+			_, err = ReadVolumeManifest(manifestPath)
+			assert.ErrorContains(t, err, "nome de volume inválido")
+			_, err = VolumePaths(manifestPath, set)
+			assert.Error(t, err)
+		})
+	}
+
+	dir := t.TempDir()
+	set := &VolumeSet{Archive: "../DB.zip", Volumes: []Volume{{Name: "../DB.zip.001", Size: 1}}}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	manifestPath := filepath.Join(dir, "DB.zip.volumes.json")
+	require.NoError(t, os.WriteFile(manifestPath, data, 0640))
+	_, err = ReadVolumeManifest(manifestPath)
+	assert.ErrorContains(t, err, "nome de arquivo inválido")
+}
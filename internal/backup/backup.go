@@ -0,0 +1,239 @@
+// Package backup implementa o pipeline do dbbackup: conectar, executar o
+// backup, verificar, compactar e publicar o arquivo final. O banco de origem é
+// um Engine (SQL Server, PostgreSQL, MySQL/MariaDB, SQLite ou uma árvore de
+// arquivos). O mesmo Job é usado pela CLI, pelo modo agendado e pelos testes
+// (com um Executor falso).
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/hooks"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mssql"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/report"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/retention"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/version"
+)
+
+// Modos de leitura do .bak.
+const (
+	FetchLocal = "local" // BackupDir acessível por esta máquina
+	FetchBulk  = "bulk"  // Lido pela conexão SQL via OPENROWSET
+)
+
+// Remoção dos .bak do servidor após o backup publicado.
+const (
+	CleanupNone  = "none"  // Mantém os .bak em BackupDir
+	CleanupLocal = "local" // Remove pelo caminho local (modo de leitura local)
+	CleanupXP    = "xp"    // Remove pelo servidor com xp_delete_file
+)
+
+// CleanupModes lista os modos de remoção aceitos.
+var CleanupModes = []string{CleanupNone, CleanupLocal, CleanupXP}
+
+// Options reúne a configuração de um backup.
+type Options struct {
+	Engine    string // sqlserver (padrão), postgres, mysql, sqlite ou files; registrado no manifesto
+	Server    string
+	Database  string
+	BackupDir string // Diretório no servidor SQL Server, ou dos arquivos intermediários dos demais engines (vazio no MySQL e em files)
+	ZipDir    string // Diretório local do arquivo final (não usado no modo -stream)
+
+	BackupType      string // full (padrão), differential ou log
+	Stripes         int
+	SQLCompression  bool
+	MaxTransferSize int
+	BufferCount     int
+	Stats           int
+	Verify          bool // Executa RESTORE VERIFYONLY após o backup
+
+	FetchMode      string // local (padrão) ou bulk
+	FetchChunkSize int64
+
+	Archive    archive.Options
+	VolumeSize int64 // 0 = sem divisão em volumes
+
+	ConnectTimeout   time.Duration
+	BackupTimeout    time.Duration // 0 = sem limite
+	ArchiveTimeout   time.Duration // 0 = sem limite
+	ProgressInterval time.Duration
+
+	Lock LockOptions
+
+	CheckSpace    bool             // Compara o tamanho estimado do backup com o espaço livre antes de começar
+	MinFreeSpace  int64            // Espaço livre mínimo em ZipDir (e BackupDir no modo local) antes do backup; 0 desativa
+	ServerCleanup string           // none (padrão), local ou xp
+	Retention     retention.Policy // Backups antigos mantidos em ZipDir
+}
+
+// LockOptions configura o lock contra execuções simultâneas do mesmo banco.
+type LockOptions struct {
+	Mode        string        // fail, skip ou wait; vazio desativa o lock
+	Dir         string        // Diretório do arquivo de lock (padrão: lock.DefaultDir)
+	WaitTimeout time.Duration // Espera máxima no modo wait (0 = sem limite)
+	StaleAfter  time.Duration // Idade a partir da qual o lock de outro host é abandonado
+	SQL         bool          // Também obtém sp_getapplock no servidor
+}
+
+// ErrSkipped indica que o backup não foi executado porque outra execução do
+// mesmo banco estava em andamento (LockOptions.Mode skip).
+var ErrSkipped = errors.New("backup ignorado")
+
+// Job executa um backup. Engine é o banco de origem; se nil, o backup é do SQL
+// Server e Connect é chamado no estágio connect, devendo retornar um Executor
+// ainda não verificado (o Ping é feito pelo pipeline).
+type Job struct {
+	Options  Options
+	Engine   Engine
+	Connect  func(ctx context.Context) (Executor, error)
+	Hooks    *hooks.Runner // Opcional
+	Logger   *slog.Logger
+	Report   *report.Report         // Criado por Run se nil
+	Progress *mssql.ProgressTracker // Opcional; progresso do BACKUP para consulta externa
+	Notify   func(msg string)       // Opcional; avisos que não interrompem o backup
+	Upload   Uploader               // Opcional; envia o arquivo final durante a compressão (-stream), sem gravá-lo em ZipDir
+	Now      func() time.Time       // Opcional; define o timestamp dos nomes de arquivo
+}
+
+// Result descreve o backup publicado.
+type Result struct {
+	ArchivePath string // Arquivo final, ou manifesto de volumes quando dividido (vazio no modo -stream)
+	SidecarPath string
+	RemoteID    string // Arquivo enviado pelo Uploader no modo -stream
+	Manifest    *manifest.Manifest
+}
+
+// StageError é o erro de um estágio do pipeline. Stage corresponde às fases
+// de report (connect, lock, pre-hooks, backup, verify, archive, finalize, post-hooks).
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("estágio %s: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Stage retorna o estágio em que err ocorreu, ou "" se err não for um StageError.
+func Stage(err error) string {
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		return stageErr.Stage
+	}
+	return ""
+}
+
+// Run executa o pipeline completo. Em caso de falha, os hooks pós-falha são
+// executados e o erro retornado é um *StageError. Um backup ignorado pelo lock
+// retorna um erro que satisfaz errors.Is(err, ErrSkipped), sem hooks. O
+// relatório em j.Report é preenchido em todos os casos.
+func (j *Job) Run(ctx context.Context) (*Result, error) {
+	if j.Report == nil {
+		j.Report = report.New(version.Version, j.Options.Server, j.Options.Database)
+	}
+	if j.Hooks == nil {
+		j.Hooks = &hooks.Runner{Logger: j.Logger}
+	}
+	if j.Now == nil {
+		j.Now = time.Now
+	}
+
+	r := &run{
+		job:     j,
+		opts:    j.Options,
+		l:       j.Logger,
+		hookEnv: hooks.Env{Server: j.Options.Server, Database: j.Options.Database, Status: hooks.StatusRunning},
+	}
+	defer r.close()
+
+	result, err := r.execute(ctx)
+	if errors.Is(err, ErrSkipped) {
+		j.Report.Skip(err.Error())
+		j.Logger.Warn("Backup ignorado", slog.Any("reason", err))
+		return nil, err
+	}
+	if err != nil {
+		j.Report.Fail(err, errors.Is(ctx.Err(), context.Canceled))
+		j.Logger.Error("Backup falhou", slog.String("stage", Stage(err)), slog.Any("error", err))
+		if Stage(err) != report.PhasePostHooks {
+			env := r.hookEnv
+			env.Status = hooks.StatusFailure
+			env.Error = err.Error()
+			// O contexto pode ter sido cancelado por sinal; os hooks rodam mesmo assim
+			if hookErr := j.Hooks.Run(context.Background(), hooks.PhasePostFailure, env); hookErr != nil {
+				j.Logger.Error("Falha nos hooks pós-falha", slog.Any("error", hookErr))
+				j.Report.AddHookError(hookErr)
+			}
+		}
+		return result, err
+	}
+
+	j.Report.Succeed()
+	return result, nil
+}
+
+// execute encadeia os estágios, registrando cada um no relatório.
+func (r *run) execute(ctx context.Context) (*Result, error) {
+	stages := []struct {
+		name string
+		fn   func(ctx context.Context) error
+		skip bool
+	}{
+		{name: report.PhaseConnect, fn: r.connect},
+		{name: report.PhaseLock, fn: r.lock, skip: r.opts.Lock.Mode == ""},
+		{name: report.PhasePreflight, fn: r.preflight, skip: !r.opts.CheckSpace && r.opts.MinFreeSpace <= 0},
+		{name: report.PhasePreHooks, fn: r.preHooks},
+		{name: report.PhaseBackup, fn: r.backup},
+		{name: report.PhaseVerify, fn: r.verify, skip: !r.opts.Verify},
+		{name: report.PhaseArchive, fn: r.archive},
+		{name: report.PhaseFinalize, fn: r.finalize},
+		{name: report.PhaseRetention, fn: r.retention, skip: !r.cleanupEnabled()},
+	}
+	for _, stage := range stages {
+		if stage.skip {
+			continue
+		}
+		r.job.Report.StartPhase(stage.name)
+		if err := stage.fn(ctx); err != nil {
+			return nil, &StageError{Stage: stage.name, Err: err}
+		}
+	}
+
+	result := &Result{ArchivePath: r.hookEnv.Archive, SidecarPath: r.sidecarPath, RemoteID: r.remoteID, Manifest: r.manifest}
+
+	r.job.Report.StartPhase(report.PhasePostHooks)
+	if err := r.postHooks(ctx); err != nil {
+		return result, &StageError{Stage: report.PhasePostHooks, Err: err}
+	}
+	return result, nil
+}
+
+// phaseContext deriva de ctx o contexto de uma fase, com tempo limite opcional (0 = sem limite).
+func phaseContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// phaseError indica em err se a fase foi interrompida por tempo limite ou por sinal.
+func phaseError(ctx context.Context, phase string, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("tempo limite da fase de %s excedido: %w", phase, err)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("fase de %s cancelada: %w", phase, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,472 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/diskspace"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/hooks"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/lock"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mssql"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/report"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/retention"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var diskPattern = regexp.MustCompile(`DISK = N'[^']*\\([^'\\]+)'`)
+
+// fakeExecutor simula o SQL Server: o BACKUP grava os .bak em dir.
+type fakeExecutor struct {
+	dir       string
+	content   string
+	pingErr   error
+	backupErr error
+	execErr   error
+	block     bool // ExecBackup espera o cancelamento do contexto
+	appLocked bool // sp_getapplock em uso por outra sessão
+	estimate  *mssql.SizeEstimate
+
+	appLocks int // Locks de aplicação obtidos e ainda não liberados
+
+	executed []string
+	tracker  *mssql.ProgressTracker // Recebido em ExecBackup
+	closed   bool
+}
+
+func (f *fakeExecutor) Ping(ctx context.Context) error { return f.pingErr }
+
+func (f *fakeExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	f.executed = append(f.executed, query)
+	return nil, f.execErr
+}
+
+func (f *fakeExecutor) ExecBackup(ctx context.Context, stmt string, opts mssql.ProgressOptions) error {
+	f.executed = append(f.executed, stmt)
+	f.tracker = opts.Tracker
+	for _, m := range diskPattern.FindAllStringSubmatch(stmt, -1) {
+		if err := os.WriteFile(filepath.Join(f.dir, m[1]), []byte(f.content), 0640); err != nil {
+			return err
+		}
+	}
+	if f.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return f.backupErr
+}
+
+func (f *fakeExecutor) ServerInfo(ctx context.Context) (*mssql.ServerInfo, error) {
+	return &mssql.ServerInfo{Name: "SQL01", Version: "16.0.1000.6"}, nil
+}
+
+func (f *fakeExecutor) BackupSet(ctx context.Context, devicePath string) (*mssql.BackupSet, error) {
+	return nil, errors.New("sem msdb")
+}
+
+func (f *fakeExecutor) EstimateBackupSize(ctx context.Context, database, backupType string, compressed bool) (*mssql.SizeEstimate, error) {
+	if f.estimate == nil {
+		return nil, errors.New("sem msdb")
+	}
+	return f.estimate, nil
+}
+
+func (f *fakeExecutor) AppLock(ctx context.Context, resource string) (func() error, error) {
+	if f.appLocked {
+		return nil, mssql.ErrAppLockHeld
+	}
+	f.appLocks++
+	return func() error {
+		f.appLocks--
+		return nil
+	}, nil
+}
+
+func (f *fakeExecutor) OpenRemoteFile(ctx context.Context, path string, chunkSize int64) RemoteFile {
+	return nil
+}
+
+func (f *fakeExecutor) Close() error {
+	f.closed = true
+	return nil
+}
+
+func newTestJob(t *testing.T, exec *fakeExecutor) *Job {
+	t.Helper()
+	backupDir, zipDir := t.TempDir(), t.TempDir()
+	exec.dir = backupDir
+	if exec.content == "" {
+		exec.content = strings.Repeat("dados do backup ", 1000)
+	}
+	return &Job{
+		Options: Options{
+			Server:           "sql01",
+			Database:         "SCM",
+			BackupDir:        backupDir,
+			ZipDir:           zipDir,
+			Stripes:          1,
+			Archive:          archive.Options{Format: archive.FormatZip, Level: archive.DefaultLevel},
+			ConnectTimeout:   time.Second,
+			ProgressInterval: time.Second,
+		},
+		Connect: func(ctx context.Context) (Executor, error) { return exec, nil },
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Now:     func() time.Time { return time.Date(2025, 4, 7, 16, 45, 0, 0, time.UTC) },
+	}
+}
+
+func TestRun_Success(t *testing.T) {
+	exec := &fakeExecutor{}
+	job := newTestJob(t, exec)
+
+	result, err := job.Run(context.Background())
+	require.NoError(t, err)
+
+	wantArchive := filepath.Join(job.Options.ZipDir, "SCM_20250407_164500.zip")
+	assert.Equal(t, wantArchive, result.ArchivePath)
+	assert.FileExists(t, wantArchive)
+	assert.NoFileExists(t, filepath.Join(job.Options.ZipDir, "SCM_20250407_164500.tmp"))
+	assert.True(t, exec.closed)
+
+	// O zip contém o .bak e o manifesto embutido
+	zr, err := zip.OpenReader(wantArchive)
+	require.NoError(t, err)
+	defer zr.Close()
+	require.Len(t, zr.File, 2)
+	assert.Equal(t, "SCM_20250407_164500.bak", zr.File[0].Name)
+	assert.Equal(t, manifest.EntryName, zr.File[1].Name)
+
+	m, err := manifest.ReadFile(result.SidecarPath)
+	require.NoError(t, err)
+	assert.Equal(t, "SQL01", m.Server)
+	assert.Equal(t, "16.0.1000.6", m.SQLServerVersion)
+	assert.Equal(t, int64(len(exec.content)), m.BakSize)
+	require.Len(t, m.Files, 1)
+	assert.Equal(t, "SCM_20250407_164500.zip", m.Archive.Name)
+
+	assert.Equal(t, report.StatusSuccess, job.Report.Status)
+	assert.Equal(t, report.ExitOK, job.Report.ExitCode)
+	assert.Equal(t, wantArchive, job.Report.ArchivePath)
+	assert.Equal(t, m.Archive.SHA256, job.Report.ArchiveSHA256)
+
+	var phases []string
+	for _, p := range job.Report.Phases {
+		phases = append(phases, p.Name)
+	}
+	assert.Equal(t, []string{report.PhaseConnect, report.PhasePreHooks, report.PhaseBackup, report.PhaseArchive, report.PhaseFinalize, report.PhasePostHooks}, phases)
+}
+
+func TestRun_Progress(t *testing.T) {
+	exec := &fakeExecutor{}
+	job := newTestJob(t, exec)
+	job.Progress = &mssql.ProgressTracker{}
+
+	_, err := job.Run(context.Background())
+	require.NoError(t, err)
+	assert.Same(t, job.Progress, exec.tracker, "progresso do BACKUP publicado no tracker do job")
+}
+
+func TestRun_VolumesAndVerify(t *testing.T) {
+	exec := &fakeExecutor{}
+	job := newTestJob(t, exec)
+	job.Options.Stripes = 2
+	job.Options.Verify = true
+	job.Options.Archive = archive.Options{Format: archive.FormatNone}
+	job.Options.VolumeSize = 4096
+
+	result, err := job.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, archive.IsVolumeManifest(result.ArchivePath))
+	set, err := archive.ReadVolumeManifest(result.ArchivePath)
+	require.NoError(t, err)
+	assert.Greater(t, len(set.Volumes), 1)
+	assert.Equal(t, len(set.Volumes), job.Report.VolumeCount)
+
+	require.Len(t, exec.executed, 2)
+	assert.Contains(t, exec.executed[1], "RESTORE VERIFYONLY")
+	assert.Contains(t, exec.executed[1], "SCM_20250407_164500_2of2.bak")
+}
+
+func TestRun_StageErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		exec     *fakeExecutor
+		stage    string
+		exitCode int
+	}{
+		{name: "connect", exec: &fakeExecutor{pingErr: errors.New("login failed")}, stage: report.PhaseConnect, exitCode: report.ExitConnect},
+		{name: "backup", exec: &fakeExecutor{backupErr: errors.New("disk full")}, stage: report.PhaseBackup, exitCode: report.ExitBackup},
+		{name: "verify", exec: &fakeExecutor{execErr: errors.New("media corrupta")}, stage: report.PhaseVerify, exitCode: report.ExitVerify},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := newTestJob(t, tt.exec)
+			job.Options.Verify = true
+
+			_, err := job.Run(context.Background())
+			require.Error(t, err)
+			assert.Equal(t, tt.stage, Stage(err))
+			assert.Equal(t, report.StatusFailure, job.Report.Status)
+			assert.Equal(t, tt.exitCode, job.Report.ExitCode)
+			assert.Equal(t, tt.stage, job.Report.FailedPhase)
+
+			entries, err := os.ReadDir(job.Options.ZipDir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestRun_FatalPreHook(t *testing.T) {
+	exec := &fakeExecutor{}
+	job := newTestJob(t, exec)
+	marker := filepath.Join(t.TempDir(), "post-failure")
+	job.Hooks = &hooks.Runner{
+		Hooks: []hooks.Hook{
+			{Phase: hooks.PhasePre, Command: "exit 3"},
+			{Phase: hooks.PhasePostFailure, Command: "echo $DBBACKUP_STATUS > " + marker},
+		},
+		Timeout: 10 * time.Second,
+		Fatal:   true,
+		Logger:  job.Logger,
+	}
+
+	_, err := job.Run(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, report.PhasePreHooks, Stage(err))
+	assert.Equal(t, report.ExitHook, job.Report.ExitCode)
+	assert.Empty(t, exec.executed, "o BACKUP não deve ser executado")
+
+	data, err := os.ReadFile(marker)
+	require.NoError(t, err)
+	assert.Equal(t, hooks.StatusFailure, strings.TrimSpace(string(data)))
+}
+
+func TestRun_NonFatalPreHookNotifies(t *testing.T) {
+	job := newTestJob(t, &fakeExecutor{})
+	job.Hooks = &hooks.Runner{
+		Hooks:   []hooks.Hook{{Phase: hooks.PhasePre, Command: "exit 1"}},
+		Timeout: 10 * time.Second,
+		Logger:  job.Logger,
+	}
+	var notified []string
+	job.Notify = func(msg string) { notified = append(notified, msg) }
+
+	_, err := job.Run(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, notified, 1)
+	assert.Len(t, job.Report.HookErrors, 1)
+}
+
+func TestRun_CanceledRemovesPartialFiles(t *testing.T) {
+	exec := &fakeExecutor{block: true}
+	job := newTestJob(t, exec)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := job.Run(ctx)
+	require.Error(t, err)
+	assert.Equal(t, report.PhaseBackup, Stage(err))
+	assert.True(t, job.Report.Canceled)
+	assert.Equal(t, report.ExitCanceled, job.Report.ExitCode)
+
+	entries, err := os.ReadDir(job.Options.BackupDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "o .bak interrompido deve ser removido")
+}
+
+func TestRun_Lock(t *testing.T) {
+	t.Run("liberado ao final", func(t *testing.T) {
+		exec := &fakeExecutor{}
+		job := newTestJob(t, exec)
+		job.Options.Lock = LockOptions{Mode: lock.ModeFail, SQL: true, Dir: t.TempDir()}
+
+		_, err := job.Run(context.Background())
+		require.NoError(t, err)
+		assert.NoFileExists(t, filepath.Join(job.Options.Lock.Dir, lock.FileName("SCM")))
+		assert.Zero(t, exec.appLocks)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		exec := &fakeExecutor{}
+		job := newTestJob(t, exec)
+		job.Options.Lock = LockOptions{Mode: lock.ModeFail, Dir: t.TempDir()}
+		held, err := lock.TryFile(filepath.Join(job.Options.Lock.Dir, lock.FileName("SCM")), "SCM", 0)
+		require.NoError(t, err)
+		defer held.Release()
+
+		_, err = job.Run(context.Background())
+		assert.ErrorIs(t, err, lock.ErrLocked)
+		assert.Equal(t, report.PhaseLock, Stage(err))
+		assert.Equal(t, report.ExitLocked, job.Report.ExitCode)
+		assert.Len(t, exec.executed, 0)
+		assert.FileExists(t, held.Path, "o lock de outra execução não pode ser removido")
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		exec := &fakeExecutor{}
+		job := newTestJob(t, exec)
+		job.Options.Lock = LockOptions{Mode: lock.ModeSkip, Dir: t.TempDir()}
+		held, err := lock.TryFile(filepath.Join(job.Options.Lock.Dir, lock.FileName("SCM")), "SCM", 0)
+		require.NoError(t, err)
+		defer held.Release()
+
+		_, err = job.Run(context.Background())
+		assert.ErrorIs(t, err, ErrSkipped)
+		assert.Equal(t, report.StatusSkipped, job.Report.Status)
+		assert.Equal(t, report.ExitOK, job.Report.ExitCode)
+		assert.Len(t, exec.executed, 0)
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		old := lock.PollInterval
+		lock.PollInterval = 10 * time.Millisecond
+		t.Cleanup(func() { lock.PollInterval = old })
+
+		job := newTestJob(t, &fakeExecutor{})
+		job.Options.Lock = LockOptions{Mode: lock.ModeWait, WaitTimeout: 5 * time.Second, Dir: t.TempDir()}
+		held, err := lock.TryFile(filepath.Join(job.Options.Lock.Dir, lock.FileName("SCM")), "SCM", 0)
+		require.NoError(t, err)
+		time.AfterFunc(50*time.Millisecond, func() { _ = held.Release() })
+
+		_, err = job.Run(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("sql", func(t *testing.T) {
+		job := newTestJob(t, &fakeExecutor{appLocked: true})
+		job.Options.Lock = LockOptions{Mode: lock.ModeFail, SQL: true, Dir: t.TempDir()}
+
+		_, err := job.Run(context.Background())
+		assert.ErrorIs(t, err, lock.ErrLocked)
+		assert.Equal(t, report.ExitLocked, job.Report.ExitCode)
+		// O lock de arquivo obtido antes é liberado
+		assert.NoFileExists(t, filepath.Join(job.Options.Lock.Dir, lock.FileName("SCM")))
+	})
+
+	t.Run("diretório padrão fora de ZipDir", func(t *testing.T) {
+		old := lock.DefaultDir
+		lock.DefaultDir = filepath.Join(t.TempDir(), "locks")
+		t.Cleanup(func() { lock.DefaultDir = old })
+
+		job := newTestJob(t, &fakeExecutor{})
+		job.Options.Lock = LockOptions{Mode: lock.ModeFail}
+		require.NoError(t, os.MkdirAll(lock.DefaultDir, 0750))
+		held, err := lock.TryFile(filepath.Join(lock.DefaultDir, lock.FileName("SCM")), "SCM", 0)
+		require.NoError(t, err)
+		defer held.Release()
+
+		_, err = job.Run(context.Background())
+		assert.ErrorIs(t, err, lock.ErrLocked)
+		assert.NoFileExists(t, filepath.Join(job.Options.ZipDir, lock.FileName("SCM")))
+	})
+}
+
+func TestRun_PreflightDiskSpace(t *testing.T) {
+	exec := &fakeExecutor{}
+	job := newTestJob(t, exec)
+	job.Options.MinFreeSpace = math.MaxInt64
+
+	_, err := job.Run(context.Background())
+	var insufficient *diskspace.InsufficientError
+	require.ErrorAs(t, err, &insufficient)
+	assert.Equal(t, report.PhasePreflight, Stage(err))
+	assert.Equal(t, report.ExitPreflight, job.Report.ExitCode)
+	assert.Empty(t, exec.executed, "o BACKUP não deve ser executado")
+}
+
+func TestRun_PreflightEstimate(t *testing.T) {
+	// Estimativa acima de qualquer disco: falha antes do BACKUP
+	exec := &fakeExecutor{estimate: &mssql.SizeEstimate{Bytes: math.MaxInt64 / 2, Source: mssql.EstimateHistory}}
+	job := newTestJob(t, exec)
+	job.Options.CheckSpace = true
+
+	_, err := job.Run(context.Background())
+	var insufficient *diskspace.InsufficientError
+	require.ErrorAs(t, err, &insufficient)
+	assert.ErrorContains(t, err, "backup estimado em")
+	assert.Equal(t, report.ExitPreflight, job.Report.ExitCode)
+	assert.Equal(t, int64(math.MaxInt64/2), job.Report.EstimatedSize)
+	assert.Empty(t, exec.executed)
+
+	// Sem estimativa o backup segue normalmente
+	exec = &fakeExecutor{content: "dados"}
+	job = newTestJob(t, exec)
+	job.Options.CheckSpace = true
+	_, err = job.Run(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, job.Report.EstimatedSize)
+}
+
+func TestRun_Retention(t *testing.T) {
+	exec := &fakeExecutor{}
+	job := newTestJob(t, exec)
+	job.Options.ServerCleanup = CleanupLocal
+	job.Options.Retention = retention.Policy{KeepLast: 2}
+	for _, name := range []string{"SCM_20250405_020000.zip", "SCM_20250405_020000.zip.manifest.json", "SCM_20250406_020000.zip", "SCM_20250406_031500_log.zip"} {
+		require.NoError(t, os.WriteFile(filepath.Join(job.Options.ZipDir, name), []byte("x"), 0640))
+	}
+
+	_, err := job.Run(context.Background())
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(job.Options.BackupDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "os .bak devem ser removidos após o backup")
+
+	var names []string
+	entries, err = os.ReadDir(job.Options.ZipDir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{
+		"SCM_20250406_020000.zip",
+		"SCM_20250406_031500_log.zip",
+		"SCM_20250407_164500.zip",
+		"SCM_20250407_164500.zip.manifest.json",
+	}, names)
+}
+
+func TestRun_ServerCleanupXP(t *testing.T) {
+	exec := &fakeExecutor{execErr: errors.New("permissão negada")}
+	job := newTestJob(t, exec)
+	job.Options.ServerCleanup = CleanupXP
+	var notified []string
+	job.Notify = func(msg string) { notified = append(notified, msg) }
+
+	// Falhas na limpeza não invalidam o backup
+	_, err := job.Run(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, exec.executed[len(exec.executed)-1], "xp_delete_file 0, N'")
+	assert.Len(t, job.Report.Warnings, 1)
+	assert.Len(t, notified, 1)
+}
+
+func TestBaseName(t *testing.T) {
+	ts := time.Date(2025, 4, 7, 16, 45, 0, 0, time.UTC)
+	assert.Equal(t, "SCM_20250407_164500", BaseName("SCM", mssql.BackupFull, ts))
+	assert.Equal(t, "SCM_20250407_164500_diff", BaseName("SCM", mssql.BackupDifferential, ts))
+	assert.Equal(t, "SCM_20250407_164500_log", BaseName("SCM", mssql.BackupLog, ts))
+}
+
+func TestStripeFilenames(t *testing.T) {
+	assert.Equal(t, []string{"DB_1.bak"}, StripeFilenames("DB_1", 1))
+	assert.Equal(t, []string{"DB_1_1of2.bak", "DB_1_2of2.bak"}, StripeFilenames("DB_1", 2))
+}
@@ -0,0 +1,86 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+)
+
+// Engines suportados (flag -engine).
+const (
+	EngineSQLServer = "sqlserver"
+	EnginePostgres  = "postgres"
+	EngineMySQL     = "mysql"
+	EngineSQLite    = "sqlite"
+	EngineFiles     = "files" // Árvore de diretórios
+)
+
+// Engines lista os engines aceitos.
+var Engines = []string{EngineSQLServer, EnginePostgres, EngineMySQL, EngineSQLite, EngineFiles}
+
+// Engine é o banco de dados de origem do backup. O pipeline cuida do lock, dos
+// hooks, da compressão, do manifesto e da retenção; o Engine gera os arquivos
+// do backup e descreve o servidor.
+type Engine interface {
+	// Connect verifica o acesso ao servidor.
+	Connect(ctx context.Context) error
+	// EstimateSize estima o tamanho dos arquivos do próximo backup.
+	EstimateSize(ctx context.Context) (*SizeEstimate, error)
+	// Backup executa o backup com o nome base informado, preenche os metadados
+	// de m e retorna as entradas do arquivo final, na ordem de gravação.
+	Backup(ctx context.Context, base string, m *manifest.Manifest) ([]Entry, error)
+	// Verify confere o backup gerado por Backup.
+	Verify(ctx context.Context) error
+	// Cleanup remove os arquivos intermediários do backup já publicado
+	// (CleanupLocal ou CleanupXP).
+	Cleanup(ctx context.Context, mode string) error
+	// Close encerra as conexões.
+	Close() error
+}
+
+// SizeEstimate é o tamanho estimado dos arquivos de um backup.
+type SizeEstimate struct {
+	Bytes  int64
+	Source string // Origem da estimativa (ex: history, used)
+}
+
+// Entry é um arquivo do backup a ser gravado no arquivo final. O conteúdo pode
+// ser gerado apenas quando Open é chamado, na fase de compressão (ex: saída do
+// mysqldump); nesse caso as falhas do processo são retornadas por Close.
+type Entry struct {
+	Name string // Nome dentro do arquivo final
+	Path string // Origem, para os logs
+	// Open abre o conteúdo e retorna seu tamanho (-1 se desconhecido).
+	Open func(ctx context.Context) (io.ReadCloser, int64, error)
+}
+
+// FileEntry retorna a entrada name com o conteúdo do arquivo local path.
+func FileEntry(name, path string) Entry {
+	return Entry{Name: name, Path: path, Open: func(ctx context.Context) (io.ReadCloser, int64, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, 0, fmt.Errorf("abrir %s falhou (verifique o caminho e as permissões): %w", path, err)
+		}
+		size := int64(-1)
+		if info, err := f.Stat(); err == nil {
+			size = info.Size()
+		}
+		return f, size, nil
+	}}
+}
+
+// appLocker é implementado por engines com lock de aplicação no servidor
+// (LockOptions.SQL). AppLock não espera: com o lock em uso por outra sessão
+// retorna um erro que satisfaz errors.Is(err, lock.ErrLocked).
+type appLocker interface {
+	AppLock(ctx context.Context, resource string) (release func() error, err error)
+}
+
+// sqlRunner é implementado por engines que executam os scripts SQL dos hooks.
+type sqlRunner interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
@@ -0,0 +1,143 @@
+package backup
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mssql"
+)
+
+// RemoteFile é um arquivo do servidor lido pela conexão SQL (modo bulk).
+type RemoteFile interface {
+	io.ReadCloser
+	Size() (int64, error)
+}
+
+// Executor é tudo o que o pipeline precisa do SQL Server. SQLExecutor o
+// implementa com database/sql; os testes usam um executor falso.
+type Executor interface {
+	// Ping verifica a conexão com o servidor.
+	Ping(ctx context.Context) error
+	// ExecContext executa um comando sem resultado (RESTORE VERIFYONLY, scripts de hooks).
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	// ExecBackup executa o comando BACKUP acompanhando seu progresso.
+	ExecBackup(ctx context.Context, stmt string, opts mssql.ProgressOptions) error
+	// ServerInfo retorna o nome e a versão da instância.
+	ServerInfo(ctx context.Context) (*mssql.ServerInfo, error)
+	// BackupSet retorna os dados do backup gravado em devicePath.
+	BackupSet(ctx context.Context, devicePath string) (*mssql.BackupSet, error)
+	// EstimateBackupSize estima o tamanho do .bak do próximo backup de database.
+	EstimateBackupSize(ctx context.Context, database, backupType string, compressed bool) (*mssql.SizeEstimate, error)
+	// AppLock obtém o lock de aplicação exclusivo resource (sp_getapplock), sem
+	// espera, e retorna a função que o libera. Retorna mssql.ErrAppLockHeld se
+	// outra sessão o detém.
+	AppLock(ctx context.Context, resource string) (release func() error, err error)
+	// OpenRemoteFile abre um arquivo do servidor para leitura pela conexão.
+	OpenRemoteFile(ctx context.Context, path string, chunkSize int64) RemoteFile
+	// Close encerra as conexões.
+	Close() error
+}
+
+// SQLExecutor implementa Executor sobre um *sql.DB do driver go-mssqldb.
+type SQLExecutor struct {
+	DB       *sql.DB
+	TLSState *mssql.TLSState
+}
+
+// OpenSQL prepara o pool de conexões com o servidor. A conexão só é
+// estabelecida de fato em Ping.
+func OpenSQL(opts mssql.ConnOptions) (*SQLExecutor, error) {
+	db, tlsState, err := mssql.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLExecutor{DB: db, TLSState: tlsState}, nil
+}
+
+func (e *SQLExecutor) Ping(ctx context.Context) error {
+	return mssql.ExplainTLSError(e.DB.PingContext(ctx))
+}
+
+func (e *SQLExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return e.DB.ExecContext(ctx, query, args...)
+}
+
+func (e *SQLExecutor) ExecBackup(ctx context.Context, stmt string, opts mssql.ProgressOptions) error {
+	return mssql.ExecWithProgress(ctx, e.DB, stmt, opts)
+}
+
+func (e *SQLExecutor) ServerInfo(ctx context.Context) (*mssql.ServerInfo, error) {
+	return mssql.QueryServerInfo(ctx, e.DB)
+}
+
+func (e *SQLExecutor) BackupSet(ctx context.Context, devicePath string) (*mssql.BackupSet, error) {
+	return mssql.QueryBackupSet(ctx, e.DB, devicePath)
+}
+
+func (e *SQLExecutor) EstimateBackupSize(ctx context.Context, database, backupType string, compressed bool) (*mssql.SizeEstimate, error) {
+	return mssql.EstimateBackupSize(ctx, e.DB, database, backupType, compressed)
+}
+
+// AppLock mantém uma conexão dedicada, dona do lock, até a liberação.
+func (e *SQLExecutor) AppLock(ctx context.Context, resource string) (func() error, error) {
+	conn, err := e.DB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("abrir conexão para o lock de aplicação falhou: %w", err)
+	}
+	if err := mssql.GetAppLock(ctx, conn, resource); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return func() error {
+		err := mssql.ReleaseAppLock(context.Background(), conn, resource)
+		if closeErr := conn.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+func (e *SQLExecutor) OpenRemoteFile(ctx context.Context, path string, chunkSize int64) RemoteFile {
+	return mssql.OpenRemoteFile(ctx, e.DB, path, chunkSize)
+}
+
+func (e *SQLExecutor) Close() error {
+	return e.DB.Close()
+}
+
+// LogSecurity registra o estado TLS negociado e a criptografia da sessão
+// segundo o servidor, para auditoria.
+func (e *SQLExecutor) LogSecurity(ctx context.Context, l *slog.Logger) {
+	if cs, ok := e.TLSState.Last(); ok {
+		attrs := []any{
+			slog.String("tls_version", tls.VersionName(cs.Version)),
+			slog.String("cipher_suite", tls.CipherSuiteName(cs.CipherSuite)),
+		}
+		if len(cs.PeerCertificates) > 0 {
+			attrs = append(attrs,
+				slog.String("server_cert_subject", cs.PeerCertificates[0].Subject.String()),
+				slog.String("server_cert_sha256", mssql.Fingerprint(cs.PeerCertificates[0].Raw)))
+		}
+		l.Info("TLS negociado com o servidor", attrs...)
+	}
+
+	sec, err := mssql.QueryConnSecurity(ctx, e.DB)
+	if err != nil {
+		l.Warn("Não foi possível verificar a criptografia da sessão", slog.Any("error", err))
+		return
+	}
+	if !sec.Encrypted {
+		l.Warn("A sessão com o SQL Server não está criptografada", slog.String("auth_scheme", sec.AuthScheme), slog.String("transport", sec.Transport))
+		return
+	}
+	l.Info("Sessão criptografada", slog.String("auth_scheme", sec.AuthScheme), slog.String("transport", sec.Transport))
+}
+
+// securityLogger é implementado por executores capazes de descrever a segurança da conexão.
+type securityLogger interface {
+	LogSecurity(ctx context.Context, l *slog.Logger)
+}
@@ -0,0 +1,96 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFilesJob(t *testing.T, p FilesOptions) *Job {
+	t.Helper()
+	opts := Options{
+		Engine:   EngineFiles,
+		Server:   "host01",
+		Database: "docs",
+		ZipDir:   t.TempDir(),
+		Archive:  archive.Options{Format: archive.FormatZip, Level: archive.DefaultLevel},
+	}
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return &Job{
+		Options: opts,
+		Engine:  NewFiles(p, opts, l),
+		Logger:  l,
+		Now:     func() time.Time { return time.Date(2025, 4, 7, 16, 45, 0, 0, time.UTC) },
+	}
+}
+
+func TestRun_Files(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"laudo.pdf":            "pdf",
+		"notas.txt":            "txt",
+		"2025/abril/exame.pdf": "exame",
+		"2025/abril/exame.tmp": "tmp",
+		"cache/antigo.pdf":     "cache",
+		"cache/sub/outro.pdf":  "cache",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	job := newFilesJob(t, FilesOptions{Root: root, Include: []string{"*.pdf"}, Exclude: []string{"cache"}})
+	result, err := job.Run(context.Background())
+	require.NoError(t, err)
+
+	zr, err := zip.OpenReader(result.ArchivePath)
+	require.NoError(t, err)
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{
+		"docs_20250407_164500/2025/abril/exame.pdf",
+		"docs_20250407_164500/laudo.pdf",
+		manifest.EntryName,
+	}, names)
+	assert.Equal(t, "files", result.Manifest.DumpFormat)
+	assert.Equal(t, int64(len("exame")+len("pdf")), result.Manifest.BakSize)
+}
+
+func TestRun_FilesNoneSelected(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notas.txt"), []byte("x"), 0644))
+
+	job := newFilesJob(t, FilesOptions{Root: root, Include: []string{"*.pdf"}})
+	_, err := job.Run(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "backup", Stage(err))
+	assert.Contains(t, err.Error(), "nenhum arquivo selecionado")
+}
+
+func TestTreeFileEntry_Shrunk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	require.NoError(t, os.WriteFile(path, []byte("0123456789"), 0644))
+
+	rc, size, err := treeFileEntry("log.txt", path).Open(context.Background())
+	require.NoError(t, err)
+	defer rc.Close()
+	assert.Equal(t, int64(10), size)
+	require.NoError(t, os.Truncate(path, 4))
+
+	_, err = io.ReadAll(rc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "diminuiu durante o backup")
+}
@@ -0,0 +1,238 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/lock"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mysql"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/process"
+)
+
+// MySQLOptions configura o Engine do MySQL/MariaDB.
+type MySQLOptions struct {
+	Conn   mysql.ConnOptions
+	BinDir string // Diretório de mysqldump e mariabackup (vazio = PATH)
+	Tool   string // mysqldump (padrão) ou mariabackup
+}
+
+// myEngine é o Engine do MySQL/MariaDB. A saída da ferramenta vai direto para
+// o compressor, sem arquivo intermediário: o dump é executado na fase de
+// compressão, quando a entrada retornada por Backup é aberta.
+type myEngine struct {
+	p    MySQLOptions
+	opts Options
+	l    *slog.Logger
+
+	db   *sql.DB
+	info *mysql.ServerInfo
+	m    *manifest.Manifest
+}
+
+// NewMySQL retorna o Engine do MySQL/MariaDB para o backup descrito em opts.
+func NewMySQL(p MySQLOptions, opts Options, l *slog.Logger) Engine {
+	if p.Tool == "" {
+		p.Tool = mysql.ToolMysqldump
+	}
+	return &myEngine{p: p, opts: opts, l: l, info: &mysql.ServerInfo{}}
+}
+
+func (e *myEngine) Connect(ctx context.Context) error {
+	e.l.Info("Conectando ao servidor MySQL...", slog.String("host", e.p.Conn.Host), slog.Int("port", e.p.Conn.Port))
+	db, err := mysql.Open(e.p.Conn)
+	if err != nil {
+		return err
+	}
+	e.db = db
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("conectar ao banco de dados falhou: %w", err)
+	}
+	info, err := mysql.QueryServerInfo(ctx, db)
+	if err != nil {
+		return err
+	}
+	e.info = info
+	if e.p.Tool == mysql.ToolMariabackup && !info.MariaDB {
+		return fmt.Errorf("mariabackup exige MariaDB (servidor %s); use -mysql-tool mysqldump", info.Version)
+	}
+	if !info.LogBin {
+		e.l.Warn("Log binário desativado no servidor; o manifesto não terá as coordenadas do binlog")
+	}
+	e.l.Info("Conexão estabelecida com sucesso.", slog.String("server_version", info.Version), slog.Bool("log_bin", info.LogBin))
+	return nil
+}
+
+func (e *myEngine) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return e.db.ExecContext(ctx, query, args...)
+}
+
+// AppLock usa GET_LOCK, mantido por uma conexão dedicada até a liberação.
+func (e *myEngine) AppLock(ctx context.Context, resource string) (func() error, error) {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("abrir conexão para o GET_LOCK falhou: %w", err)
+	}
+	ok, err := mysql.TryLock(ctx, conn, resource)
+	if err != nil || !ok {
+		_ = conn.Close()
+		if err == nil {
+			err = &lock.HeldError{Resource: "GET_LOCK " + resource}
+		}
+		return nil, err
+	}
+	return func() error {
+		err := mysql.ReleaseLock(context.Background(), conn, resource)
+		if closeErr := conn.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+// EstimateSize usa o tamanho de dados e índices do banco (ou da instância, no
+// mariabackup), um limite superior para o dump comprimido.
+func (e *myEngine) EstimateSize(ctx context.Context) (*SizeEstimate, error) {
+	database, source := e.p.Conn.Database, "data_size"
+	if e.p.Tool == mysql.ToolMariabackup {
+		database, source = "", "instance_size"
+	}
+	size, err := mysql.QueryDatabaseSize(ctx, e.db, database)
+	if err != nil {
+		return nil, err
+	}
+	return &SizeEstimate{Bytes: size, Source: source}, nil
+}
+
+// Backup apenas descreve a entrada do arquivo final; a ferramenta é executada
+// quando ela é aberta.
+func (e *myEngine) Backup(ctx context.Context, base string, m *manifest.Manifest) ([]Entry, error) {
+	e.m = m
+	m.ServerVersion = e.info.Version
+	name, format := base+".sql", "sql"
+	if e.p.Tool == mysql.ToolMariabackup {
+		name, format = base+".xb", "xbstream"
+	}
+	m.DumpFormat = format
+	e.l.Info("O backup será gravado diretamente no arquivo final",
+		slog.String("database", e.opts.Database),
+		slog.String("tool", e.p.Tool),
+		slog.String("filename_in_archive", name))
+	return []Entry{{Name: name, Path: e.p.Tool, Open: e.open}}, nil
+}
+
+// open inicia a ferramenta e retorna sua saída. O tempo limite da fase de
+// backup vale para o processo.
+func (e *myEngine) open(ctx context.Context) (io.ReadCloser, int64, error) {
+	ctx, cancel := phaseContext(ctx, e.opts.BackupTimeout)
+	optionFile, err := mysql.WriteOptionFile(e.p.Conn, e.info.MariaDB)
+	if err != nil {
+		cancel()
+		return nil, 0, err
+	}
+	var targetDir string
+	cleanup := func() {
+		cancel()
+		if err := os.Remove(optionFile); err != nil {
+			e.l.Warn("Não foi possível remover o arquivo de opções", slog.String("path", optionFile), slog.Any("error", err))
+		}
+		if targetDir != "" {
+			_ = os.RemoveAll(targetDir)
+		}
+	}
+
+	var args []string
+	var coords *mysql.Coordinates
+	var completedOK bool
+	if e.p.Tool == mysql.ToolMariabackup {
+		if targetDir, err = os.MkdirTemp("", "dbbackup-mariabackup-*"); err != nil {
+			cleanup()
+			return nil, 0, fmt.Errorf("criar diretório temporário do mariabackup falhou: %w", err)
+		}
+		args = mysql.MariabackupArgs(optionFile, targetDir)
+	} else {
+		args = mysql.DumpArgs(optionFile, e.p.Conn.Database, mysql.BinlogOption(e.info))
+	}
+
+	e.m.BackupStart = time.Now()
+	e.l.Info("Iniciando backup", slog.String("database", e.opts.Database), slog.String("tool", e.p.Tool))
+	stream, err := process.Start(ctx, process.Tool(e.p.BinDir, e.p.Tool), args, nil, func(line string) {
+		e.l.Debug("Saída do "+e.p.Tool, slog.String("message", line))
+		// O mariabackup informa as coordenadas e a conclusão na saída de erro
+		if c, ok := mysql.ParseMariabackupLine(line); ok {
+			coords = c
+		}
+		if strings.Contains(line, "completed OK!") {
+			completedOK = true
+		}
+	})
+	if err != nil {
+		cleanup()
+		return nil, 0, err
+	}
+
+	inspector := &mysql.DumpInspector{}
+	r := io.Reader(stream)
+	if e.p.Tool == mysql.ToolMysqldump {
+		r = io.TeeReader(stream, inspector)
+	}
+	return &toolStream{Reader: r, close: func() error {
+		defer cleanup()
+		if err := stream.Close(); err != nil {
+			return phaseError(ctx, "backup", err)
+		}
+		// Após o Close a saída de erro já foi lida por completo
+		if e.p.Tool == mysql.ToolMysqldump {
+			if !inspector.Completed() {
+				return fmt.Errorf("dump incompleto: a marca de conclusão do mysqldump não foi encontrada")
+			}
+			coords = inspector.Binlog()
+		} else if !completedOK {
+			return fmt.Errorf("mariabackup não informou a conclusão do backup (completed OK!)")
+		}
+		e.m.BackupFinish = time.Now()
+		e.recordBinlog(coords)
+		e.l.Info("Backup executado com sucesso.", slog.String("tool", e.p.Tool))
+		return nil
+	}}, -1, nil
+}
+
+// recordBinlog registra as coordenadas do log binário no manifesto.
+func (e *myEngine) recordBinlog(c *mysql.Coordinates) {
+	if c == nil {
+		if e.info.LogBin {
+			e.l.Warn("Coordenadas do log binário não encontradas na saída do " + e.p.Tool)
+		}
+		return
+	}
+	e.m.Binlog = &manifest.Binlog{File: c.File, Position: c.Position, GTID: c.GTID}
+	e.l.Info("Coordenadas do log binário registradas",
+		slog.String("file", c.File),
+		slog.Int64("position", c.Position),
+		slog.String("gtid", c.GTID))
+}
+
+// Verify não tem o que conferir antes da compressão: a conclusão do dump é
+// verificada sempre, ao fim da leitura.
+func (e *myEngine) Verify(ctx context.Context) error {
+	e.l.Info("A conclusão do backup é verificada durante a compressão", slog.String("tool", e.p.Tool))
+	return nil
+}
+
+// Cleanup não remove nada: o backup não gera arquivos intermediários.
+func (e *myEngine) Cleanup(ctx context.Context, mode string) error {
+	return nil
+}
+
+func (e *myEngine) Close() error {
+	if e.db == nil {
+		return nil
+	}
+	return e.db.Close()
+}
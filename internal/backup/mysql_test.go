@@ -0,0 +1,143 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMysqldump simula o mysqldump: grava o dump na saída padrão, com as
+// coordenadas do log binário no início. O banco "truncado" termina sem a marca
+// de conclusão e o banco "falha" termina com erro.
+const fakeMysqldump = `#!/bin/sh
+case "$1" in
+--defaults-extra-file=*) test -f "${1#--defaults-extra-file=}" || exit 7 ;;
+*) exit 8 ;;
+esac
+for db; do :; done
+echo "-- MySQL dump 10.13"
+echo "-- CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='binlog.000002', SOURCE_LOG_POS=157;"
+echo "INSERT INTO pacientes VALUES (1);"
+if [ "$db" = falha ]; then
+	echo "mysqldump: Got error: 2013: Lost connection to server" >&2
+	exit 2
+fi
+if [ "$db" != truncado ]; then
+	echo "-- Dump completed on 2025-04-07 16:45:12"
+fi
+`
+
+// offlineMySQL é o engine MySQL sem servidor: apenas a ferramenta (falsa) é
+// executada.
+type offlineMySQL struct{ *myEngine }
+
+func (e offlineMySQL) Connect(ctx context.Context) error { return nil }
+func (e offlineMySQL) Close() error                      { return nil }
+
+func newMySQLJob(t *testing.T, database string) *Job {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("ferramentas falsas escritas em sh")
+	}
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "mysqldump"), []byte(fakeMysqldump), 0755))
+	// O arquivo de opções é gravado no diretório temporário
+	t.Setenv("TMPDIR", t.TempDir())
+
+	opts := Options{
+		Engine:         EngineMySQL,
+		Server:         "my01",
+		Database:       database,
+		ZipDir:         t.TempDir(),
+		Verify:         true,
+		Archive:        archive.Options{Format: archive.FormatZip, Level: archive.DefaultLevel},
+		ConnectTimeout: time.Second,
+	}
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	my := NewMySQL(MySQLOptions{
+		Conn:   mysql.ConnOptions{Host: "my01", Port: mysql.DefaultPort, Database: database, User: "backup", Password: "x"},
+		BinDir: binDir,
+	}, opts, l).(*myEngine)
+	my.info = &mysql.ServerInfo{Version: "8.0.36", LogBin: true}
+	return &Job{
+		Options: opts,
+		Engine:  offlineMySQL{my},
+		Logger:  l,
+		Now:     func() time.Time { return time.Date(2025, 4, 7, 16, 45, 0, 0, time.UTC) },
+	}
+}
+
+func TestRun_MySQLDump(t *testing.T) {
+	job := newMySQLJob(t, "scm")
+
+	result, err := job.Run(context.Background())
+	require.NoError(t, err)
+
+	zr, err := zip.OpenReader(result.ArchivePath)
+	require.NoError(t, err)
+	defer zr.Close()
+	require.Len(t, zr.File, 2)
+	assert.Equal(t, "scm_20250407_164500.sql", zr.File[0].Name)
+	assert.Equal(t, manifest.EntryName, zr.File[1].Name)
+	f, err := zr.File[0].Open()
+	require.NoError(t, err)
+	dump, err := io.ReadAll(f)
+	f.Close()
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(dump), "-- Dump completed on 2025-04-07 16:45:12\n"))
+
+	// As coordenadas do binlog vão para o sidecar e para o manifesto embutido
+	m := result.Manifest
+	assert.Equal(t, EngineMySQL, m.Engine)
+	assert.Equal(t, "8.0.36", m.ServerVersion)
+	assert.Equal(t, "sql", m.DumpFormat)
+	assert.Equal(t, &manifest.Binlog{File: "binlog.000002", Position: 157}, m.Binlog)
+	assert.Equal(t, int64(len(dump)), m.BakSize)
+	sidecar, err := manifest.ReadFile(result.SidecarPath)
+	require.NoError(t, err)
+	assert.Equal(t, m.Binlog, sidecar.Binlog)
+
+	// O arquivo de opções com a senha é removido
+	tmp, err := os.ReadDir(os.Getenv("TMPDIR"))
+	require.NoError(t, err)
+	assert.Empty(t, tmp)
+}
+
+func TestRun_MySQLDumpFailures(t *testing.T) {
+	tests := []struct {
+		database string
+		wantErr  string
+	}{
+		{database: "falha", wantErr: "Lost connection to server"},
+		{database: "truncado", wantErr: "dump incompleto"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.database, func(t *testing.T) {
+			job := newMySQLJob(t, tt.database)
+
+			_, err := job.Run(context.Background())
+			require.Error(t, err)
+			assert.Equal(t, "archive", Stage(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+
+			// Nenhum arquivo parcial fica em -zip-dir
+			entries, err := os.ReadDir(job.Options.ZipDir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
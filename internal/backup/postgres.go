@@ -0,0 +1,270 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/lock"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/postgres"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/process"
+)
+
+// PostgresOptions configura o Engine do PostgreSQL.
+type PostgresOptions struct {
+	Conn   postgres.ConnOptions
+	BinDir string // Diretório de pg_dump, pg_restore e pg_basebackup (vazio = PATH)
+	Format string // custom (padrão), directory ou basebackup
+	Jobs   int    // Processos paralelos do pg_dump no formato directory
+	Stream bool   // Formato custom: a saída do pg_dump vai direto ao compressor, sem BackupDir
+}
+
+// pgEngine é o Engine do PostgreSQL: pg_dump ou pg_basebackup gravam o backup
+// em BackupDir, que precisa ser um diretório desta máquina. Com Stream, o dump
+// custom é executado na fase de compressão, como no MySQL.
+type pgEngine struct {
+	p    PostgresOptions
+	opts Options
+	l    *slog.Logger
+
+	db      *sql.DB
+	version string
+	out     string // Arquivo ou diretório do backup em BackupDir
+	m       *manifest.Manifest
+}
+
+// NewPostgres retorna o Engine do PostgreSQL para o backup descrito em opts.
+func NewPostgres(p PostgresOptions, opts Options, l *slog.Logger) Engine {
+	if p.Format == "" {
+		p.Format = postgres.FormatCustom
+	}
+	return &pgEngine{p: p, opts: opts, l: l}
+}
+
+func (e *pgEngine) Connect(ctx context.Context) error {
+	e.l.Info("Conectando ao servidor PostgreSQL...", slog.String("host", e.p.Conn.Host), slog.Int("port", e.p.Conn.Port))
+	db, err := postgres.Open(e.p.Conn)
+	if err != nil {
+		return err
+	}
+	e.db = db
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("conectar ao banco de dados falhou: %w", err)
+	}
+	if e.version, err = postgres.QueryServerVersion(ctx, db); err != nil {
+		e.l.Warn("Não foi possível obter a versão do PostgreSQL", slog.Any("error", err))
+	}
+	e.l.Info("Conexão estabelecida com sucesso.", slog.String("server_version", e.version))
+	return nil
+}
+
+func (e *pgEngine) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return e.db.ExecContext(ctx, query, args...)
+}
+
+// AppLock usa um lock consultivo de sessão, mantido por uma conexão dedicada
+// até a liberação.
+func (e *pgEngine) AppLock(ctx context.Context, resource string) (func() error, error) {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("abrir conexão para o lock consultivo falhou: %w", err)
+	}
+	key := postgres.AdvisoryLockKey(resource)
+	ok, err := postgres.TryAdvisoryLock(ctx, conn, key)
+	if err != nil || !ok {
+		_ = conn.Close()
+		if err == nil {
+			err = &lock.HeldError{Resource: "pg_advisory_lock " + resource}
+		}
+		return nil, err
+	}
+	return func() error {
+		err := postgres.AdvisoryUnlock(context.Background(), conn, key)
+		if closeErr := conn.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+// EstimateSize usa o tamanho em disco do banco (ou do cluster, no
+// basebackup), um limite superior para o dump.
+func (e *pgEngine) EstimateSize(ctx context.Context) (*SizeEstimate, error) {
+	database, source := e.p.Conn.Database, "database_size"
+	if e.p.Format == postgres.FormatBasebackup {
+		database, source = "", "cluster_size"
+	}
+	size, err := postgres.QueryDatabaseSize(ctx, e.db, database)
+	if err != nil {
+		return nil, err
+	}
+	return &SizeEstimate{Bytes: size, Source: source}, nil
+}
+
+// Backup executa pg_dump ou pg_basebackup. Em caso de falha, o que foi gravado
+// é removido.
+func (e *pgEngine) Backup(ctx context.Context, base string, m *manifest.Manifest) ([]Entry, error) {
+	if e.p.Stream {
+		e.m = m
+		m.ServerVersion = e.version
+		m.DumpFormat = e.p.Format
+		name := base + ".dump"
+		e.l.Info("O backup será gravado diretamente no arquivo final",
+			slog.String("database", e.opts.Database),
+			slog.String("tool", "pg_dump"),
+			slog.String("filename_in_archive", name))
+		return []Entry{{Name: name, Path: "pg_dump", Open: e.openDump}}, nil
+	}
+	if err := os.MkdirAll(e.opts.BackupDir, 0750); err != nil {
+		return nil, fmt.Errorf("criar diretório %s falhou: %w", e.opts.BackupDir, err)
+	}
+
+	var tool string
+	var args []string
+	switch e.p.Format {
+	case postgres.FormatBasebackup:
+		e.out = filepath.Join(e.opts.BackupDir, base+".basebackup")
+		tool, args = "pg_basebackup", postgres.BasebackupArgs(e.out)
+	case postgres.FormatDirectory:
+		e.out = filepath.Join(e.opts.BackupDir, base+".dir")
+		tool, args = "pg_dump", postgres.DumpArgs(e.p.Format, e.out, e.p.Jobs)
+	default:
+		e.out = filepath.Join(e.opts.BackupDir, base+".dump")
+		tool, args = "pg_dump", postgres.DumpArgs(e.p.Format, e.out, e.p.Jobs)
+	}
+
+	e.l.Info("Preparando para executar backup",
+		slog.String("database", e.opts.Database),
+		slog.String("tool", tool),
+		slog.String("format", e.p.Format),
+		slog.String("output", e.out))
+	err := process.Run(ctx, process.Tool(e.p.BinDir, tool), args, e.p.Conn.Env(), func(line string) {
+		e.l.Debug("Saída do "+tool, slog.String("message", line))
+	})
+	if err != nil {
+		if rmErr := os.RemoveAll(e.out); rmErr != nil {
+			e.l.Warn("Não foi possível remover o backup parcial", slog.String("path", e.out), slog.Any("error", rmErr))
+		}
+		return nil, err
+	}
+	e.l.Info("Backup executado com sucesso.", slog.String("tool", tool))
+
+	m.ServerVersion = e.version
+	m.DumpFormat = e.p.Format
+	if e.p.Format == postgres.FormatBasebackup {
+		if bm, err := postgres.ReadBackupManifest(filepath.Join(e.out, postgres.ManifestName)); err != nil {
+			e.l.Warn("Não foi possível ler o manifesto do pg_basebackup", slog.Any("error", err))
+		} else if n := len(bm.WALRanges); n > 0 {
+			m.FirstLSN = bm.WALRanges[0].StartLSN
+			m.LastLSN = bm.WALRanges[n-1].EndLSN
+		}
+	}
+
+	if e.p.Format == postgres.FormatCustom {
+		return []Entry{FileEntry(filepath.Base(e.out), e.out)}, nil
+	}
+	return treeEntries(e.out)
+}
+
+// openDump inicia o pg_dump com a saída na saída padrão. O tempo limite da
+// fase de backup vale para o processo.
+func (e *pgEngine) openDump(ctx context.Context) (io.ReadCloser, int64, error) {
+	ctx, cancel := phaseContext(ctx, e.opts.BackupTimeout)
+	e.m.BackupStart = time.Now()
+	e.l.Info("Iniciando backup", slog.String("database", e.opts.Database), slog.String("tool", "pg_dump"))
+	stream, err := process.Start(ctx, process.Tool(e.p.BinDir, "pg_dump"), postgres.DumpArgs(e.p.Format, "", 0), e.p.Conn.Env(), func(line string) {
+		e.l.Debug("Saída do pg_dump", slog.String("message", line))
+	})
+	if err != nil {
+		cancel()
+		return nil, 0, err
+	}
+	return &toolStream{Reader: stream, close: func() error {
+		defer cancel()
+		if err := stream.Close(); err != nil {
+			return phaseError(ctx, "backup", err)
+		}
+		e.m.BackupFinish = time.Now()
+		e.l.Info("Backup executado com sucesso.", slog.String("tool", "pg_dump"))
+		return nil
+	}}, -1, nil
+}
+
+// treeEntries retorna uma entrada por arquivo de dir, com nomes relativos ao
+// diretório pai (ex: DB_timestamp.dir/toc.dat), em ordem lexical.
+func treeEntries(dir string) ([]Entry, error) {
+	var entries []Entry
+	root := filepath.Dir(dir)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, FileEntry(path.Clean(filepath.ToSlash(rel)), p))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listar arquivos de %s falhou: %w", dir, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("nenhum arquivo gravado em %s", dir)
+	}
+	return entries, nil
+}
+
+// Verify lê o índice do dump com pg_restore --list ou, no basebackup, confere
+// os arquivos com o backup_manifest.
+func (e *pgEngine) Verify(ctx context.Context) error {
+	if e.p.Stream {
+		// O pg_dump em fluxo só termina com sucesso após gravar o dump inteiro
+		e.l.Info("O resultado do pg_dump é verificado durante a compressão")
+		return nil
+	}
+	if e.p.Format == postgres.FormatBasebackup {
+		e.l.Info("Verificando backup com o backup_manifest")
+		if err := postgres.VerifyBaseBackup(e.out); err != nil {
+			return fmt.Errorf("verificação do pg_basebackup falhou: %w", err)
+		}
+	} else {
+		e.l.Info("Verificando backup (pg_restore --list)")
+		if err := process.Run(ctx, process.Tool(e.p.BinDir, "pg_restore"), postgres.RestoreListArgs(e.out), nil, nil); err != nil {
+			return err
+		}
+	}
+	e.l.Info("Backup verificado com sucesso.")
+	return nil
+}
+
+// Cleanup remove o dump de BackupDir (CleanupLocal).
+func (e *pgEngine) Cleanup(ctx context.Context, mode string) error {
+	if mode != CleanupLocal {
+		return fmt.Errorf("limpeza %s não suportada pelo PostgreSQL", mode)
+	}
+	if e.out == "" {
+		return nil
+	}
+	if err := os.RemoveAll(e.out); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	e.l.Info("Dump removido de -backup-dir", slog.String("path", e.out))
+	return nil
+}
+
+func (e *pgEngine) Close() error {
+	if e.db == nil {
+		return nil
+	}
+	return e.db.Close()
+}
@@ -0,0 +1,146 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePgDump simula o pg_dump: grava em --file um arquivo (custom) ou um
+// diretório com o toc.dat e um arquivo por tabela (directory); sem --file, o
+// dump custom vai para a saída padrão. Falha se PGDATABASE for "falha",
+// deixando o dump pela metade.
+const fakePgDump = `#!/bin/sh
+for arg in "$@"; do
+	case "$arg" in
+	--file=*) out="${arg#--file=}" ;;
+	--format=*) format="${arg#--format=}" ;;
+	esac
+done
+echo "pg_dump: reading schemas" >&2
+if [ "$format" = directory ]; then
+	mkdir -p "$out" && echo toc > "$out/toc.dat" && echo dados > "$out/3456.dat"
+elif [ -z "$out" ]; then
+	echo "dump de $PGDATABASE"
+else
+	echo "dump de $PGDATABASE" > "$out"
+fi
+if [ "$PGDATABASE" = falha ]; then
+	echo "pg_dump: error: connection lost" >&2
+	exit 1
+fi
+`
+
+// fakePgRestore simula o pg_restore --list: falha se o dump não existir.
+const fakePgRestore = `#!/bin/sh
+test -e "$2"
+`
+
+// offlineEngine é o engine PostgreSQL sem servidor: apenas as ferramentas de
+// linha de comando (falsas) são executadas.
+type offlineEngine struct{ *pgEngine }
+
+func (e offlineEngine) Connect(ctx context.Context) error { return nil }
+func (e offlineEngine) Close() error                      { return nil }
+
+func newPostgresJob(t *testing.T, database, format string) *Job {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("ferramentas falsas escritas em sh")
+	}
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "pg_dump"), []byte(fakePgDump), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "pg_restore"), []byte(fakePgRestore), 0755))
+
+	opts := Options{
+		Engine:         EnginePostgres,
+		Server:         "pg01",
+		Database:       database,
+		BackupDir:      t.TempDir(),
+		ZipDir:         t.TempDir(),
+		Verify:         true,
+		Archive:        archive.Options{Format: archive.FormatZip, Level: archive.DefaultLevel},
+		ConnectTimeout: time.Second,
+		ServerCleanup:  CleanupLocal,
+	}
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	pg := NewPostgres(PostgresOptions{
+		Conn:   postgres.ConnOptions{Host: "pg01", Port: postgres.DefaultPort, Database: database},
+		BinDir: binDir,
+		Format: format,
+	}, opts, l).(*pgEngine)
+	return &Job{
+		Options: opts,
+		Engine:  offlineEngine{pg},
+		Logger:  l,
+		Now:     func() time.Time { return time.Date(2025, 4, 7, 16, 45, 0, 0, time.UTC) },
+	}
+}
+
+func zipNames(t *testing.T, path string) []string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestRun_PostgresCustom(t *testing.T) {
+	job := newPostgresJob(t, "scm", postgres.FormatCustom)
+
+	result, err := job.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(job.Options.ZipDir, "scm_20250407_164500.zip"), result.ArchivePath)
+	assert.Equal(t, []string{"scm_20250407_164500.dump", manifest.EntryName}, zipNames(t, result.ArchivePath))
+	assert.Equal(t, EnginePostgres, result.Manifest.Engine)
+	assert.Equal(t, postgres.FormatCustom, result.Manifest.DumpFormat)
+	require.Len(t, result.Manifest.Files, 1)
+
+	// -server-cleanup local remove o dump de -backup-dir
+	assert.NoFileExists(t, filepath.Join(job.Options.BackupDir, "scm_20250407_164500.dump"))
+}
+
+func TestRun_PostgresDirectory(t *testing.T) {
+	job := newPostgresJob(t, "scm", postgres.FormatDirectory)
+
+	result, err := job.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"scm_20250407_164500.dir/3456.dat",
+		"scm_20250407_164500.dir/toc.dat",
+		manifest.EntryName,
+	}, zipNames(t, result.ArchivePath))
+	assert.NoDirExists(t, filepath.Join(job.Options.BackupDir, "scm_20250407_164500.dir"))
+}
+
+func TestRun_PostgresDumpFailure(t *testing.T) {
+	job := newPostgresJob(t, "falha", postgres.FormatCustom)
+
+	_, err := job.Run(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "backup", Stage(err))
+	assert.Contains(t, err.Error(), "connection lost")
+
+	// O dump parcial é removido
+	entries, err := os.ReadDir(job.Options.BackupDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
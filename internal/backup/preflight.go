@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/diskspace"
+)
+
+// estimateMarginPercent é a folga somada à estimativa do backup, pelo
+// crescimento do banco desde o backup usado como referência.
+const estimateMarginPercent = 10
+
+// preflight interrompe o backup antes de começar se algum diretório de
+// destino não comportar o backup estimado ou não tiver o espaço livre mínimo.
+func (r *run) preflight(ctx context.Context) error {
+	var estimate uint64
+	if r.opts.CheckSpace {
+		est, err := r.engine.EstimateSize(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			r.l.Warn("Não foi possível estimar o tamanho do backup; verificando apenas o espaço livre mínimo", slog.Any("error", err))
+		} else {
+			estimate = uint64(est.Bytes) + uint64(est.Bytes)*estimateMarginPercent/100
+			r.job.Report.EstimatedSize = est.Bytes
+			r.l.Info("Tamanho do backup estimado",
+				slog.String("size", diskspace.FormatBytes(uint64(est.Bytes))),
+				slog.String("source", est.Source))
+		}
+	}
+
+	// O arquivo final nunca é maior que o .bak; se os dois ficam no mesmo
+	// diretório, ele precisa comportar ambos até a limpeza. Engines que enviam
+	// o backup direto ao compressor não usam BackupDir
+	required := map[string]uint64{r.opts.ZipDir: estimate}
+	if r.job.Upload != nil {
+		// No modo -stream o arquivo final vai para o Uploader, não para ZipDir
+		required[r.opts.ZipDir] = 0
+		if err := r.checkRemoteSpace(ctx, estimate); err != nil {
+			return err
+		}
+	}
+	if r.opts.BackupDir == "" {
+		r.l.Debug("Backup sem arquivos intermediários; apenas -zip-dir é verificado")
+	} else if r.opts.FetchMode != FetchBulk {
+		required[r.opts.BackupDir] += estimate
+	} else if estimate > 0 {
+		// No modo bulk BackupDir só existe no servidor
+		r.l.Debug("Espaço livre de -backup-dir não verificado no modo bulk", slog.String("dir", r.opts.BackupDir))
+	}
+
+	for _, dir := range []string{r.opts.BackupDir, r.opts.ZipDir} {
+		need, ok := required[dir]
+		if !ok {
+			continue
+		}
+		delete(required, dir)
+		if err := diskspace.Check(dir, max(need, uint64(r.opts.MinFreeSpace))); err != nil {
+			if need > 0 {
+				return fmt.Errorf("backup estimado em %s: %w", diskspace.FormatBytes(estimate), err)
+			}
+			return err
+		}
+		r.l.Debug("Espaço livre verificado", slog.String("dir", dir), slog.String("required", diskspace.FormatBytes(need)))
+	}
+	return nil
+}
+
+// checkRemoteSpace verifica a cota do destino do modo -stream, quando o
+// Uploader oferece essa verificação. Apenas a falta de espaço interrompe o
+// backup; uma falha na consulta é registrada e o backup segue.
+func (r *run) checkRemoteSpace(ctx context.Context, estimate uint64) error {
+	checker, ok := r.job.Upload.(spaceChecker)
+	if !ok || estimate == 0 {
+		return nil
+	}
+	err := checker.CheckSpace(ctx, int64(estimate))
+	var insufficient *diskspace.InsufficientError
+	switch {
+	case err == nil:
+		r.l.Debug("Espaço no destino remoto verificado", slog.String("required", diskspace.FormatBytes(estimate)))
+		return nil
+	case errors.As(err, &insufficient):
+		return fmt.Errorf("backup estimado em %s: %w", diskspace.FormatBytes(estimate), err)
+	case ctx.Err() != nil:
+		return err
+	default:
+		r.l.Warn("Não foi possível verificar o espaço no destino remoto", slog.Any("error", err))
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/retention"
+)
+
+func (r *run) cleanupEnabled() bool {
+	return (r.opts.ServerCleanup != "" && r.opts.ServerCleanup != CleanupNone) || r.opts.Retention.Enabled()
+}
+
+// retention remove os arquivos intermediários do servidor e os backups
+// antigos de ZipDir. O backup já foi publicado, então falhas aqui são apenas avisos.
+func (r *run) retention(ctx context.Context) error {
+	if r.opts.ServerCleanup != "" && r.opts.ServerCleanup != CleanupNone {
+		if err := r.engine.Cleanup(ctx, r.opts.ServerCleanup); err != nil {
+			r.warn("Não foi possível remover os arquivos do servidor", err)
+		}
+	}
+	// No modo -stream nenhum backup é gravado em ZipDir
+	if r.opts.Retention.Enabled() && r.job.Upload == nil {
+		r.pruneArchives()
+	}
+	return nil
+}
+
+// pruneArchives aplica a política de retenção aos backups deste banco em ZipDir.
+func (r *run) pruneArchives() {
+	sets, err := retention.Scan(r.opts.ZipDir, r.opts.Database)
+	if err != nil {
+		r.warn("Não foi possível listar os backups antigos", err)
+		return
+	}
+	// O backup recém-publicado conta para KeepLast, mas nunca é removido
+	expired := retention.Expired(sets, r.opts.Retention, r.job.Now())
+	expired = slices.DeleteFunc(expired, func(s retention.Set) bool { return s.Base == r.baseName })
+	if len(expired) == 0 {
+		return
+	}
+	removed, err := retention.Remove(expired)
+	for _, set := range expired {
+		r.l.Info("Backup antigo removido", slog.String("backup", set.Base), slog.Time("created_at", set.Time))
+	}
+	r.l.Info("Retenção aplicada", slog.Int("backups", len(expired)), slog.Int("files", len(removed)))
+	if err != nil {
+		r.warn("Falha ao remover backups antigos", err)
+	}
+}
+
+// warn registra um problema que não invalida o backup.
+func (r *run) warn(msg string, err error) {
+	r.l.Warn(msg, slog.Any("error", err))
+	r.job.Report.AddWarning(fmt.Sprintf("%s: %v", msg, err))
+	if r.job.Notify != nil {
+		r.job.Notify(fmt.Sprintf("%s: %v", msg, err))
+	}
+}
@@ -0,0 +1,136 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/sqlite"
+)
+
+// SQLiteOptions configura o Engine do SQLite.
+type SQLiteOptions struct {
+	Path   string // Arquivo do banco
+	Method string // backup (padrão) ou vacuum
+}
+
+// sqliteEngine é o Engine do SQLite: uma cópia consistente do banco, feita
+// sem interromper a aplicação, é gravada em BackupDir e removida ao final.
+type sqliteEngine struct {
+	p    SQLiteOptions
+	opts Options
+	l    *slog.Logger
+
+	db      *sql.DB
+	version string
+	out     string // Cópia do banco em BackupDir
+}
+
+// NewSQLite retorna o Engine do SQLite para o backup descrito em opts.
+func NewSQLite(p SQLiteOptions, opts Options, l *slog.Logger) Engine {
+	if p.Method == "" {
+		p.Method = sqlite.MethodBackup
+	}
+	return &sqliteEngine{p: p, opts: opts, l: l}
+}
+
+func (e *sqliteEngine) Connect(ctx context.Context) error {
+	e.l.Info("Abrindo banco SQLite...", slog.String("path", e.p.Path))
+	info, err := os.Stat(e.p.Path)
+	if err != nil {
+		return fmt.Errorf("banco SQLite inacessível: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s não é um arquivo", e.p.Path)
+	}
+	db, err := sqlite.Open(e.p.Path)
+	if err != nil {
+		return err
+	}
+	e.db = db
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("abrir banco SQLite falhou: %w", err)
+	}
+	if e.version, err = sqlite.Version(ctx, db); err != nil {
+		return err
+	}
+	e.l.Info("Banco aberto com sucesso.", slog.String("sqlite_version", e.version))
+	return nil
+}
+
+func (e *sqliteEngine) EstimateSize(ctx context.Context) (*SizeEstimate, error) {
+	size, err := sqlite.Size(ctx, e.db)
+	if err != nil {
+		return nil, err
+	}
+	return &SizeEstimate{Bytes: size, Source: "page_count"}, nil
+}
+
+// Backup grava a cópia do banco em BackupDir. Em caso de falha, a cópia
+// parcial é removida.
+func (e *sqliteEngine) Backup(ctx context.Context, base string, m *manifest.Manifest) ([]Entry, error) {
+	if err := os.MkdirAll(e.opts.BackupDir, 0750); err != nil {
+		return nil, fmt.Errorf("criar diretório %s falhou: %w", e.opts.BackupDir, err)
+	}
+	ext := filepath.Ext(e.p.Path)
+	if ext == "" {
+		ext = ".db"
+	}
+	e.out = filepath.Join(e.opts.BackupDir, base+ext)
+
+	e.l.Info("Copiando banco SQLite",
+		slog.String("source", e.p.Path),
+		slog.String("method", e.p.Method),
+		slog.String("output", e.out))
+	if err := sqlite.Snapshot(ctx, e.db, e.out, e.p.Method); err != nil {
+		e.removeSnapshot()
+		return nil, err
+	}
+	e.l.Info("Cópia do banco concluída.", slog.String("output", e.out))
+
+	m.ServerVersion = e.version
+	m.DumpFormat = e.p.Method
+	return []Entry{FileEntry(filepath.Base(e.out), e.out)}, nil
+}
+
+// Verify executa PRAGMA integrity_check na cópia.
+func (e *sqliteEngine) Verify(ctx context.Context) error {
+	e.l.Info("Verificando cópia (PRAGMA integrity_check)")
+	if err := sqlite.IntegrityCheck(ctx, e.out); err != nil {
+		return err
+	}
+	e.l.Info("Cópia verificada com sucesso.")
+	return nil
+}
+
+// Cleanup não remove nada: a cópia é sempre removida por Close.
+func (e *sqliteEngine) Cleanup(ctx context.Context, mode string) error {
+	return nil
+}
+
+// removeSnapshot remove a cópia do banco e o journal deixado por uma cópia
+// interrompida.
+func (e *sqliteEngine) removeSnapshot() {
+	if e.out == "" {
+		return
+	}
+	for _, p := range []string{e.out, e.out + "-journal"} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			e.l.Warn("Não foi possível remover a cópia do banco", slog.String("path", p), slog.Any("error", err))
+		}
+	}
+}
+
+func (e *sqliteEngine) Close() error {
+	e.removeSnapshot()
+	if e.db == nil {
+		return nil
+	}
+	return e.db.Close()
+}
@@ -0,0 +1,91 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_SQLite(t *testing.T) {
+	for _, method := range sqlite.Methods {
+		t.Run(method, func(t *testing.T) {
+			src := filepath.Join(t.TempDir(), "app.sqlite3")
+			db, err := sql.Open("sqlite", src)
+			require.NoError(t, err)
+			_, err = db.Exec("CREATE TABLE pacientes (id INTEGER PRIMARY KEY, nome TEXT); INSERT INTO pacientes (nome) VALUES ('a'), ('b')")
+			require.NoError(t, err)
+			// A conexão fica aberta durante o backup, como a da aplicação
+			defer db.Close()
+
+			opts := Options{
+				Engine:    EngineSQLite,
+				Server:    "host01",
+				Database:  "app",
+				BackupDir: t.TempDir(),
+				ZipDir:    t.TempDir(),
+				Verify:    true,
+				Archive:   archive.Options{Format: archive.FormatZip, Level: archive.DefaultLevel},
+			}
+			l := slog.New(slog.NewTextHandler(io.Discard, nil))
+			job := &Job{
+				Options: opts,
+				Engine:  NewSQLite(SQLiteOptions{Path: src, Method: method}, opts, l),
+				Logger:  l,
+				Now:     func() time.Time { return time.Date(2025, 4, 7, 16, 45, 0, 0, time.UTC) },
+			}
+
+			result, err := job.Run(context.Background())
+			require.NoError(t, err)
+
+			zr, err := zip.OpenReader(result.ArchivePath)
+			require.NoError(t, err)
+			defer zr.Close()
+			require.Len(t, zr.File, 2)
+			assert.Equal(t, "app_20250407_164500.sqlite3", zr.File[0].Name)
+			assert.Equal(t, manifest.EntryName, zr.File[1].Name)
+
+			m := result.Manifest
+			assert.Equal(t, EngineSQLite, m.Engine)
+			assert.Equal(t, method, m.DumpFormat)
+			assert.NotEmpty(t, m.ServerVersion)
+
+			// A cópia intermediária é removida de -backup-dir
+			entries, err := os.ReadDir(opts.BackupDir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestRun_SQLiteMissing(t *testing.T) {
+	opts := Options{
+		Engine:    EngineSQLite,
+		Server:    "host01",
+		Database:  "app",
+		BackupDir: t.TempDir(),
+		ZipDir:    t.TempDir(),
+		Archive:   archive.Options{Format: archive.FormatZip, Level: archive.DefaultLevel},
+	}
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	job := &Job{
+		Options: opts,
+		Engine:  NewSQLite(SQLiteOptions{Path: filepath.Join(t.TempDir(), "app.db")}, opts, l),
+		Logger:  l,
+	}
+
+	_, err := job.Run(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "connect", Stage(err))
+}
@@ -0,0 +1,261 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/lock"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mssql"
+)
+
+// sqlServer é o Engine do SQL Server: o BACKUP grava os .bak em BackupDir,
+// que são lidos pelo caminho local ou pela conexão (modo bulk).
+type sqlServer struct {
+	connect  func(ctx context.Context) (Executor, error)
+	opts     Options
+	l        *slog.Logger
+	progress *mssql.ProgressTracker
+
+	exec        Executor
+	bakNames    []string // Um .bak por stripe
+	devicePaths []string // Caminhos usados no TO DISK (separador do Windows)
+	localPaths  []string // Mesmos arquivos vistos por esta máquina (modo local)
+}
+
+func newSQLServer(j *Job) *sqlServer {
+	return &sqlServer{connect: j.Connect, opts: j.Options, l: j.Logger, progress: j.Progress}
+}
+
+func (s *sqlServer) Connect(ctx context.Context) error {
+	s.l.Info("Conectando ao servidor SQL Server...", slog.String("server", s.opts.Server))
+	exec, err := s.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("preparar conexão falhou: %w", err)
+	}
+	s.exec = exec
+	if err := exec.Ping(ctx); err != nil {
+		return fmt.Errorf("conectar ao banco de dados falhou: %w", err)
+	}
+	s.l.Info("Conexão estabelecida com sucesso.")
+	if sl, ok := exec.(securityLogger); ok {
+		sl.LogSecurity(ctx, s.l)
+	}
+	return nil
+}
+
+func (s *sqlServer) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.exec.ExecContext(ctx, query, args...)
+}
+
+func (s *sqlServer) AppLock(ctx context.Context, resource string) (func() error, error) {
+	release, err := s.exec.AppLock(ctx, resource)
+	if errors.Is(err, mssql.ErrAppLockHeld) {
+		return nil, &lock.HeldError{Resource: "sp_getapplock " + resource}
+	}
+	return release, err
+}
+
+func (s *sqlServer) EstimateSize(ctx context.Context) (*SizeEstimate, error) {
+	est, err := s.exec.EstimateBackupSize(ctx, s.opts.Database, s.backupType(), s.opts.SQLCompression)
+	if err != nil {
+		return nil, err
+	}
+	return &SizeEstimate{Bytes: est.Bytes, Source: est.Source}, nil
+}
+
+func (s *sqlServer) backupType() string {
+	if s.opts.BackupType == "" {
+		return mssql.BackupFull
+	}
+	return s.opts.BackupType
+}
+
+// Backup executa o comando BACKUP e coleta os metadados para o manifesto.
+func (s *sqlServer) Backup(ctx context.Context, base string, m *manifest.Manifest) ([]Entry, error) {
+	backupType := s.backupType()
+	s.bakNames = StripeFilenames(base, s.opts.Stripes)
+	s.devicePaths = make([]string, len(s.bakNames))
+	s.localPaths = make([]string, len(s.bakNames))
+	for i, name := range s.bakNames {
+		s.devicePaths[i] = DevicePath(s.opts.BackupDir, name)
+		s.localPaths[i] = filepath.ToSlash(filepath.Join(s.opts.BackupDir, name))
+	}
+	stmt, err := mssql.BackupStatement(mssql.BackupOptions{
+		Database:        s.opts.Database,
+		Type:            backupType,
+		Disks:           s.devicePaths,
+		Compression:     s.opts.SQLCompression,
+		MaxTransferSize: s.opts.MaxTransferSize,
+		BufferCount:     s.opts.BufferCount,
+		Stats:           s.opts.Stats,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("montar o comando de backup falhou: %w", err)
+	}
+
+	s.l.Info("Preparando para executar backup",
+		slog.String("database", s.opts.Database),
+		slog.String("type", backupType),
+		slog.Any("backup_paths_on_server", s.devicePaths))
+	s.l.Debug("Comando SQL de Backup", slog.String("sql", stmt))
+
+	err = s.exec.ExecBackup(ctx, stmt, mssql.ProgressOptions{
+		Interval: s.opts.ProgressInterval,
+		Tracker:  s.progress,
+		OnSession: func(sessionID int) {
+			s.l.Info("Executando backup no servidor", slog.Int("session_id", sessionID))
+		},
+		OnProgress: func(p mssql.Progress) {
+			s.l.Info("Progresso do backup",
+				slog.String("percent_complete", fmt.Sprintf("%.1f%%", p.PercentComplete)),
+				slog.Duration("elapsed", p.Elapsed.Round(time.Second)),
+				slog.Duration("estimated_remaining", p.EstimatedRemaining.Round(time.Second)))
+		},
+		OnMessage: func(msg string) {
+			s.l.Info("Mensagem do servidor", slog.String("message", msg))
+		},
+		OnPollErr: func(err error) {
+			s.l.Debug("Não foi possível consultar o progresso do backup", slog.Any("error", err))
+		},
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			// A sessão recebeu um KILL em ExecBackup; o .bak interrompido não
+			// serve para restore
+			s.removePartialBakFiles()
+		}
+		return nil, fmt.Errorf("executar o comando de backup falhou: %w", err)
+	}
+	s.l.Info("Comando de backup executado com sucesso no servidor.")
+
+	m.BackupFinish = time.Now()
+	s.collectMetadata(ctx, m)
+
+	entries := make([]Entry, len(s.bakNames))
+	for i, name := range s.bakNames {
+		if s.opts.FetchMode == FetchBulk {
+			entries[i] = s.remoteEntry(name, s.devicePaths[i])
+		} else {
+			entries[i] = FileEntry(name, s.localPaths[i])
+		}
+	}
+	return entries, nil
+}
+
+// remoteEntry lê o .bak em blocos pela própria conexão SQL (modo bulk).
+func (s *sqlServer) remoteEntry(name, path string) Entry {
+	return Entry{Name: name, Path: path, Open: func(ctx context.Context) (io.ReadCloser, int64, error) {
+		s.l.Info("Lendo arquivo de backup pela conexão SQL", slog.String("path", path), slog.Int64("chunk_size", s.opts.FetchChunkSize))
+		remote := s.exec.OpenRemoteFile(ctx, path, s.opts.FetchChunkSize)
+		size := int64(-1)
+		if s.opts.Archive.Container(len(s.bakNames)) && s.opts.Archive.Format != archive.FormatZip {
+			// Entradas de tar exigem o tamanho antecipadamente
+			var err error
+			if size, err = remote.Size(); err != nil {
+				_ = remote.Close()
+				return nil, 0, fmt.Errorf("consultar tamanho do .bak no servidor falhou: %w", err)
+			}
+		}
+		return remote, size, nil
+	}}
+}
+
+// collectMetadata preenche o manifesto. Falhas aqui não invalidam o backup; o
+// manifesto apenas fica sem esses campos.
+func (s *sqlServer) collectMetadata(ctx context.Context, m *manifest.Manifest) {
+	if serverInfo, err := s.exec.ServerInfo(ctx); err != nil {
+		s.l.Warn("Não foi possível obter a versão do SQL Server", slog.Any("error", err))
+	} else {
+		if serverInfo.Name != "" {
+			m.Server = serverInfo.Name
+		}
+		m.SQLServerVersion = serverInfo.Version
+	}
+	if backupSet, err := s.exec.BackupSet(ctx, s.devicePaths[0]); err != nil {
+		s.l.Warn("Não foi possível obter os dados do backup em msdb", slog.Any("error", err))
+	} else {
+		m.BackupType = backupSet.Type
+		m.FirstLSN = backupSet.First
+		m.LastLSN = backupSet.Last
+		m.BackupStart = backupSet.Start
+		m.BackupFinish = backupSet.Finish
+	}
+}
+
+// Verify confere o backup no servidor com RESTORE VERIFYONLY.
+func (s *sqlServer) Verify(ctx context.Context) error {
+	stmt, err := mssql.VerifyStatement(s.devicePaths)
+	if err != nil {
+		return err
+	}
+	s.l.Info("Verificando backup no servidor (RESTORE VERIFYONLY)")
+	if _, err := s.exec.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("RESTORE VERIFYONLY falhou: %w", err)
+	}
+	s.l.Info("Backup verificado com sucesso.")
+	return nil
+}
+
+// Cleanup remove os .bak do servidor pelo caminho local ou pelo próprio SQL
+// Server (xp_delete_file).
+func (s *sqlServer) Cleanup(ctx context.Context, mode string) error {
+	var errs []error
+	switch mode {
+	case CleanupLocal:
+		for _, path := range s.localPaths {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+				continue
+			}
+			s.l.Info(".bak removido do servidor", slog.String("path", path))
+		}
+	case CleanupXP:
+		for _, path := range s.devicePaths {
+			stmt, err := mssql.DeleteBackupFileStatement(path)
+			if err == nil {
+				_, err = s.exec.ExecContext(ctx, stmt)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("xp_delete_file %s: %w", path, err))
+				continue
+			}
+			s.l.Info(".bak removido do servidor (xp_delete_file)", slog.String("path", path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// removePartialBakFiles remove os .bak de um backup interrompido. Só é
+// possível no modo local; no modo bulk os arquivos ficam no servidor e são
+// apenas registrados.
+func (s *sqlServer) removePartialBakFiles() {
+	if s.opts.FetchMode == FetchBulk {
+		s.l.Warn("Arquivos .bak parciais permanecem no servidor e devem ser removidos manualmente", slog.Any("paths", s.devicePaths))
+		return
+	}
+	for _, path := range s.localPaths {
+		err := os.Remove(path)
+		switch {
+		case err == nil:
+			s.l.Info(".bak parcial removido", slog.String("path", path))
+		case !errors.Is(err, os.ErrNotExist):
+			s.l.Warn("Não foi possível remover o .bak parcial", slog.String("path", path), slog.Any("error", err))
+		}
+	}
+}
+
+func (s *sqlServer) Close() error {
+	if s.exec == nil {
+		return nil
+	}
+	return s.exec.Close()
+}
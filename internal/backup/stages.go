@@ -0,0 +1,515 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/hooks"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/lock"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mssql"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/version"
+)
+
+// run guarda o estado de uma execução entre os estágios.
+type run struct {
+	job    *Job
+	opts   Options
+	l      *slog.Logger
+	engine Engine
+
+	hookEnv hooks.Env
+
+	fileLock       *lock.FileLock
+	releaseAppLock func() error
+
+	baseName     string  // DB_timestamp
+	entries      []Entry // Arquivos do backup gerados pelo Engine
+	manifest     *manifest.Manifest
+	archiveName  string
+	archivePath  string
+	tmpPath      string
+	volumeWriter *archive.VolumeWriter
+	volumeSet    *archive.VolumeSet
+	archiveHash  *archive.HashingWriter
+	sidecarPath  string
+	upload       *streamUpload // Envio do arquivo final no modo -stream
+	remoteID     string        // Arquivo enviado pelo Uploader
+}
+
+func (r *run) close() {
+	if r.releaseAppLock != nil {
+		if err := r.releaseAppLock(); err != nil {
+			r.l.Warn("Erro ao liberar o lock de aplicação", slog.Any("error", err))
+		}
+	}
+	if r.fileLock != nil {
+		if err := r.fileLock.Release(); err != nil {
+			r.l.Warn("Erro ao liberar o lock", slog.Any("error", err))
+		}
+	}
+	if r.engine != nil {
+		_ = r.engine.Close()
+	}
+}
+
+// connect abre a conexão e verifica o acesso ao servidor. Sem Job.Engine, o
+// SQL Server é usado por meio de Job.Connect.
+func (r *run) connect(ctx context.Context) error {
+	r.engine = r.job.Engine
+	if r.engine == nil {
+		r.engine = newSQLServer(r.job)
+	}
+
+	connectCtx, cancel := phaseContext(ctx, r.opts.ConnectTimeout)
+	defer cancel()
+	if err := r.engine.Connect(connectCtx); err != nil {
+		return phaseError(connectCtx, "conexão", err)
+	}
+	if db, ok := r.engine.(sqlRunner); ok {
+		r.job.Hooks.DB = db
+	}
+	return nil
+}
+
+// lock impede que outra execução faça backup do mesmo banco ao mesmo tempo.
+// Com o lock em uso, o modo decide entre falhar, ignorar o backup ou esperar.
+func (r *run) lock(ctx context.Context) error {
+	opts := r.opts.Lock
+	dir := opts.Dir
+	if dir == "" {
+		dir = lock.DefaultDir
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return fmt.Errorf("criar diretório de lock %s falhou: %w", dir, err)
+		}
+	}
+	path := filepath.Join(dir, lock.FileName(r.opts.Database))
+
+	err := r.tryLock(ctx, path)
+	if !errors.Is(err, lock.ErrLocked) {
+		return err
+	}
+	switch opts.Mode {
+	case lock.ModeSkip:
+		return fmt.Errorf("%w: %v", ErrSkipped, err)
+	case lock.ModeWait:
+		r.l.Info("Aguardando outra execução do backup terminar", slog.Any("lock", err))
+		return lock.Wait(ctx, opts.WaitTimeout, func() error { return r.tryLock(ctx, path) })
+	default:
+		return err
+	}
+}
+
+// tryLock obtém o lock de arquivo e, se configurado, o lock de aplicação no
+// servidor, que também protege contra execuções em outras máquinas.
+func (r *run) tryLock(ctx context.Context, path string) error {
+	fl, err := lock.TryFile(path, r.opts.Database, r.opts.Lock.StaleAfter)
+	if err != nil {
+		return err
+	}
+	if fl.Stale != nil {
+		r.l.Warn("Lock abandonado removido", slog.String("path", path), slog.Int("pid", fl.Stale.PID), slog.String("host", fl.Stale.Host), slog.Time("started_at", fl.Stale.Started))
+	}
+
+	if r.opts.Lock.SQL {
+		locker, ok := r.engine.(appLocker)
+		if !ok {
+			_ = fl.Release()
+			return errors.New("o engine não oferece lock de aplicação no servidor")
+		}
+		release, err := locker.AppLock(ctx, lock.AppLockResource(r.opts.Database))
+		if err != nil {
+			_ = fl.Release()
+			return err
+		}
+		r.releaseAppLock = release
+	}
+
+	r.fileLock = fl
+	r.l.Debug("Lock obtido", slog.String("path", path), slog.Bool("sql", r.opts.Lock.SQL))
+	return nil
+}
+
+// preHooks executa os hooks pré-backup. Falhas só interrompem com Hooks.Fatal.
+func (r *run) preHooks(ctx context.Context) error {
+	if err := r.job.Hooks.Run(ctx, hooks.PhasePre, r.hookEnv); err != nil {
+		if r.job.Hooks.Fatal {
+			return err
+		}
+		r.hookWarning("Falha nos hooks pré-backup (backup continua)", err)
+	}
+	return nil
+}
+
+// postHooks executa os hooks pós-backup. Falhas só resultam em erro com Hooks.Fatal.
+func (r *run) postHooks(ctx context.Context) error {
+	r.hookEnv.Status = hooks.StatusSuccess
+	if err := r.job.Hooks.Run(ctx, hooks.PhasePostSuccess, r.hookEnv); err != nil {
+		if r.job.Hooks.Fatal {
+			return err
+		}
+		r.hookWarning("Backup concluído, mas os hooks pós-backup falharam", err)
+	}
+	return nil
+}
+
+func (r *run) hookWarning(msg string, err error) {
+	r.l.Error(msg, slog.Any("error", err))
+	r.job.Report.AddHookError(err)
+	if r.job.Notify != nil {
+		r.job.Notify(fmt.Sprintf("%s: %v", msg, err))
+	}
+}
+
+// backup executa o backup no Engine e prepara o manifesto.
+func (r *run) backup(ctx context.Context) error {
+	backupType := r.opts.BackupType
+	if backupType == "" {
+		backupType = mssql.BackupFull
+	}
+	r.baseName = BaseName(r.opts.Database, backupType, r.job.Now())
+	r.manifest = &manifest.Manifest{
+		ToolVersion: version.Version,
+		Server:      r.opts.Server,
+		Database:    r.opts.Database,
+		BackupType:  backupType,
+		Engine:      r.opts.Engine,
+		BackupStart: time.Now(),
+	}
+
+	backupCtx, cancel := phaseContext(ctx, r.opts.BackupTimeout)
+	defer cancel()
+	entries, err := r.engine.Backup(backupCtx, r.baseName, r.manifest)
+	if err != nil {
+		return phaseError(backupCtx, "backup", err)
+	}
+	if r.manifest.BackupFinish.IsZero() {
+		r.manifest.BackupFinish = time.Now()
+	}
+	r.entries = entries
+	return nil
+}
+
+// verify confere o backup gerado pelo Engine.
+func (r *run) verify(ctx context.Context) error {
+	verifyCtx, cancel := phaseContext(ctx, r.opts.BackupTimeout)
+	defer cancel()
+	if err := r.engine.Verify(verifyCtx); err != nil {
+		return phaseError(verifyCtx, "verificação", err)
+	}
+	return nil
+}
+
+// archive compacta os arquivos do backup no arquivo final (.tmp ou volumes). Em caso de
+// erro os arquivos parciais são removidos.
+func (r *run) archive(ctx context.Context) error {
+	archiveCtx, cancel := phaseContext(ctx, r.opts.ArchiveTimeout)
+	defer cancel()
+
+	if err := r.writeArchive(archiveCtx); err != nil {
+		r.removePartial()
+		return phaseError(archiveCtx, "compressão", err)
+	}
+	// Um sinal recebido durante o fechamento ainda impede a publicação do arquivo
+	if err := archiveCtx.Err(); err != nil {
+		r.removePartial()
+		return phaseError(archiveCtx, "compressão", fmt.Errorf("backup interrompido antes de publicar o arquivo final: %w", err))
+	}
+	return nil
+}
+
+func (r *run) writeArchive(ctx context.Context) error {
+	opts := r.opts.Archive
+	names := make([]string, len(r.entries))
+	for i, e := range r.entries {
+		names[i] = e.Name
+	}
+	r.archiveName = opts.FileName(r.baseName, names)
+	r.archivePath = filepath.Join(r.opts.ZipDir, r.archiveName)
+	r.tmpPath = filepath.Join(r.opts.ZipDir, r.baseName+".tmp")
+	r.hookEnv.Archive = r.archivePath
+
+	// Sem divisão o arquivo é gravado com nome .tmp e renomeado ao final. Com
+	// volumes as partes são gravadas direto no ZipDir e o manifesto de
+	// volumes, gravado por último, sinaliza que o conjunto está completo. No
+	// modo -stream o arquivo é enviado ao Uploader enquanto é comprimido, com
+	// o nome .tmp até a publicação, e nada é gravado em ZipDir.
+	var output io.Writer
+	var tmpFile *os.File
+	if r.job.Upload != nil {
+		r.l.Info("Enviando arquivo final durante a compressão", slog.String("archive", r.archiveName), slog.String("compression", string(opts.Format)))
+		r.tmpPath = ""
+		r.hookEnv.Archive = ""
+		r.upload = startUpload(ctx, r.job.Upload, r.baseName+".tmp")
+		output = r.upload.w
+	} else if r.opts.VolumeSize > 0 {
+		r.l.Info("Dividindo arquivo final em volumes", slog.String("archive", r.archiveName), slog.Int64("volume_size", r.opts.VolumeSize), slog.String("compression", string(opts.Format)))
+		vw, err := archive.NewVolumeWriter(r.opts.ZipDir, r.archiveName, r.opts.VolumeSize)
+		if err != nil {
+			return fmt.Errorf("preparar volumes falhou: %w", err)
+		}
+		r.volumeWriter = vw
+		output = vw
+	} else {
+		r.l.Info("Criando arquivo temporário", slog.String("path", r.tmpPath), slog.String("compression", string(opts.Format)))
+		f, err := os.Create(r.tmpPath)
+		if err != nil {
+			return fmt.Errorf("criar arquivo temporário %s falhou: %w", r.tmpPath, err)
+		}
+		defer f.Close()
+		tmpFile = f
+		output = f
+	}
+
+	// Calcula tamanho e SHA-256 do arquivo final para o manifesto sidecar
+	r.archiveHash = archive.NewHashingWriter(output)
+
+	// Zip e tar também recebem o manifesto como última entrada
+	embedManifest := opts.Container(len(r.entries))
+	entries := len(r.entries)
+	if embedManifest {
+		entries++
+	}
+	aw, err := archive.NewWriter(r.archiveHash, opts, entries)
+	if err != nil {
+		return fmt.Errorf("configurar compressão falhou: %w", err)
+	}
+
+	for _, e := range r.entries {
+		if err := r.addEntry(ctx, aw, e); err != nil {
+			return err
+		}
+	}
+
+	if embedManifest {
+		r.manifest.CreatedAt = time.Now()
+		data, err := r.manifest.Marshal()
+		if err == nil {
+			_, err = aw.Add(manifest.EntryName, int64(len(data)), bytes.NewReader(data))
+		}
+		if err != nil {
+			return fmt.Errorf("adicionar manifesto ao arquivo falhou: %w", err)
+		}
+	}
+
+	r.l.Debug("Finalizando compressão...")
+	if err := aw.Close(); err != nil {
+		return fmt.Errorf("finalizar arquivo compactado falhou: %w", err)
+	}
+	r.l.Debug("Compressão finalizada.")
+
+	if r.upload != nil {
+		id, err := r.upload.finish(nil)
+		r.remoteID = id
+		if err != nil {
+			return fmt.Errorf("enviar arquivo final falhou: %w", err)
+		}
+		r.l.Info("Arquivo final enviado", slog.String("id", id), slog.Int64("size", r.archiveHash.Size()))
+		return nil
+	}
+	if r.volumeWriter != nil {
+		set, err := r.volumeWriter.Close()
+		if err != nil {
+			return fmt.Errorf("finalizar volumes falhou: %w", err)
+		}
+		r.volumeSet = set
+		return nil
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("fechar arquivo temporário falhou: %w", err)
+	}
+	return nil
+}
+
+// addEntry lê um arquivo do backup e o adiciona ao arquivo final.
+func (r *run) addEntry(ctx context.Context, aw *archive.Writer, e Entry) error {
+	src, size, err := e.Open(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.l.Info("Comprimindo dados do backup...", slog.String("filename_in_archive", e.Name))
+	h := sha256.New()
+	n, err := aw.Add(e.Name, size, archive.NewContextReader(ctx, io.TeeReader(src, h)))
+	// Fontes em fluxo (ex: saída de um processo) só informam falhas ao fechar
+	closeErr := src.Close()
+	if err != nil {
+		return fmt.Errorf("comprimir dados de %s falhou: %w", e.Path, err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("ler %s falhou: %w", e.Path, closeErr)
+	}
+	r.l.Info("Dados copiados para o arquivo final", slog.String("filename_in_archive", e.Name), slog.Int64("bytes_copied", n))
+
+	r.manifest.Files = append(r.manifest.Files, manifest.File{Name: e.Name, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))})
+	r.manifest.BakSize += n
+	return nil
+}
+
+// finalize grava o manifesto sidecar e publica o arquivo final (rename do .tmp
+// ou manifesto de volumes).
+func (r *run) finalize(ctx context.Context) error {
+	// O sidecar é gravado antes do arquivo final aparecer com o nome
+	// definitivo, para que o uploader já o encontre ao enviar o backup
+	r.manifest.CreatedAt = time.Now()
+	r.manifest.Archive = &manifest.Archive{
+		Name:   r.archiveName,
+		Format: string(r.opts.Archive.Format),
+		Size:   r.archiveHash.Size(),
+		SHA256: r.archiveHash.SHA256(),
+	}
+	if r.volumeSet != nil {
+		r.manifest.Archive.VolumeCount = len(r.volumeSet.Volumes)
+	}
+	if r.upload != nil {
+		return r.publish(ctx)
+	}
+	r.sidecarPath = filepath.Join(r.opts.ZipDir, manifest.SidecarName(r.archiveName))
+	if err := r.manifest.WriteFile(r.sidecarPath); err != nil {
+		r.removePartial()
+		return fmt.Errorf("gravar manifesto do backup falhou: %w", err)
+	}
+	r.l.Info("Manifesto do backup gravado", slog.String("path", r.sidecarPath), slog.String("sha256", r.manifest.Archive.SHA256))
+
+	if r.volumeSet != nil {
+		manifestPath, err := archive.WriteVolumeManifest(r.opts.ZipDir, r.volumeSet)
+		if err != nil {
+			r.removePartial()
+			_ = os.Remove(r.sidecarPath)
+			return fmt.Errorf("gravar manifesto de volumes falhou: %w", err)
+		}
+		r.l.Info("Volumes gravados", slog.String("manifest", manifestPath), slog.Int("volumes", len(r.volumeSet.Volumes)))
+		r.hookEnv.Archive = manifestPath
+	} else {
+		r.l.Info("Renomeando arquivo temporário para final", slog.String("from", r.tmpPath), slog.String("to", r.archivePath))
+		if err := os.Rename(r.tmpPath, r.archivePath); err != nil {
+			_ = os.Remove(r.tmpPath)
+			_ = os.Remove(r.sidecarPath)
+			return fmt.Errorf("renomear arquivo final falhou: %w", err)
+		}
+	}
+
+	r.l.Info("Backup concluído e compactado com sucesso",
+		slog.String("database", r.opts.Database),
+		slog.String("compression", string(r.opts.Archive.Format)),
+		slog.String("archive_file", r.hookEnv.Archive))
+	r.reportArchive()
+	return nil
+}
+
+// publish envia o manifesto sidecar e dá ao arquivo já enviado o nome
+// definitivo, com os campos principais do manifesto como properties.
+func (r *run) publish(ctx context.Context) error {
+	u := r.job.Upload
+	data, err := r.manifest.Marshal()
+	if err != nil {
+		r.removePartial()
+		return fmt.Errorf("gerar manifesto do backup falhou: %w", err)
+	}
+	sidecarName := manifest.SidecarName(r.archiveName)
+	sidecarID, err := u.Upload(ctx, sidecarName, bytes.NewReader(data))
+	if err != nil {
+		r.removePartial()
+		return fmt.Errorf("enviar manifesto do backup falhou: %w", err)
+	}
+	r.l.Info("Manifesto do backup enviado", slog.String("name", sidecarName), slog.String("sha256", r.manifest.Archive.SHA256))
+
+	if err := u.Publish(ctx, r.remoteID, r.archiveName, r.manifest.DriveProperties()); err != nil {
+		r.removePartial()
+		if delErr := u.Delete(context.Background(), sidecarID); delErr != nil {
+			r.l.Warn("Não foi possível remover o manifesto enviado", slog.String("id", sidecarID), slog.Any("error", delErr))
+		}
+		return fmt.Errorf("publicar arquivo final falhou: %w", err)
+	}
+
+	r.l.Info("Backup concluído e enviado com sucesso",
+		slog.String("database", r.opts.Database),
+		slog.String("compression", string(r.opts.Archive.Format)),
+		slog.String("archive", r.archiveName),
+		slog.String("id", r.remoteID))
+	r.reportArchive()
+	r.job.Report.RemoteID = r.remoteID
+	return nil
+}
+
+// reportArchive registra o arquivo publicado no relatório.
+func (r *run) reportArchive() {
+	rep := r.job.Report
+	rep.BakSize = r.manifest.BakSize
+	rep.ArchivePath = r.hookEnv.Archive
+	rep.ArchiveSize = r.manifest.Archive.Size
+	rep.ArchiveSHA256 = r.manifest.Archive.SHA256
+	rep.VolumeCount = r.manifest.Archive.VolumeCount
+}
+
+// errArchiveAborted interrompe o envio do arquivo final após uma falha.
+var errArchiveAborted = errors.New("backup interrompido")
+
+// removePartial remove o arquivo temporário, os volumes incompletos ou o
+// arquivo enviado e ainda não publicado.
+func (r *run) removePartial() {
+	if r.upload != nil {
+		if id, _ := r.upload.finish(errArchiveAborted); id != "" {
+			r.remoteID = id
+		}
+		if r.remoteID == "" {
+			return
+		}
+		// O contexto da fase pode ter sido cancelado por sinal
+		if err := r.job.Upload.Delete(context.Background(), r.remoteID); err != nil {
+			r.l.Warn("Não foi possível remover o arquivo enviado", slog.String("id", r.remoteID), slog.Any("error", err))
+		}
+		r.remoteID = ""
+		return
+	}
+	if r.volumeWriter != nil {
+		r.volumeWriter.Abort()
+		return
+	}
+	if r.tmpPath != "" {
+		_ = os.Remove(r.tmpPath)
+	}
+}
+
+// BaseName retorna o nome base dos arquivos do backup (DB_timestamp). Backups
+// diferenciais e de log recebem o sufixo _diff ou _log, para não colidirem com
+// o full do mesmo horário.
+func BaseName(database, backupType string, t time.Time) string {
+	base := fmt.Sprintf("%s_%s", database, t.Format("20060102_150405"))
+	switch backupType {
+	case mssql.BackupDifferential:
+		base += "_diff"
+	case mssql.BackupLog:
+		base += "_log"
+	}
+	return base
+}
+
+// StripeFilenames retorna os nomes dos arquivos .bak do backup. Com um único
+// stripe mantém o nome tradicional (DB_timestamp.bak).
+func StripeFilenames(base string, stripes int) []string {
+	if stripes <= 1 {
+		return []string{base + ".bak"}
+	}
+	names := make([]string, stripes)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s_%dof%d.bak", base, i+1, stripes)
+	}
+	return names
+}
+
+// DevicePath retorna o caminho do .bak como usado no TO DISK, que é o mesmo
+// registrado em msdb.dbo.backupmediafamily.physical_device_name.
+func DevicePath(backupDir, bakFilename string) string {
+	return backupDir + "\\" + bakFilename
+}
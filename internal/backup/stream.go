@@ -0,0 +1,82 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Uploader envia o arquivo final a um destino remoto enquanto ele é comprimido
+// (modo -stream), sem gravá-lo em ZipDir. Como o .tmp do modo local, o arquivo
+// é enviado com um nome provisório e só recebe o nome definitivo em Publish,
+// depois do manifesto. Satisfeito por gdrive.DriveUploader.
+type Uploader interface {
+	// Upload envia o conteúdo de r até o fim como name e retorna o
+	// identificador do arquivo remoto. Um erro na leitura de r interrompe o
+	// envio sem criar o arquivo.
+	Upload(ctx context.Context, name string, r io.Reader) (id string, err error)
+	// Publish renomeia o arquivo id para name e anexa properties.
+	Publish(ctx context.Context, id, name string, properties map[string]string) error
+	// Delete remove o arquivo id.
+	Delete(ctx context.Context, id string) error
+}
+
+// spaceChecker é implementado por Uploaders capazes de verificar, antes do
+// backup, se o destino comporta size bytes.
+type spaceChecker interface {
+	CheckSpace(ctx context.Context, size int64) error
+}
+
+// errUploadEnded é a falha da compressão quando o envio termina antes do fim
+// do arquivo.
+var errUploadEnded = errors.New("envio do arquivo final terminou antes do fim da compressão")
+
+// streamUpload é o envio em andamento do arquivo final. A compressão grava em
+// w enquanto Uploader.Upload lê do outro lado do pipe, em outra goroutine.
+type streamUpload struct {
+	w    *io.PipeWriter
+	done chan struct{}
+	id   string
+	err  error
+}
+
+func startUpload(ctx context.Context, u Uploader, name string) *streamUpload {
+	pr, pw := io.Pipe()
+	s := &streamUpload{w: pw, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		s.id, s.err = u.Upload(ctx, name, pr)
+		// Sem leitor, a compressão falharia bloqueada na próxima escrita
+		if s.err != nil {
+			pr.CloseWithError(s.err)
+		} else {
+			pr.CloseWithError(errUploadEnded)
+		}
+	}()
+	return s
+}
+
+// finish sinaliza o fim do arquivo (err nil) ou interrompe o envio e espera o
+// Uploader terminar. Retorna o identificador do arquivo enviado, se o
+// Uploader chegou a criá-lo, que precisa ser removido em caso de erro.
+func (s *streamUpload) finish(err error) (string, error) {
+	if err != nil {
+		s.w.CloseWithError(err)
+	} else {
+		s.w.Close()
+	}
+	<-s.done
+	if s.err != nil {
+		return s.id, s.err
+	}
+	return s.id, err
+}
+
+// toolStream é a saída de uma ferramenta de backup lida pelo compressor; close
+// espera o processo e confere o resultado.
+type toolStream struct {
+	io.Reader
+	close func() error
+}
+
+func (s *toolStream) Close() error { return s.close() }
@@ -0,0 +1,227 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/diskspace"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mssql"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/postgres"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/report"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memUploader simula o Google Drive em memória. Como no envio retomável, um
+// erro na leitura interrompe o envio sem criar o arquivo.
+type memUploader struct {
+	mu    sync.Mutex
+	files map[string]*remoteFile
+	next  int
+
+	failAfter  int64 // Upload falha após ler n bytes (0 = nunca)
+	publishErr error
+	free       int64 // Cota livre informada por CheckSpace (0 = sem limite)
+}
+
+type remoteFile struct {
+	name  string
+	data  []byte
+	props map[string]string
+}
+
+func (u *memUploader) Upload(ctx context.Context, name string, r io.Reader) (string, error) {
+	if u.failAfter > 0 {
+		r = io.MultiReader(io.LimitReader(r, u.failAfter), failingReader{})
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.files == nil {
+		u.files = map[string]*remoteFile{}
+	}
+	u.next++
+	id := fmt.Sprintf("id%d", u.next)
+	u.files[id] = &remoteFile{name: name, data: data}
+	return id, nil
+}
+
+func (u *memUploader) Publish(ctx context.Context, id, name string, properties map[string]string) error {
+	if u.publishErr != nil {
+		return u.publishErr
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.files[id].name = name
+	u.files[id].props = properties
+	return nil
+}
+
+func (u *memUploader) Delete(ctx context.Context, id string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.files, id)
+	return nil
+}
+
+func (u *memUploader) CheckSpace(ctx context.Context, size int64) error {
+	if u.free > 0 && u.free < size {
+		return &diskspace.InsufficientError{Path: "memória", Free: uint64(u.free), Required: uint64(size)}
+	}
+	return nil
+}
+
+// byName retorna os arquivos enviados pelo nome.
+func (u *memUploader) byName() map[string]*remoteFile {
+	files := map[string]*remoteFile{}
+	for _, f := range u.files {
+		files[f.name] = f
+	}
+	return files
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("conexão com o destino perdida")
+}
+
+func TestRun_Stream(t *testing.T) {
+	exec := &fakeExecutor{}
+	job := newTestJob(t, exec)
+	u := &memUploader{}
+	job.Upload = u
+
+	result, err := job.Run(context.Background())
+	require.NoError(t, err)
+
+	// Nada é gravado em -zip-dir
+	entries, err := os.ReadDir(job.Options.ZipDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.Empty(t, result.ArchivePath)
+
+	files := u.byName()
+	require.Len(t, files, 2)
+	archiveFile := files["SCM_20250407_164500.zip"]
+	require.NotNil(t, archiveFile)
+	assert.Equal(t, "SCM", archiveFile.props["database"])
+	assert.Equal(t, result.RemoteID, job.Report.RemoteID)
+
+	zr, err := zip.NewReader(bytes.NewReader(archiveFile.data), int64(len(archiveFile.data)))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 2)
+	assert.Equal(t, "SCM_20250407_164500.bak", zr.File[0].Name)
+
+	// O sidecar enviado descreve o arquivo enviado
+	sidecar := files[manifest.SidecarName("SCM_20250407_164500.zip")]
+	require.NotNil(t, sidecar)
+	var m manifest.Manifest
+	require.NoError(t, json.Unmarshal(sidecar.data, &m))
+	sum := sha256.Sum256(archiveFile.data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), m.Archive.SHA256)
+	assert.Equal(t, int64(len(archiveFile.data)), m.Archive.Size)
+	assert.Equal(t, m.Archive.SHA256, job.Report.ArchiveSHA256)
+}
+
+func TestRun_StreamFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		uploader  *memUploader
+		wantStage string
+		wantErr   string
+	}{
+		{name: "envio interrompido", uploader: &memUploader{failAfter: 100}, wantStage: report.PhaseArchive, wantErr: "conexão com o destino perdida"},
+		{name: "publicação", uploader: &memUploader{publishErr: errors.New("permissão negada")}, wantStage: report.PhaseFinalize, wantErr: "permissão negada"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := newTestJob(t, &fakeExecutor{})
+			job.Upload = tt.uploader
+
+			_, err := job.Run(context.Background())
+			require.Error(t, err)
+			assert.Equal(t, tt.wantStage, Stage(err))
+			assert.Contains(t, err.Error(), tt.wantErr)
+
+			// Nenhum arquivo incompleto fica no destino
+			assert.Empty(t, tt.uploader.files)
+		})
+	}
+}
+
+func TestRun_StreamQuota(t *testing.T) {
+	exec := &fakeExecutor{estimate: &mssql.SizeEstimate{Bytes: 1 << 20, Source: mssql.EstimateHistory}}
+	job := newTestJob(t, exec)
+	job.Options.CheckSpace = true
+	job.Upload = &memUploader{free: 1 << 10}
+
+	_, err := job.Run(context.Background())
+	var insufficient *diskspace.InsufficientError
+	require.ErrorAs(t, err, &insufficient)
+	assert.Equal(t, report.PhasePreflight, Stage(err))
+	assert.Empty(t, exec.executed)
+}
+
+func TestRun_PostgresStream(t *testing.T) {
+	tests := []struct {
+		database string
+		wantErr  string
+	}{
+		{database: "scm"},
+		{database: "falha", wantErr: "connection lost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.database, func(t *testing.T) {
+			job := newPostgresJob(t, tt.database, postgres.FormatCustom)
+			pg := job.Engine.(offlineEngine).pgEngine
+			pg.p.Stream = true
+			pg.opts.BackupDir = ""
+			job.Options.BackupDir = ""
+			job.Options.Verify = false
+			job.Options.ServerCleanup = CleanupNone
+			u := &memUploader{}
+			job.Upload = u
+
+			result, err := job.Run(context.Background())
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, report.PhaseArchive, Stage(err))
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Empty(t, u.files)
+				return
+			}
+			require.NoError(t, err)
+
+			archiveFile := u.byName()["scm_20250407_164500.zip"]
+			require.NotNil(t, archiveFile)
+			zr, err := zip.NewReader(bytes.NewReader(archiveFile.data), int64(len(archiveFile.data)))
+			require.NoError(t, err)
+			require.Len(t, zr.File, 2)
+			assert.Equal(t, "scm_20250407_164500.dump", zr.File[0].Name)
+			f, err := zr.File[0].Open()
+			require.NoError(t, err)
+			dump, err := io.ReadAll(f)
+			f.Close()
+			require.NoError(t, err)
+			assert.Equal(t, "dump de scm\n", string(dump))
+			assert.Equal(t, postgres.FormatCustom, result.Manifest.DumpFormat)
+		})
+	}
+}
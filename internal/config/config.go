@@ -1,9 +1,31 @@
 package config
 
 import (
+	"cmp"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/backup"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/diskspace"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/filetree"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/hooks"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/lock"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mssql"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mysql"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/postgres"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/report"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/retention"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/scheduler"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/secret"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/sqlite"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/watcher"
 )
 
 // UpdloaderConfig armazena as configurações da aplicação carregadas via flags.
@@ -12,18 +34,266 @@ type UpdloaderConfig struct {
 	LogDir          string
 	CredentialsFile string
 	TokenFile       string
-	LogLevel        string // e.g., "debug", "info", "warn", "error"
+	LogLevel        string          // e.g., "debug", "info", "warn", "error"
+	WatchMode       string          // notify (eventos do sistema) ou poll (listagem periódica)
+	PollInterval    time.Duration   // Intervalo entre as listagens no modo poll
+	Recursive       bool            // Monitora também os subdiretórios de WatchDir
+	RoutesFile      string          // Arquivo JSON com os destinos por subdiretório
+	Routes          []watcher.Route // Rotas lidas de RoutesFile por ValidateUploaderFlags
+	Include         string          // Padrões dos arquivos enviados, separados por vírgula
+	Exclude         string          // Padrões dos arquivos ignorados, separados por vírgula
+	MinSize         string          // Tamanho mínimo do backup enviado (ex: 1M)
+	MaxSize         string          // Tamanho máximo do backup enviado (ex: 50G)
+	Filter          watcher.Filter  // Filtro montado de Include, Exclude, MinSize e MaxSize por ValidateUploaderFlags
 }
 
 type DbBackupConfig struct {
-	Server    string
-	Database  string
-	User      string
-	Password  string
-	BackupDir string // Diretório de backup no servidor SQL
-	ZipDir    string // Diretório local para salvar o zip
-	LogDir    string // Diretório para os logs do dbbackup
-	LogLevel  string // Nível de log (debug, info, warn, error)
+	Engine   string // Banco de origem (sqlserver, postgres)
+	Server   string
+	Database string
+	User     string
+	Password string
+	Auth     string // Modo de autenticação (sql, windows, kerberos, azure-sp, azure-msi, azure-default)
+
+	PasswordFile    string // Arquivo com a senha (alternativa a -password)
+	PasswordEnv     string // Variável de ambiente com a senha
+	PasswordKeyring string // Serviço no chaveiro do sistema onde a senha de -user está guardada
+	BackupDir       string // Diretório de backup no servidor SQL
+	ZipDir          string // Diretório local para salvar o zip
+	LogDir          string // Diretório para os logs do dbbackup
+	LogLevel        string // Nível de log (debug, info, warn, error)
+
+	Compression        string // Formato do arquivo final (zip, zstd, gzip, none)
+	CompressionLevel   int    // Nível de compressão (-1 = padrão do formato)
+	CompressionThreads int    // Goroutines de compressão (0 = número de CPUs)
+
+	SQLCompression  bool // Usa a compressão nativa do SQL Server (WITH COMPRESSION)
+	MaxTransferSize int  // MAXTRANSFERSIZE em bytes (0 = padrão do servidor)
+	BufferCount     int  // BUFFERCOUNT (0 = padrão do servidor)
+	Stripes         int  // Quantidade de arquivos .bak em que o backup é dividido
+	Verify          bool // Executa RESTORE VERIFYONLY após o backup
+
+	BackupType string // Tipo do backup avulso (full, differential, log)
+
+	Serve        bool              // Modo serviço (dbbackup serve), definido pela main
+	ScheduleFile string            // Arquivo JSON com os agendamentos do modo serviço
+	StateFile    string            // Estado do agendador (última execução de cada agendamento)
+	Jitter       time.Duration     // Atraso aleatório máximo de cada execução agendada
+	CatchUp      bool              // Executa na inicialização os agendamentos perdidos durante a parada
+	StatusAddr   string            // Endereço HTTP do status dos backups em andamento (vazio = desativado)
+	Schedules    []scheduler.Entry // Agendamentos lidos de ScheduleFile por ValidateBackupFlags
+
+	Stats            int           // STATS = n do BACKUP (mensagem a cada n%; 0 desativa)
+	ProgressInterval time.Duration // Intervalo entre consultas de progresso em sys.dm_exec_requests
+
+	ConnectTimeout time.Duration // Tempo limite para conectar ao servidor
+	BackupTimeout  time.Duration // Tempo limite do comando BACKUP (0 = sem limite)
+	ArchiveTimeout time.Duration // Tempo limite da compressão e gravação do arquivo final (0 = sem limite)
+
+	PreHook         string        // Comando executado antes do backup
+	PreSQL          string        // Script T-SQL executado antes do backup
+	PostSuccessHook string        // Comando executado após o backup concluído
+	PostSuccessSQL  string        // Script T-SQL executado após o backup concluído
+	PostFailureHook string        // Comando executado após uma falha
+	PostFailureSQL  string        // Script T-SQL executado após uma falha
+	HookTimeout     time.Duration // Tempo limite de cada hook
+	HookFatal       bool          // Falhas de hook interrompem o backup / resultam em erro
+
+	LockMode        string        // Comportamento com outra execução do mesmo banco em andamento (fail, skip, wait)
+	LockDir         string        // Diretório dos arquivos de lock (padrão: lock.DefaultDir)
+	LockWaitTimeout time.Duration // Espera máxima pelo lock no modo wait (0 = sem limite)
+	LockStaleAfter  time.Duration // Idade a partir da qual o lock de outro host é considerado abandonado
+	SQLLock         bool          // Também obtém um lock de aplicação (sp_getapplock) no servidor
+
+	ServerCleanup     string // Remoção dos .bak do servidor após o backup (none, local, xp)
+	KeepLast          int    // Backups mantidos em ZipDir por banco e tipo (0 = sem limite)
+	KeepDays          int    // Backups dos últimos n dias mantidos em ZipDir (0 = sem limite)
+	CheckSpace        bool   // Compara o tamanho estimado do backup com o espaço livre antes de começar
+	MinFreeSpace      string // Espaço livre mínimo antes do backup (ex: 20G); vazio = sem verificação
+	MinFreeSpaceBytes int64  // MinFreeSpace convertido em bytes por ValidateBackupFlags
+
+	ReportFile string // Arquivo do relatório JSON da execução ("-" = saída padrão; vazio = desativado)
+
+	VolumeSize      string // Tamanho máximo de cada volume do arquivo final (ex: 2G); vazio = sem divisão
+	VolumeSizeBytes int64  // VolumeSize convertido em bytes por ValidateBackupFlags
+
+	Stream          bool   // Envia o arquivo final ao Google Drive durante a compressão, sem gravá-lo em ZipDir
+	CredentialsFile string // credentials.json do Google OAuth2 (modo -stream)
+	TokenFile       string // Token OAuth2 do usuário (modo -stream)
+
+	FetchMode           string // Como o .bak é lido: "local" (caminho/compartilhamento) ou "bulk" (pela conexão SQL)
+	FetchChunkSize      string // Tamanho de cada bloco lido no modo bulk (ex: 8M)
+	FetchChunkSizeBytes int64  // FetchChunkSize convertido em bytes por ValidateBackupFlags
+
+	Krb5ConfigFile  string // krb5.conf usado na autenticação kerberos
+	Krb5Keytab      string // Keytab do usuário (autenticação kerberos)
+	Krb5CredCache   string // Cache de credenciais (alternativa ao keytab)
+	Krb5Realm       string // Realm Kerberos (ex: EMPRESA.LOCAL)
+	AzureTenantID   string // Tenant do service principal (azure-sp)
+	AzureClientCert string // Certificado do service principal (alternativa ao client secret)
+
+	Encrypt         string // Criptografia da conexão (strict, mandatory, optional, disable)
+	TrustServerCert bool   // Aceita qualquer certificado do servidor
+	TLSCAFile       string // Bundle de CAs para validar o certificado do servidor
+	TLSHostname     string // Nome esperado no certificado do servidor
+	TLSFingerprint  string // SHA-256 do certificado do servidor (fixação)
+
+	PGFormat  string // Formato do backup PostgreSQL (custom, directory, basebackup)
+	PGBinDir  string // Diretório de pg_dump, pg_restore e pg_basebackup (vazio = PATH)
+	PGJobs    int    // Processos paralelos do pg_dump no formato directory
+	PGSSLMode string // sslmode da conexão com o PostgreSQL
+
+	MySQLTool   string // Ferramenta de backup do MySQL/MariaDB (mysqldump, mariabackup)
+	MySQLBinDir string // Diretório de mysqldump e mariabackup (vazio = PATH)
+	MySQLTLS    string // TLS da conexão com o MySQL (preferred, true, skip-verify, false)
+
+	Source       string // Arquivo SQLite ou diretório de origem (engines sqlite e files)
+	SQLiteMethod string // Método de cópia do SQLite (backup, vacuum)
+	Include      string // Padrões glob (ou re:<regex>) dos arquivos incluídos, separados por vírgula (engine files)
+	Exclude      string // Padrões glob (ou re:<regex>) dos arquivos e diretórios excluídos, separados por vírgula (engine files)
+}
+
+// Hooks retorna os hooks configurados, um por fase que tenha comando ou script.
+func (c *DbBackupConfig) Hooks() []hooks.Hook {
+	candidates := []hooks.Hook{
+		{Phase: hooks.PhasePre, Command: c.PreHook, SQLFile: c.PreSQL},
+		{Phase: hooks.PhasePostSuccess, Command: c.PostSuccessHook, SQLFile: c.PostSuccessSQL},
+		{Phase: hooks.PhasePostFailure, Command: c.PostFailureHook, SQLFile: c.PostFailureSQL},
+	}
+	var configured []hooks.Hook
+	for _, h := range candidates {
+		if h.Command != "" || h.SQLFile != "" {
+			configured = append(configured, h)
+		}
+	}
+	return configured
+}
+
+// ConnOptions converte os flags de conexão em opções do pacote mssql.
+func (c *DbBackupConfig) ConnOptions() mssql.ConnOptions {
+	return mssql.ConnOptions{
+		Server:          c.Server,
+		Database:        c.Database,
+		User:            c.User,
+		Password:        c.Password,
+		Auth:            c.Auth,
+		Krb5ConfigFile:  c.Krb5ConfigFile,
+		Krb5Keytab:      c.Krb5Keytab,
+		Krb5CredCache:   c.Krb5CredCache,
+		Krb5Realm:       c.Krb5Realm,
+		AzureTenantID:   c.AzureTenantID,
+		AzureClientCert: c.AzureClientCert,
+		Encrypt:         c.Encrypt,
+		TrustServerCert: c.TrustServerCert,
+		TLSCAFile:       c.TLSCAFile,
+		TLSHostname:     c.TLSHostname,
+		TLSFingerprint:  c.TLSFingerprint,
+	}
+}
+
+// PostgresOptions converte os flags de conexão e do pg_dump em opções do engine
+// PostgreSQL. Deve ser chamado após ValidateBackupFlags.
+func (c *DbBackupConfig) PostgresOptions() backup.PostgresOptions {
+	host, port, _ := postgres.ParseServer(c.Server)
+	return backup.PostgresOptions{
+		Conn: postgres.ConnOptions{
+			Host:           host,
+			Port:           port,
+			Database:       c.Database,
+			User:           c.User,
+			Password:       c.Password,
+			SSLMode:        c.PGSSLMode,
+			ConnectTimeout: c.ConnectTimeout,
+		},
+		BinDir: c.PGBinDir,
+		Format: c.PGFormat,
+		Jobs:   c.PGJobs,
+		Stream: c.Stream,
+	}
+}
+
+// MySQLOptions converte os flags de conexão e da ferramenta em opções do engine
+// MySQL. Deve ser chamado após ValidateBackupFlags.
+func (c *DbBackupConfig) MySQLOptions() backup.MySQLOptions {
+	host, port, _ := mysql.ParseServer(c.Server)
+	return backup.MySQLOptions{
+		Conn: mysql.ConnOptions{
+			Host:           host,
+			Port:           port,
+			Database:       c.Database,
+			User:           c.User,
+			Password:       c.Password,
+			TLS:            c.MySQLTLS,
+			ConnectTimeout: c.ConnectTimeout,
+		},
+		BinDir: c.MySQLBinDir,
+		Tool:   c.MySQLTool,
+	}
+}
+
+// SQLiteOptions converte os flags em opções do engine SQLite para o banco em
+// source.
+func (c *DbBackupConfig) SQLiteOptions(source string) backup.SQLiteOptions {
+	return backup.SQLiteOptions{Path: source, Method: c.SQLiteMethod}
+}
+
+// FilesOptions converte os flags em opções do engine de árvore de arquivos
+// para o diretório source. Deve ser chamado após ValidateBackupFlags.
+func (c *DbBackupConfig) FilesOptions(source string) backup.FilesOptions {
+	return backup.FilesOptions{
+		Root:    source,
+		Include: filetree.SplitList(c.Include),
+		Exclude: filetree.SplitList(c.Exclude),
+	}
+}
+
+// ArchiveOptions converte os flags de compressão em opções do pacote archive.
+// Deve ser chamado após ValidateBackupFlags.
+func (c *DbBackupConfig) ArchiveOptions() archive.Options {
+	format, _ := archive.ParseFormat(c.Compression)
+	return archive.Options{
+		Format:      format,
+		Level:       c.CompressionLevel,
+		Concurrency: c.CompressionThreads,
+	}
+}
+
+// BackupOptions converte os flags em opções do pipeline de backup. Deve ser
+// chamado após ValidateBackupFlags.
+func (c *DbBackupConfig) BackupOptions() backup.Options {
+	return backup.Options{
+		Engine:           c.Engine,
+		Server:           c.Server,
+		Database:         c.Database,
+		BackupDir:        c.BackupDir,
+		ZipDir:           c.ZipDir,
+		BackupType:       c.BackupType,
+		Stripes:          c.Stripes,
+		SQLCompression:   c.SQLCompression,
+		MaxTransferSize:  c.MaxTransferSize,
+		BufferCount:      c.BufferCount,
+		Stats:            c.Stats,
+		Verify:           c.Verify,
+		FetchMode:        c.FetchMode,
+		FetchChunkSize:   c.FetchChunkSizeBytes,
+		Archive:          c.ArchiveOptions(),
+		VolumeSize:       c.VolumeSizeBytes,
+		ConnectTimeout:   c.ConnectTimeout,
+		BackupTimeout:    c.BackupTimeout,
+		ArchiveTimeout:   c.ArchiveTimeout,
+		ProgressInterval: c.ProgressInterval,
+		Lock: backup.LockOptions{
+			Mode:        c.LockMode,
+			Dir:         c.LockDir,
+			WaitTimeout: c.LockWaitTimeout,
+			StaleAfter:  c.LockStaleAfter,
+			SQL:         c.SQLLock,
+		},
+		CheckSpace:    c.CheckSpace,
+		MinFreeSpace:  c.MinFreeSpaceBytes,
+		ServerCleanup: c.ServerCleanup,
+		Retention:     retention.Policy{KeepLast: c.KeepLast, KeepDays: c.KeepDays},
+	}
 }
 
 // NewUploaderConfig define os flags de configuração da aplicação, lê seus valores
@@ -37,6 +307,12 @@ type DbBackupConfig struct {
 //	-credentials-file: Caminho para o arquivo de credenciais OAuth2 (obrigatório).
 //	-token-file: Caminho para o arquivo de token OAuth2 (obrigatório).
 //	-log-level: Nível de log (debug, info, warn, error).
+//	-watch-mode: notify (eventos do sistema) ou poll (listagem periódica).
+//	-poll-interval: Intervalo entre as listagens no modo poll.
+//	-recursive: Monitora também os subdiretórios.
+//	-routes-file: Arquivo JSON com os destinos por subdiretório (ativa -recursive).
+//	-include, -exclude: Padrões dos arquivos enviados e ignorados.
+//	-min-size, -max-size: Limites de tamanho dos backups enviados.
 //
 // Retorna um ponteiro para a struct Config preenchida e um erro se os valores
 // dos flags obrigatórios (após o parse) estiverem vazios.
@@ -50,6 +326,14 @@ func NewUploaderConfig() (*UpdloaderConfig, error) {
 	flag.StringVar(&cfg.CredentialsFile, "credentials-file", "credentials.json", "Caminho para o arquivo credentials.json do Google OAuth2.")
 	flag.StringVar(&cfg.TokenFile, "token-file", "token.json", "Caminho para salvar/carregar o token OAuth2 do usuário.")
 	flag.StringVar(&cfg.LogLevel, "log-level", "info", "Nível de log (debug, info, warn, error).")
+	flag.StringVar(&cfg.WatchMode, "watch-mode", watcher.ModeNotify, "Modo de monitoramento: notify (eventos do sistema) ou poll (listagem periódica, para compartilhamentos SMB/NFS).")
+	flag.DurationVar(&cfg.PollInterval, "poll-interval", watcher.DefaultPollInterval, "Intervalo entre as listagens no modo poll.")
+	flag.BoolVar(&cfg.Recursive, "recursive", false, "Monitora também os subdiretórios de -watch-dir, inclusive os criados depois.")
+	flag.StringVar(&cfg.RoutesFile, "routes-file", "", "Arquivo JSON que direciona cada subdiretório a uma pasta ou conta do Google Drive (ativa -recursive).")
+	flag.StringVar(&cfg.Include, "include", "", "Padrões glob (ou re:<regex>) dos arquivos enviados, separados por vírgula; vazio = extensões do dbbackup.")
+	flag.StringVar(&cfg.Exclude, "exclude", "", "Padrões glob (ou re:<regex>) dos arquivos e diretórios ignorados, separados por vírgula (ex: *.tmp,~$*).")
+	flag.StringVar(&cfg.MinSize, "min-size", "", "Tamanho mínimo do backup enviado, ex: 1M (vazio = sem mínimo).")
+	flag.StringVar(&cfg.MaxSize, "max-size", "", "Tamanho máximo do backup enviado, ex: 50G (vazio = sem máximo).")
 
 	return cfg, nil
 }
@@ -57,43 +341,274 @@ func NewUploaderConfig() (*UpdloaderConfig, error) {
 func NewDBBackupConfig() (*DbBackupConfig, error) {
 	cfg := &DbBackupConfig{}
 
-	flag.StringVar(&cfg.Server, "server", "", "Endereço do servidor SQL Server (ex: host\\instância ou host,porta)")
+	flag.StringVar(&cfg.Engine, "engine", backup.EngineSQLServer, "Banco de origem: "+strings.Join(backup.Engines, ", "))
+	flag.StringVar(&cfg.Server, "server", "", "Endereço do servidor (SQL Server: host\\instância ou host,porta; PostgreSQL: host, host:porta ou diretório do socket)")
 	flag.StringVar(&cfg.Database, "database", "", "Nome do banco de dados para backup")
 	flag.StringVar(&cfg.User, "user", "", "Usuário do SQL Server (necessário se não usar Windows Auth)")
 	flag.StringVar(&cfg.Password, "password", "", "Senha do SQL Server (necessário se não usar Windows Auth)")
+	flag.StringVar(&cfg.PasswordFile, "password-file", "", "Arquivo contendo a senha (evita expor a senha na lista de processos)")
+	flag.StringVar(&cfg.PasswordEnv, "password-env", "", "Nome da variável de ambiente que contém a senha")
+	flag.StringVar(&cfg.PasswordKeyring, "password-keyring", "", "Serviço do chaveiro do sistema (Credential Manager/Keychain/Secret Service) onde está a senha de -user")
+	flag.StringVar(&cfg.Auth, "auth", mssql.AuthSQL, "Modo de autenticação: "+strings.Join(mssql.AuthModes, ", "))
+	flag.StringVar(&cfg.Krb5ConfigFile, "krb5-config", "/etc/krb5.conf", "Arquivo krb5.conf (autenticação kerberos)")
+	flag.StringVar(&cfg.Krb5Keytab, "krb5-keytab", "", "Keytab do usuário (autenticação kerberos)")
+	flag.StringVar(&cfg.Krb5CredCache, "krb5-credcache", "", "Cache de credenciais Kerberos, alternativa ao keytab")
+	flag.StringVar(&cfg.Krb5Realm, "krb5-realm", "", "Realm Kerberos (ex: EMPRESA.LOCAL)")
+	flag.StringVar(&cfg.AzureTenantID, "azure-tenant-id", "", "Tenant do service principal (autenticação azure-sp)")
+	flag.StringVar(&cfg.AzureClientCert, "azure-client-cert", "", "Certificado do service principal, alternativa ao client secret (autenticação azure-sp)")
+	flag.StringVar(&cfg.Encrypt, "encrypt", mssql.EncryptMandatory, "Criptografia da conexão: "+strings.Join(mssql.EncryptModes, ", "))
+	flag.BoolVar(&cfg.TrustServerCert, "trust-server-cert", false, "Aceita qualquer certificado do servidor sem validação (não recomendado)")
+	flag.StringVar(&cfg.TLSCAFile, "tls-ca-file", "", "Bundle de CAs (PEM ou DER) usado para validar o certificado do servidor")
+	flag.StringVar(&cfg.TLSHostname, "tls-hostname", "", "Nome esperado no certificado do servidor, quando difere de -server")
+	flag.StringVar(&cfg.TLSFingerprint, "tls-fingerprint", "", "SHA-256 do certificado do servidor (fixação; ex: saída de openssl x509 -fingerprint -sha256)")
 	flag.StringVar(&cfg.BackupDir, "backup-dir", "", "Diretório NO SERVIDOR SQL SERVER onde o .bak será salvo (ex: C:\\Backups)")
 	flag.StringVar(&cfg.ZipDir, "zip-dir", ".", "Diretório local onde o arquivo .zip final será salvo")
 	flag.StringVar(&cfg.LogDir, "log-dir", "./logs", "Diretório para armazenar arquivos de log.")
 	flag.StringVar(&cfg.LogLevel, "log-level", "info", "Nível de log (debug, info, warn, error).")
+	flag.StringVar(&cfg.Compression, "compression", "zip", "Formato do arquivo final (zip, zstd, gzip, none)")
+	flag.IntVar(&cfg.CompressionLevel, "compression-level", archive.DefaultLevel, "Nível de compressão (zip/gzip: 0-9, zstd: 1-22, -1 = padrão do formato)")
+	flag.IntVar(&cfg.CompressionThreads, "compression-threads", 0, "Número de threads de compressão para zstd/gzip (0 = número de CPUs)")
+	flag.BoolVar(&cfg.SQLCompression, "sql-compression", false, "Usa a compressão nativa do SQL Server no backup (WITH COMPRESSION)")
+	flag.IntVar(&cfg.MaxTransferSize, "max-transfer-size", 0, "MAXTRANSFERSIZE do backup em bytes, múltiplo de 65536 até 4194304 (0 = padrão do servidor)")
+	flag.IntVar(&cfg.BufferCount, "buffer-count", 0, "BUFFERCOUNT do backup (0 = padrão do servidor)")
+	flag.IntVar(&cfg.Stripes, "stripes", 1, "Quantidade de arquivos .bak em que o backup é dividido (1 a 64)")
+	flag.StringVar(&cfg.BackupType, "backup-type", mssql.BackupFull, "Tipo do backup: "+strings.Join(mssql.BackupTypes, ", "))
+	flag.StringVar(&cfg.ScheduleFile, "schedule-file", "", "Arquivo JSON com os agendamentos (modo serve)")
+	flag.StringVar(&cfg.StateFile, "state-file", "dbbackup-state.json", "Arquivo de estado do agendador, usado para recuperar execuções perdidas (modo serve)")
+	flag.DurationVar(&cfg.Jitter, "jitter", 0, "Atraso aleatório máximo de cada execução agendada (modo serve)")
+	flag.BoolVar(&cfg.CatchUp, "catch-up", true, "Executa na inicialização os agendamentos perdidos enquanto o serviço estava parado (modo serve)")
+	flag.StringVar(&cfg.StatusAddr, "status-addr", "", "Endereço HTTP (ex: 127.0.0.1:8089) que expõe em /status os backups em andamento e seu progresso (modo serve)")
+	flag.BoolVar(&cfg.Verify, "verify", false, "Verifica o backup no servidor com RESTORE VERIFYONLY antes de compactar")
+	flag.IntVar(&cfg.Stats, "stats", 10, "Registra o progresso informado pelo servidor a cada n% do backup (STATS = n; 0 desativa)")
+	flag.DurationVar(&cfg.ProgressInterval, "progress-interval", mssql.DefaultProgressInterval, "Intervalo entre consultas de progresso do backup em sys.dm_exec_requests")
+	flag.DurationVar(&cfg.ConnectTimeout, "connect-timeout", 30*time.Second, "Tempo limite para conectar ao servidor SQL Server")
+	flag.DurationVar(&cfg.BackupTimeout, "backup-timeout", 0, "Tempo limite do comando BACKUP no servidor (ex: 2h; 0 = sem limite)")
+	flag.DurationVar(&cfg.ArchiveTimeout, "archive-timeout", 0, "Tempo limite da compressão e gravação do arquivo final (ex: 1h; 0 = sem limite)")
+	flag.StringVar(&cfg.PreHook, "pre-hook", "", "Comando executado antes do backup (ex: colocar a aplicação em manutenção)")
+	flag.StringVar(&cfg.PreSQL, "pre-sql", "", "Script T-SQL executado antes do backup (lotes separados por GO)")
+	flag.StringVar(&cfg.PostSuccessHook, "post-success-hook", "", "Comando executado após o backup concluído (ex: copiar para fita)")
+	flag.StringVar(&cfg.PostSuccessSQL, "post-success-sql", "", "Script T-SQL executado após o backup concluído")
+	flag.StringVar(&cfg.PostFailureHook, "post-failure-hook", "", "Comando executado após uma falha do backup")
+	flag.StringVar(&cfg.PostFailureSQL, "post-failure-sql", "", "Script T-SQL executado após uma falha do backup")
+	flag.DurationVar(&cfg.HookTimeout, "hook-timeout", hooks.DefaultTimeout, "Tempo limite de cada hook")
+	flag.BoolVar(&cfg.HookFatal, "hook-fatal", false, "Falha em hook pré-backup interrompe o backup e falha em hook pós-backup encerra com erro")
+	flag.StringVar(&cfg.LockMode, "lock-mode", lock.ModeFail, "Com outra execução do mesmo banco em andamento: fail (erro e notificação), skip (ignora o backup) ou wait (aguarda)")
+	flag.StringVar(&cfg.LockDir, "lock-dir", "", "Diretório dos arquivos de lock (padrão: dbbackup no diretório temporário do sistema)")
+	flag.DurationVar(&cfg.LockWaitTimeout, "lock-wait-timeout", 0, "Espera máxima pelo lock com -lock-mode wait (0 = sem limite)")
+	flag.DurationVar(&cfg.LockStaleAfter, "lock-stale-after", lock.DefaultStaleAfter, "Idade a partir da qual o lock de outro host é considerado abandonado")
+	flag.BoolVar(&cfg.SQLLock, "sql-lock", false, "Também obtém um lock no servidor (sp_getapplock no SQL Server, lock consultivo no PostgreSQL, GET_LOCK no MySQL), que protege contra execuções em outras máquinas")
+	flag.StringVar(&cfg.ServerCleanup, "server-cleanup", backup.CleanupNone, "Remoção dos .bak do servidor após o backup: none, local (pelo caminho de -backup-dir) ou xp (xp_delete_file no servidor)")
+	flag.IntVar(&cfg.KeepLast, "keep-last", 0, "Quantidade de backups mantidos em -zip-dir por banco e tipo (0 = sem limite)")
+	flag.IntVar(&cfg.KeepDays, "keep-days", 0, "Mantém em -zip-dir os backups dos últimos n dias (0 = sem limite)")
+	flag.BoolVar(&cfg.CheckSpace, "check-space", true, "Estima o tamanho do backup (histórico em msdb ou espaço em uso) e verifica antes de começar se -zip-dir e -backup-dir o comportam")
+	flag.StringVar(&cfg.MinFreeSpace, "min-free-space", "", "Espaço livre mínimo em -zip-dir (e -backup-dir no modo local) para iniciar o backup (ex: 20G)")
+	flag.StringVar(&cfg.ReportFile, "report-file", "", "Grava o relatório JSON da execução neste arquivo (\"-\" = saída padrão)")
+	flag.StringVar(&cfg.VolumeSize, "volume-size", "", "Divide o arquivo final em volumes deste tamanho (ex: 500M, 2G); vazio = sem divisão")
+	flag.StringVar(&cfg.FetchMode, "fetch-mode", "local", "Como ler o .bak: local (backup-dir acessível por esta máquina) ou bulk (lido pela conexão SQL via OPENROWSET; apenas bancos pequenos, até 2 GiB por arquivo)")
+	flag.StringVar(&cfg.FetchChunkSize, "fetch-chunk-size", "8M", "Tamanho de cada bloco lido no modo -fetch-mode bulk; aumentado para que o arquivo tenha no máximo 32 blocos")
+	flag.StringVar(&cfg.PGFormat, "pg-format", postgres.FormatCustom, "Formato do backup PostgreSQL: custom (pg_dump -Fc), directory (pg_dump -Fd) ou basebackup (pg_basebackup, cluster inteiro)")
+	flag.StringVar(&cfg.PGBinDir, "pg-bin-dir", "", "Diretório de pg_dump, pg_restore e pg_basebackup (vazio = PATH)")
+	flag.IntVar(&cfg.PGJobs, "pg-jobs", 1, "Processos paralelos do pg_dump com -pg-format directory")
+	flag.StringVar(&cfg.PGSSLMode, "pg-sslmode", "prefer", "sslmode da conexão com o PostgreSQL: "+strings.Join(postgres.SSLModes, ", "))
+	flag.StringVar(&cfg.MySQLTool, "mysql-tool", mysql.ToolMysqldump, "Ferramenta de backup do MySQL/MariaDB: mysqldump (dump lógico do banco) ou mariabackup (cópia física da instância)")
+	flag.StringVar(&cfg.MySQLBinDir, "mysql-bin-dir", "", "Diretório de mysqldump e mariabackup (vazio = PATH)")
+	flag.StringVar(&cfg.MySQLTLS, "mysql-tls", "preferred", "TLS da conexão com o MySQL: "+strings.Join(mysql.TLSModes, ", "))
+	flag.BoolVar(&cfg.Stream, "stream", false, "Envia o arquivo final ao Google Drive durante a compressão, sem gravá-lo em -zip-dir")
+	flag.StringVar(&cfg.CredentialsFile, "credentials-file", "credentials.json", "Caminho para o arquivo credentials.json do Google OAuth2 (-stream)")
+	flag.StringVar(&cfg.TokenFile, "token-file", "token.json", "Caminho para salvar/carregar o token OAuth2 do usuário (-stream)")
+	flag.StringVar(&cfg.Source, "source", "", "Arquivo do banco (-engine sqlite) ou diretório (-engine files) de origem")
+	flag.StringVar(&cfg.SQLiteMethod, "sqlite-method", sqlite.MethodBackup, "Cópia do SQLite: backup (API de backup online) ou vacuum (VACUUM INTO, cópia compactada)")
+	flag.StringVar(&cfg.Include, "include", "", "Padrões glob (ou re:<regex>) dos arquivos incluídos, separados por vírgula (ex: *.pdf,docs/**); vazio = todos (-engine files)")
+	flag.StringVar(&cfg.Exclude, "exclude", "", "Padrões glob (ou re:<regex>) dos arquivos e diretórios excluídos, separados por vírgula (ex: *.tmp,node_modules) (-engine files)")
 
 	return cfg, nil
 }
 
 // validateFlags realiza validações adicionais nos flags de configuração
 func ValidateBackupFlags(cfg *DbBackupConfig) {
+	if !slices.Contains(backup.Engines, cfg.Engine) {
+		fatalf("Flag -engine inválido '%s': use %s", cfg.Engine, strings.Join(backup.Engines, ", "))
+	}
+	// Nas fontes locais, -server e -database são apenas nomes: o host desta
+	// máquina e o nome do arquivo ou diretório de -source
+	if cfg.localSource() {
+		if cfg.Server == "" {
+			cfg.Server, _ = os.Hostname()
+		}
+		if cfg.Database == "" && cfg.Source != "" {
+			cfg.Database = SourceName(cfg.Source)
+		}
+	}
+
 	// Validação de campos obrigatórios
 	if cfg.Server == "" {
-		log.Fatal("Flag -server é obrigatório")
+		fatal("Flag -server é obrigatório")
+	}
+	if cfg.Serve {
+		// No modo serviço os bancos vêm dos agendamentos
+		if cfg.ScheduleFile == "" {
+			fatal("Flag -schedule-file é obrigatório no modo serve")
+		}
+		schedules, err := scheduler.LoadFile(cfg.ScheduleFile)
+		if err != nil {
+			fatalf("Agendamentos inválidos: %v", err)
+		}
+		cfg.Schedules = schedules
+		if cfg.Jitter < 0 {
+			fatal("Flag -jitter não pode ser negativo")
+		}
+	} else if cfg.Database == "" {
+		fatal("Flag -database é obrigatório")
 	}
-	if cfg.Database == "" {
-		log.Fatal("Flag -database é obrigatório")
+	if cfg.StatusAddr != "" && !cfg.Serve {
+		fatal("Flag -status-addr só se aplica ao modo serve")
 	}
-	if cfg.User == "" {
-		log.Fatal("Flag -user é obrigatório")
+	if !slices.Contains(mssql.BackupTypes, cfg.BackupType) {
+		fatalf("Flag -backup-type inválido '%s': use %s", cfg.BackupType, strings.Join(mssql.BackupTypes, ", "))
 	}
-	if cfg.Password == "" {
-		log.Fatal("Flag -password é obrigatório")
+	// Carrega a senha da fonte escolhida antes de validar a autenticação
+	if err := cfg.ResolvePassword(); err != nil {
+		fatalf("Erro ao obter a senha: %v", err)
+	}
+
+	switch cfg.Engine {
+	case backup.EnginePostgres:
+		validatePostgresFlags(cfg)
+	case backup.EngineMySQL:
+		validateMySQLFlags(cfg)
+	case backup.EngineSQLite, backup.EngineFiles:
+		validateSourceFlags(cfg)
+	default:
+		// -user e -password só são obrigatórios conforme o modo de autenticação
+		if err := cfg.ConnOptions().Validate(); err != nil {
+			fatalf("Flags de conexão inválidos (-auth %s, -encrypt %s): %v", cfg.Auth, cfg.Encrypt, err)
+		}
 	}
-	if cfg.BackupDir == "" {
-		log.Fatal("Flag -backup-dir é obrigatório")
+	if cfg.TrustServerCert {
+		log.Printf("Aviso: -trust-server-cert desativa a validação do certificado do servidor; prefira -tls-ca-file ou -tls-fingerprint.")
+	}
+	// O MySQL, a árvore de arquivos e o PostgreSQL com -stream vão direto ao
+	// compressor, sem arquivos em -backup-dir
+	pgStream := cfg.Engine == backup.EnginePostgres && cfg.Stream
+	if cfg.BackupDir == "" && cfg.Engine != backup.EngineMySQL && cfg.Engine != backup.EngineFiles && !pgStream {
+		fatal("Flag -backup-dir é obrigatório")
 	}
 	if cfg.ZipDir == "" {
-		log.Fatal("Flag -zip-dir é obrigatório")
+		fatal("Flag -zip-dir é obrigatório")
 	}
 	if cfg.LogDir == "" {
-		log.Fatal("Flag -log-dir é obrigatório")
+		fatal("Flag -log-dir é obrigatório")
 	}
 
+	// Validação da compressão
+	if _, err := archive.ParseFormat(cfg.Compression); err != nil {
+		fatalf("Flag -compression inválido: %v", err)
+	}
+	if err := cfg.ArchiveOptions().Validate(); err != nil {
+		fatalf("Flags de compressão inválidos: %v", err)
+	}
+
+	// Validação das opções nativas do BACKUP
+	if cfg.MaxTransferSize != 0 && (cfg.MaxTransferSize < 65536 || cfg.MaxTransferSize > 4194304 || cfg.MaxTransferSize%65536 != 0) {
+		fatal("Flag -max-transfer-size deve ser múltiplo de 65536 entre 65536 e 4194304")
+	}
+	if cfg.BufferCount < 0 {
+		fatal("Flag -buffer-count não pode ser negativo")
+	}
+	if cfg.Stripes < 1 || cfg.Stripes > 64 {
+		fatal("Flag -stripes deve estar entre 1 e 64")
+	}
+	if cfg.Stats < 0 || cfg.Stats > 100 {
+		fatal("Flag -stats deve estar entre 0 e 100")
+	}
+	if cfg.ProgressInterval < time.Second {
+		fatal("Flag -progress-interval deve ser de pelo menos 1s")
+	}
+
+	// Validação dos tempos limite
+	if cfg.ConnectTimeout <= 0 {
+		fatal("Flag -connect-timeout deve ser maior que zero")
+	}
+	if cfg.BackupTimeout < 0 || cfg.ArchiveTimeout < 0 {
+		fatal("Flags -backup-timeout e -archive-timeout não podem ser negativos")
+	}
+
+	// Validação do lock
+	if !slices.Contains(lock.Modes, cfg.LockMode) {
+		fatalf("Flag -lock-mode inválido '%s': use %s", cfg.LockMode, strings.Join(lock.Modes, ", "))
+	}
+	if cfg.LockWaitTimeout < 0 || cfg.LockStaleAfter < 0 {
+		fatal("Flags -lock-wait-timeout e -lock-stale-after não podem ser negativos")
+	}
+
+	// Validação da limpeza e da retenção
+	if !slices.Contains(backup.CleanupModes, cfg.ServerCleanup) {
+		fatalf("Flag -server-cleanup inválido '%s': use %s", cfg.ServerCleanup, strings.Join(backup.CleanupModes, ", "))
+	}
+	if cfg.ServerCleanup == backup.CleanupLocal && cfg.FetchMode == backup.FetchBulk {
+		fatal("Flag -server-cleanup local exige -fetch-mode local; use -server-cleanup xp")
+	}
+	if cfg.KeepLast < 0 || cfg.KeepDays < 0 {
+		fatal("Flags -keep-last e -keep-days não podem ser negativos")
+	}
+	if cfg.MinFreeSpace != "" {
+		size, err := diskspace.ParseSize(cfg.MinFreeSpace)
+		if err != nil {
+			fatalf("Flag -min-free-space inválido: %v", err)
+		}
+		cfg.MinFreeSpaceBytes = size
+	}
+
+	// Validação dos hooks
+	for _, h := range cfg.Hooks() {
+		if h.SQLFile == "" {
+			continue
+		}
+		if _, err := os.Stat(h.SQLFile); err != nil {
+			fatalf("Script T-SQL do hook %s inválido: %v", h.Phase, err)
+		}
+	}
+	if cfg.HookTimeout <= 0 {
+		fatal("Flag -hook-timeout deve ser maior que zero")
+	}
+
+	// Validação da divisão em volumes
+	if cfg.VolumeSize != "" {
+		size, err := diskspace.ParseSize(cfg.VolumeSize)
+		if err != nil || size <= 0 {
+			fatalf("Flag -volume-size inválido '%s': use um tamanho como 500M ou 2G", cfg.VolumeSize)
+		}
+		cfg.VolumeSizeBytes = size
+	}
+
+	// Validação do modo -stream: o arquivo final não é gravado em -zip-dir
+	if cfg.Stream {
+		if cfg.VolumeSizeBytes > 0 {
+			fatal("Flag -volume-size não se aplica a -stream: o arquivo final é enviado inteiro ao Google Drive")
+		}
+		if cfg.KeepLast > 0 || cfg.KeepDays > 0 {
+			fatal("Flags -keep-last e -keep-days não se aplicam a -stream: nenhum backup é gravado em -zip-dir")
+		}
+		if cfg.TokenFile == "" {
+			fatal("Flag -token-file é obrigatório com -stream")
+		}
+		if _, err := os.Stat(cfg.CredentialsFile); err != nil {
+			fatalf("Flag -credentials-file inválido: %v", err)
+		}
+	}
+
+	// Validação do modo de leitura do .bak
+	switch cfg.FetchMode {
+	case "local", "bulk":
+	default:
+		fatalf("Flag -fetch-mode inválido '%s': use local ou bulk", cfg.FetchMode)
+	}
+	chunkSize, err := diskspace.ParseSize(cfg.FetchChunkSize)
+	if err != nil || chunkSize <= 0 {
+		fatalf("Flag -fetch-chunk-size inválido '%s': use um tamanho como 4M ou 16M", cfg.FetchChunkSize)
+	}
+	cfg.FetchChunkSizeBytes = chunkSize
+
 	// Validação de diretórios
 	if _, err := os.Stat(cfg.ZipDir); os.IsNotExist(err) {
 		log.Printf("Aviso: O diretório -zip-dir '%s' não existe. Será criado se necessário.", cfg.ZipDir)
@@ -112,6 +627,230 @@ func ValidateBackupFlags(cfg *DbBackupConfig) {
 	}
 }
 
+// validatePostgresFlags valida os flags do engine PostgreSQL e rejeita os
+// recursos exclusivos do SQL Server.
+func validatePostgresFlags(cfg *DbBackupConfig) {
+	if _, _, err := postgres.ParseServer(cfg.Server); err != nil {
+		fatalf("Flag -server inválido: %v", err)
+	}
+	if !slices.Contains(postgres.Formats, cfg.PGFormat) {
+		fatalf("Flag -pg-format inválido '%s': use %s", cfg.PGFormat, strings.Join(postgres.Formats, ", "))
+	}
+	if cfg.PGJobs < 1 {
+		fatal("Flag -pg-jobs deve ser pelo menos 1")
+	}
+	if cfg.PGJobs > 1 && cfg.PGFormat != postgres.FormatDirectory {
+		fatal("Flag -pg-jobs exige -pg-format directory")
+	}
+	if !slices.Contains(postgres.SSLModes, cfg.PGSSLMode) {
+		fatalf("Flag -pg-sslmode inválido '%s': use %s", cfg.PGSSLMode, strings.Join(postgres.SSLModes, ", "))
+	}
+	if cfg.Auth != mssql.AuthSQL {
+		fatal("Flag -auth não se aplica ao PostgreSQL; use -user e a senha (ou ~/.pgpass)")
+	}
+	// No modo -stream o pg_dump grava na saída padrão, o que só o formato custom permite
+	if cfg.Stream {
+		if cfg.PGFormat != postgres.FormatCustom {
+			fatal("Flag -stream exige -pg-format custom no PostgreSQL")
+		}
+		if cfg.Verify {
+			fatal("Flag -verify não se aplica a -stream no PostgreSQL: não há arquivo para o pg_restore --list; o resultado do pg_dump é conferido durante a compressão")
+		}
+		if cfg.ServerCleanup != backup.CleanupNone {
+			fatal("Flag -server-cleanup não se aplica a -stream no PostgreSQL: nenhum dump é gravado em -backup-dir")
+		}
+		if cfg.BackupDir != "" {
+			log.Printf("Aviso: -backup-dir é ignorado com -stream no PostgreSQL: a saída do pg_dump vai direto ao compressor.")
+			cfg.BackupDir = ""
+		}
+	}
+
+	// Backups diferenciais e de log não existem no pg_dump
+	types := []string{cfg.BackupType}
+	for _, e := range cfg.Schedules {
+		types = append(types, e.Type)
+	}
+	for _, t := range types {
+		if t != mssql.BackupFull {
+			fatalf("Backup %s não é suportado pelo PostgreSQL; use full", t)
+		}
+	}
+	if cfg.FetchMode != backup.FetchLocal {
+		fatal("Flag -fetch-mode bulk não é suportado pelo PostgreSQL: o dump é gravado em -backup-dir nesta máquina")
+	}
+	if cfg.ServerCleanup == backup.CleanupXP {
+		fatal("Flag -server-cleanup xp não é suportado pelo PostgreSQL; use local")
+	}
+	if cfg.Stripes != 1 || cfg.SQLCompression || cfg.MaxTransferSize != 0 || cfg.BufferCount != 0 {
+		fatal("Flags -stripes, -sql-compression, -max-transfer-size e -buffer-count são exclusivos do SQL Server")
+	}
+}
+
+// validateMySQLFlags valida os flags do engine MySQL/MariaDB e rejeita os
+// recursos exclusivos do SQL Server.
+func validateMySQLFlags(cfg *DbBackupConfig) {
+	if _, _, err := mysql.ParseServer(cfg.Server); err != nil {
+		fatalf("Flag -server inválido: %v", err)
+	}
+	if !slices.Contains(mysql.Tools, cfg.MySQLTool) {
+		fatalf("Flag -mysql-tool inválido '%s': use %s", cfg.MySQLTool, strings.Join(mysql.Tools, ", "))
+	}
+	if !slices.Contains(mysql.TLSModes, cfg.MySQLTLS) {
+		fatalf("Flag -mysql-tls inválido '%s': use %s", cfg.MySQLTLS, strings.Join(mysql.TLSModes, ", "))
+	}
+	if cfg.Auth != mssql.AuthSQL {
+		fatal("Flag -auth não se aplica ao MySQL; use -user e a senha")
+	}
+
+	types := []string{cfg.BackupType}
+	for _, e := range cfg.Schedules {
+		types = append(types, e.Type)
+	}
+	for _, t := range types {
+		if t != mssql.BackupFull {
+			fatalf("Backup %s não é suportado pelo MySQL; use full (os incrementais ficam no log binário)", t)
+		}
+	}
+	if cfg.BackupDir != "" {
+		log.Printf("Aviso: -backup-dir é ignorado com -engine mysql: o dump é gravado direto no arquivo final.")
+		cfg.BackupDir = ""
+	}
+	if cfg.FetchMode != backup.FetchLocal || cfg.ServerCleanup != backup.CleanupNone {
+		fatal("Flags -fetch-mode e -server-cleanup não se aplicam ao MySQL, que não gera arquivos intermediários")
+	}
+	if cfg.Stripes != 1 || cfg.SQLCompression || cfg.MaxTransferSize != 0 || cfg.BufferCount != 0 {
+		fatal("Flags -stripes, -sql-compression, -max-transfer-size e -buffer-count são exclusivos do SQL Server")
+	}
+}
+
+// localSource indica os engines cuja origem é um arquivo ou diretório desta
+// máquina (-source).
+func (c *DbBackupConfig) localSource() bool {
+	return c.Engine == backup.EngineSQLite || c.Engine == backup.EngineFiles
+}
+
+// SourceName retorna o nome padrão do backup de source: o nome do arquivo sem
+// extensão ou o nome do diretório.
+func SourceName(source string) string {
+	name := filepath.Base(filepath.Clean(source))
+	if ext := filepath.Ext(name); ext != "" && ext != name {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
+// validateSourceFlags valida os flags dos engines sqlite e files e rejeita os
+// recursos que dependem de um servidor.
+func validateSourceFlags(cfg *DbBackupConfig) {
+	sources := []string{cfg.Source}
+	if cfg.Serve {
+		sources = sources[:0]
+		for _, e := range cfg.Schedules {
+			if e.Source == "" && cfg.Source == "" {
+				fatalf("Agendamento %s sem source: informe -source ou \"source\" no agendamento", e.Name)
+			}
+			sources = append(sources, cmp.Or(e.Source, cfg.Source))
+		}
+	} else if cfg.Source == "" {
+		fatalf("Flag -source é obrigatório com -engine %s", cfg.Engine)
+	}
+	for _, src := range sources {
+		info, err := os.Stat(src)
+		if err != nil {
+			fatalf("Flag -source inválido: %v", err)
+		}
+		if cfg.Engine == backup.EngineFiles && !info.IsDir() {
+			fatalf("Flag -source '%s' deve ser um diretório com -engine files", src)
+		}
+		if cfg.Engine == backup.EngineSQLite && !info.Mode().IsRegular() {
+			fatalf("Flag -source '%s' deve ser o arquivo do banco com -engine sqlite", src)
+		}
+	}
+
+	if cfg.Engine == backup.EngineSQLite {
+		if !slices.Contains(sqlite.Methods, cfg.SQLiteMethod) {
+			fatalf("Flag -sqlite-method inválido '%s': use %s", cfg.SQLiteMethod, strings.Join(sqlite.Methods, ", "))
+		}
+		// A cópia do banco é temporária; sem -backup-dir vai para o diretório temporário
+		if cfg.BackupDir == "" {
+			cfg.BackupDir = os.TempDir()
+		}
+	} else {
+		if _, err := filetree.NewMatcher(filetree.SplitList(cfg.Include), filetree.SplitList(cfg.Exclude)); err != nil {
+			fatalf("Flags -include/-exclude inválidos: %v", err)
+		}
+		if cfg.BackupDir != "" {
+			log.Printf("Aviso: -backup-dir é ignorado com -engine files: os arquivos são lidos direto da origem.")
+			cfg.BackupDir = ""
+		}
+	}
+
+	types := []string{cfg.BackupType}
+	for _, e := range cfg.Schedules {
+		types = append(types, e.Type)
+	}
+	for _, t := range types {
+		if t != mssql.BackupFull {
+			fatalf("Backup %s não é suportado com -engine %s; use full", t, cfg.Engine)
+		}
+	}
+	if cfg.PreSQL != "" || cfg.PostSuccessSQL != "" || cfg.PostFailureSQL != "" || cfg.SQLLock {
+		fatalf("Flags -pre-sql, -post-success-sql, -post-failure-sql e -sql-lock exigem um servidor e não se aplicam a -engine %s", cfg.Engine)
+	}
+	if cfg.FetchMode != backup.FetchLocal || cfg.ServerCleanup != backup.CleanupNone {
+		fatalf("Flags -fetch-mode e -server-cleanup não se aplicam a -engine %s", cfg.Engine)
+	}
+	if cfg.Stripes != 1 || cfg.SQLCompression || cfg.MaxTransferSize != 0 || cfg.BufferCount != 0 {
+		fatal("Flags -stripes, -sql-compression, -max-transfer-size e -buffer-count são exclusivos do SQL Server")
+	}
+}
+
+// fatal e fatalf registram o erro de configuração e encerram o dbbackup com
+// report.ExitConfig, distinguindo-o das falhas de execução.
+func fatal(msg string) {
+	log.Print(msg)
+	os.Exit(report.ExitConfig)
+}
+
+func fatalf(format string, args ...any) {
+	log.Printf(format, args...)
+	os.Exit(report.ExitConfig)
+}
+
+// ResolvePassword preenche cfg.Password a partir de -password-file, -password-env
+// ou -password-keyring. Apenas uma fonte de senha pode ser usada por vez.
+func (c *DbBackupConfig) ResolvePassword() error {
+	sources := 0
+	for _, v := range []string{c.Password, c.PasswordFile, c.PasswordEnv, c.PasswordKeyring} {
+		if v != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("use apenas um entre -password, -password-file, -password-env e -password-keyring")
+	}
+
+	var err error
+	switch {
+	case c.Password != "":
+		log.Printf("Aviso: -password fica visível na lista de processos; prefira -password-file, -password-env ou -password-keyring.")
+	case c.PasswordFile != "":
+		var warning string
+		c.Password, warning, err = secret.FromFile(c.PasswordFile)
+		if warning != "" {
+			log.Printf("Aviso: %s", warning)
+		}
+	case c.PasswordEnv != "":
+		c.Password, err = secret.FromEnv(c.PasswordEnv)
+	case c.PasswordKeyring != "":
+		if c.User == "" {
+			return fmt.Errorf("-password-keyring exige -user")
+		}
+		c.Password, err = secret.FromKeyring(c.PasswordKeyring, c.User)
+	}
+	return err
+}
+
 func ValidateUploaderFlags(cfg *UpdloaderConfig) {
 	if cfg.WatchDir == "" {
 		log.Fatal("Flag -watch-dir é obrigatório")
@@ -128,5 +867,28 @@ func ValidateUploaderFlags(cfg *UpdloaderConfig) {
 	if cfg.LogLevel == "" {
 		log.Fatal("Flag -log-level é obrigatório")
 	}
+	if !slices.Contains(watcher.Modes, cfg.WatchMode) {
+		log.Fatalf("Flag -watch-mode inválido '%s' (use %s)", cfg.WatchMode, strings.Join(watcher.Modes, ", "))
+	}
+	if cfg.PollInterval <= 0 {
+		log.Fatal("Flag -poll-interval deve ser maior que zero")
+	}
+	cfg.Filter = watcher.Filter{
+		Include: filetree.SplitList(cfg.Include),
+		Exclude: filetree.SplitList(cfg.Exclude),
+		MinSize: cfg.MinSize,
+		MaxSize: cfg.MaxSize,
+	}
+	if err := cfg.Filter.Compile(); err != nil {
+		log.Fatalf("Flags -include, -exclude, -min-size ou -max-size inválidos: %v", err)
+	}
+	if cfg.RoutesFile != "" {
+		routes, err := watcher.LoadRoutes(cfg.RoutesFile)
+		if err != nil {
+			log.Fatalf("Flag -routes-file inválido: %v", err)
+		}
+		cfg.Routes = routes
+		cfg.Recursive = true
+	}
 
 }
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/hooks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks(t *testing.T) {
+	cfg := &DbBackupConfig{PreSQL: "pre.sql", PostFailureHook: "notify.sh"}
+
+	assert.Equal(t, []hooks.Hook{
+		{Phase: hooks.PhasePre, SQLFile: "pre.sql"},
+		{Phase: hooks.PhasePostFailure, Command: "notify.sh"},
+	}, cfg.Hooks())
+
+	assert.Empty(t, (&DbBackupConfig{}).Hooks())
+}
+
+func TestPostgresOptions(t *testing.T) {
+	cfg := &DbBackupConfig{
+		Server:         "pg01:5433",
+		Database:       "scm",
+		User:           "backup",
+		Password:       "x",
+		PGSSLMode:      "require",
+		PGFormat:       "directory",
+		PGJobs:         4,
+		ConnectTimeout: 30 * time.Second,
+	}
+
+	opts := cfg.PostgresOptions()
+	assert.Equal(t, "pg01", opts.Conn.Host)
+	assert.Equal(t, 5433, opts.Conn.Port)
+	assert.Equal(t, "scm", opts.Conn.Database)
+	assert.Equal(t, "require", opts.Conn.SSLMode)
+	assert.Equal(t, 30*time.Second, opts.Conn.ConnectTimeout)
+	assert.Equal(t, "directory", opts.Format)
+	assert.Equal(t, 4, opts.Jobs)
+}
+
+func TestMySQLOptions(t *testing.T) {
+	cfg := &DbBackupConfig{
+		Server:      "/var/run/mysqld/mysqld.sock",
+		Database:    "scm",
+		User:        "backup",
+		MySQLTool:   "mariabackup",
+		MySQLBinDir: "/opt/mariadb/bin",
+		MySQLTLS:    "false",
+	}
+
+	opts := cfg.MySQLOptions()
+	assert.Equal(t, "/var/run/mysqld/mysqld.sock", opts.Conn.Host)
+	assert.Equal(t, "scm", opts.Conn.Database)
+	assert.Equal(t, "false", opts.Conn.TLS)
+	assert.Equal(t, "mariabackup", opts.Tool)
+	assert.Equal(t, "/opt/mariadb/bin", opts.BinDir)
+}
+
+func TestFilesOptions(t *testing.T) {
+	cfg := &DbBackupConfig{Include: "*.pdf, docs/** ,", Exclude: "cache"}
+
+	opts := cfg.FilesOptions("/srv/laudos")
+	assert.Equal(t, "/srv/laudos", opts.Root)
+	assert.Equal(t, []string{"*.pdf", "docs/**"}, opts.Include)
+	assert.Equal(t, []string{"cache"}, opts.Exclude)
+}
+
+func TestSourceName(t *testing.T) {
+	assert.Equal(t, "app", SourceName("/var/lib/app/app.sqlite3"))
+	assert.Equal(t, "laudos", SourceName("/srv/laudos/"))
+	assert.Equal(t, ".config", SourceName("/home/u/.config"))
+}
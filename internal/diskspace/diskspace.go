@@ -0,0 +1,88 @@
+// Package diskspace consulta o espaço livre de um sistema de arquivos.
+package diskspace
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Free retorna os bytes disponíveis para o usuário atual no sistema de
+// arquivos que contém path.
+func Free(path string) (uint64, error) {
+	free, err := free(path)
+	if err != nil {
+		return 0, fmt.Errorf("consultar espaço livre em %s falhou: %w", path, err)
+	}
+	return free, nil
+}
+
+// Check retorna erro se o espaço livre em path for menor que required bytes.
+func Check(path string, required uint64) error {
+	free, err := Free(path)
+	if err != nil {
+		return err
+	}
+	if free < required {
+		return &InsufficientError{Path: path, Free: free, Required: required}
+	}
+	return nil
+}
+
+// InsufficientError indica espaço livre insuficiente.
+type InsufficientError struct {
+	Path     string
+	Free     uint64
+	Required uint64
+}
+
+func (e *InsufficientError) Error() string {
+	return fmt.Sprintf("espaço livre insuficiente em %s: %s disponíveis, %s necessários", e.Path, FormatBytes(e.Free), FormatBytes(e.Required))
+}
+
+// FormatBytes formata n em unidades binárias (ex: 1.5 GiB).
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseSize converte tamanhos como "512K", "500M", "2G" ou "1T" (base 1024) em bytes.
+// Números sem sufixo são interpretados como bytes.
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "B")
+
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = s[:n-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("tamanho inválido '%s': %w", s, err)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("tamanho negativo: %s", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
@@ -0,0 +1,66 @@
+package diskspace
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFree(t *testing.T) {
+	free, err := Free(t.TempDir())
+	require.NoError(t, err)
+	assert.Greater(t, free, uint64(0))
+
+	_, err = Free("/caminho/inexistente")
+	assert.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, Check(dir, 1))
+
+	err := Check(dir, math.MaxUint64)
+	var insufficient *InsufficientError
+	require.ErrorAs(t, err, &insufficient)
+	assert.Equal(t, dir, insufficient.Path)
+	assert.ErrorContains(t, err, "espaço livre insuficiente")
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", FormatBytes(512))
+	assert.Equal(t, "1.5 KiB", FormatBytes(1536))
+	assert.Equal(t, "2.0 GiB", FormatBytes(2<<30))
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{input: "1024", want: 1024},
+		{input: "512K", want: 512 << 10},
+		{input: "500M", want: 500 << 20},
+		{input: "2G", want: 2 << 30},
+		{input: "2gb", want: 2 << 30},
+		{input: "1.5G", want: 3 << 29},
+		{input: "1T", want: 1 << 40},
+		{input: "abc", wantErr: true},
+		{input: "-1G", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSize(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package diskspace
+
+import "syscall"
+
+func free(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	// Bavail exclui os blocos reservados ao root
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
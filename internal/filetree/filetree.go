@@ -0,0 +1,193 @@
+// Package filetree percorre uma árvore de diretórios selecionando arquivos por
+// padrões glob (ou expressões regulares) de inclusão e exclusão.
+//
+// Os padrões são comparados com o caminho relativo à raiz, separado por "/":
+//
+//   - sem "/", o padrão vale para o nome em qualquer nível (ex: *.tmp, node_modules);
+//   - com "/", o padrão é ancorado na raiz (ex: docs/*.pdf, /config.ini);
+//   - "*" e "?" não atravessam "/"; "**" atravessa qualquer número de diretórios
+//     (ex: **/2025/*.pdf, logs/**);
+//   - classes como [0-9] e [!a-z] seguem path.Match;
+//   - com o prefixo "re:", o restante é uma expressão regular comparada com o
+//     caminho relativo inteiro, sem âncoras implícitas (ex: re:\.bak\.(zst|gz)$, re:(^|/)~\$[^/]*$).
+//
+// Um diretório excluído não é percorrido. Sem padrões de inclusão, todos os
+// arquivos não excluídos são selecionados.
+package filetree
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Matcher seleciona arquivos por padrões glob ou expressões regulares.
+type Matcher struct {
+	include []*pattern
+	exclude []*pattern
+}
+
+type pattern struct {
+	glob     string
+	re       *regexp.Regexp
+	basename bool // Sem "/": comparado apenas com o nome
+}
+
+// NewMatcher compila os padrões de inclusão e exclusão.
+func NewMatcher(include, exclude []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, list := range []struct {
+		globs []string
+		dst   *[]*pattern
+	}{{include, &m.include}, {exclude, &m.exclude}} {
+		for _, g := range list.globs {
+			p, err := compile(g)
+			if err != nil {
+				return nil, err
+			}
+			*list.dst = append(*list.dst, p)
+		}
+	}
+	return m, nil
+}
+
+// SplitList separa uma lista de padrões por vírgula, ignorando itens vazios.
+func SplitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// regexPrefix marca um padrão como expressão regular.
+const regexPrefix = "re:"
+
+func compile(glob string) (*pattern, error) {
+	if expr, ok := strings.CutPrefix(strings.TrimSpace(glob), regexPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("expressão regular inválida '%s': %w", glob, err)
+		}
+		return &pattern{glob: glob, re: re}, nil
+	}
+
+	g := strings.TrimSuffix(filepath.ToSlash(strings.TrimSpace(glob)), "/")
+	if g == "" {
+		return nil, fmt.Errorf("padrão vazio")
+	}
+	p := &pattern{glob: glob, basename: !strings.Contains(g, "/")}
+	g = strings.TrimPrefix(g, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(g); i++ {
+		c := g[i]
+		switch {
+		case strings.HasPrefix(g[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(g[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(g[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("padrão inválido '%s': '[' sem ']'", glob)
+			}
+			class := g[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("padrão inválido '%s': %w", glob, err)
+	}
+	p.re = re
+	return p, nil
+}
+
+func (p *pattern) match(rel string) bool {
+	if p.basename {
+		return p.re.MatchString(path.Base(rel))
+	}
+	return p.re.MatchString(rel)
+}
+
+func matchAny(patterns []*pattern, rel string) bool {
+	for _, p := range patterns {
+		if p.match(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Excluded indica se o caminho relativo rel (arquivo ou diretório) é excluído.
+func (m *Matcher) Excluded(rel string) bool {
+	return matchAny(m.exclude, rel)
+}
+
+// Match indica se o arquivo de caminho relativo rel é selecionado.
+func (m *Matcher) Match(rel string) bool {
+	if m.Excluded(rel) {
+		return false
+	}
+	return len(m.include) == 0 || matchAny(m.include, rel)
+}
+
+// File é um arquivo selecionado por Walk.
+type File struct {
+	Rel  string // Caminho relativo à raiz, separado por "/"
+	Path string
+	Info fs.FileInfo
+}
+
+// Walk percorre root em ordem lexical e chama fn para cada arquivo regular
+// selecionado por m. Links simbólicos e arquivos especiais são ignorados.
+func Walk(root string, m *Matcher, fn func(f File) error) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if m.Excluded(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !m.Match(rel) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(File{Rel: rel, Path: p, Info: info})
+	})
+}
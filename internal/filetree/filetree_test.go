@@ -0,0 +1,84 @@
+package filetree
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		rel     string
+		want    bool
+	}{
+		{name: "sem padrões", rel: "docs/a.pdf", want: true},
+		{name: "nome em qualquer nível", include: []string{"*.pdf"}, rel: "docs/2025/a.pdf", want: true},
+		{name: "nome não corresponde", include: []string{"*.pdf"}, rel: "docs/a.txt", want: false},
+		{name: "ancorado na raiz", include: []string{"docs/*.pdf"}, rel: "docs/a.pdf", want: true},
+		{name: "* não atravessa diretórios", include: []string{"docs/*.pdf"}, rel: "docs/2025/a.pdf", want: false},
+		{name: "** atravessa diretórios", include: []string{"docs/**/*.pdf"}, rel: "docs/2025/01/a.pdf", want: true},
+		{name: "** com zero diretórios", include: []string{"docs/**/*.pdf"}, rel: "docs/a.pdf", want: true},
+		{name: "** no início", include: []string{"**/2025/*"}, rel: "a/b/2025/x.pdf", want: true},
+		{name: "barra inicial", include: []string{"/config.ini"}, rel: "config.ini", want: true},
+		{name: "barra inicial não vale em subdiretório", include: []string{"/config.ini"}, rel: "app/config.ini", want: false},
+		{name: "classe", include: []string{"backup_[0-9].db"}, rel: "backup_7.db", want: true},
+		{name: "classe negada", include: []string{"backup_[!0-9].db"}, rel: "backup_7.db", want: false},
+		{name: "exclusão vence inclusão", include: []string{"*.pdf"}, exclude: []string{"tmp/**"}, rel: "tmp/a.pdf", want: false},
+		{name: "exclusão por nome", exclude: []string{"*.tmp", "Thumbs.db"}, rel: "fotos/Thumbs.db", want: false},
+		{name: "ponto é literal", include: []string{"*.db"}, rel: "appxdb", want: false},
+		{name: "regex", include: []string{`re:\.bak\.(zst|gz)$`}, rel: "sql/SCM.bak.zst", want: true},
+		{name: "regex não corresponde", include: []string{`re:\.bak\.(zst|gz)$`}, rel: "sql/SCM.bak", want: false},
+		{name: "regex de exclusão", exclude: []string{`re:(^|/)~\$[^/]*$`}, rel: "docs/~$laudo.docx", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher(tt.include, tt.exclude)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Match(tt.rel))
+		})
+	}
+}
+
+func TestNewMatcher_Invalid(t *testing.T) {
+	_, err := NewMatcher([]string{"[abc"}, nil)
+	assert.Error(t, err)
+	_, err = NewMatcher(nil, []string{" "})
+	assert.Error(t, err)
+	_, err = NewMatcher([]string{"re:(abc"}, nil)
+	assert.Error(t, err)
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"*.pdf", "docs/**"}, SplitList(" *.pdf, ,docs/** "))
+	assert.Nil(t, SplitList(""))
+}
+
+func TestWalk(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{"b.pdf", "a.txt", "docs/c.pdf", "node_modules/x/d.pdf", "tmp/e.pdf"} {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(rel), 0644))
+	}
+	if runtime.GOOS != "windows" {
+		require.NoError(t, os.Symlink(filepath.Join(root, "b.pdf"), filepath.Join(root, "link.pdf")))
+	}
+
+	m, err := NewMatcher([]string{"*.pdf"}, []string{"node_modules", "/tmp"})
+	require.NoError(t, err)
+	var got []string
+	require.NoError(t, Walk(root, m, func(f File) error {
+		assert.Equal(t, int64(len(f.Rel)), f.Info.Size())
+		got = append(got, f.Rel)
+		return nil
+	}))
+	assert.Equal(t, []string{"b.pdf", "docs/c.pdf"}, got)
+}
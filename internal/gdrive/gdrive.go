@@ -4,21 +4,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/diskspace"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
 )
 
 // DriveUploader encapsula a lógica de interação com o Google Drive.
-// Ele satisfaz a interface watcher.Uploader.
+// Ele satisfaz as interfaces watcher.Uploader e backup.Uploader (dbbackup -stream).
 type DriveUploader struct {
 	logger  *slog.Logger
 	service *drive.Service
+	parent  string // Pasta que recebe as pastas diárias ("root" = raiz do Drive)
 }
 
 // NewDriveUploader cria e configura um novo cliente para a API do Google Drive.
@@ -50,7 +55,45 @@ func NewDriveUploader(ctx context.Context, logger *slog.Logger, credentialsFile,
 	}
 
 	log.Info("Serviço Google Drive inicializado com sucesso.")
-	return &DriveUploader{logger: log, service: driveService}, nil
+	return &DriveUploader{logger: log, service: driveService, parent: "root"}, nil
+}
+
+// InFolder retorna um uploader da mesma conta que cria as pastas diárias dentro
+// da pasta name, na raiz do Drive, criando-a se necessário.
+func (du *DriveUploader) InFolder(ctx context.Context, name string) (*DriveUploader, error) {
+	query := fmt.Sprintf("name = '%s' and mimeType = '%s' and 'root' in parents and trashed = false", escapeQuery(name), folderMimeType)
+	files, err := du.service.Files.List().Q(query).Fields("files(id)").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("busca de pasta %s falhou: %w", name, err)
+	}
+
+	var id string
+	if len(files.Files) > 0 {
+		id = files.Files[0].Id
+	} else {
+		created, err := du.service.Files.Create(&drive.File{Name: name, MimeType: folderMimeType}).
+			Fields("id").
+			Context(ctx).
+			Do()
+		if err != nil {
+			return nil, fmt.Errorf("criação de pasta %s falhou: %w", name, err)
+		}
+		id = created.Id
+		du.logger.Info("Pasta criada com sucesso no Google Drive", slog.String("folder", name), slog.String("id", id))
+	}
+
+	return &DriveUploader{
+		logger:  du.logger.With(slog.String("drive_folder", name)),
+		service: du.service,
+		parent:  id,
+	}, nil
+}
+
+const folderMimeType = "application/vnd.google-apps.folder"
+
+// escapeQuery escapa s para uso entre aspas simples em uma consulta do Drive.
+func escapeQuery(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
 }
 
 // createBackupFolder creates a new folder in Google Drive with the current date as name if it doesn't exist
@@ -59,7 +102,7 @@ func (du *DriveUploader) createBackupFolder(ctx context.Context) (string, error)
 	du.logger.Info("Verificando pasta de backup", slog.String("folder", folderName))
 
 	// First, check if the folder already exists
-	query := fmt.Sprintf("name = '%s' and mimeType = 'application/vnd.google-apps.folder' and trashed = false", folderName)
+	query := fmt.Sprintf("name = '%s' and mimeType = '%s' and '%s' in parents and trashed = false", folderName, folderMimeType, du.parent)
 	files, err := du.service.Files.List().
 		Q(query).
 		Fields("files(id, name, mimeType)").
@@ -98,7 +141,8 @@ func (du *DriveUploader) createBackupFolder(ctx context.Context) (string, error)
 	// If folder doesn't exist, create it
 	folder := &drive.File{
 		Name:     folderName,
-		MimeType: "application/vnd.google-apps.folder",
+		MimeType: folderMimeType,
+		Parents:  []string{du.parent},
 	}
 
 	createdFolder, err := du.service.Files.Create(folder).
@@ -125,7 +169,7 @@ func (du *DriveUploader) deleteOldBackupFolder(ctx context.Context) error {
 	twoDaysAgo := time.Now().AddDate(0, 0, -2).Format("02-01-2006")
 
 	// Search for the folder from two days ago
-	query := fmt.Sprintf("name = '%s' and mimeType = 'application/vnd.google-apps.folder'", twoDaysAgo)
+	query := fmt.Sprintf("name = '%s' and mimeType = '%s' and '%s' in parents", twoDaysAgo, folderMimeType, du.parent)
 	files, err := du.service.Files.List().Q(query).Context(ctx).Do()
 	if err != nil {
 		du.logger.Error("Erro ao buscar pasta antiga", slog.String("folder", twoDaysAgo), slog.Any("error", err))
@@ -148,18 +192,70 @@ func (du *DriveUploader) deleteOldBackupFolder(ctx context.Context) error {
 	return nil
 }
 
-// UploadFile envia um arquivo para o Google Drive. Satisfaz watcher.Uploader.
-func (du *DriveUploader) UploadFile(ctx context.Context, filePath string) error {
+// manifestProperties lê o manifesto sidecar do backup (se existir) e retorna os
+// campos principais para serem anexados como properties do arquivo no Drive.
+func (du *DriveUploader) manifestProperties(filePath string) map[string]string {
+	if manifest.IsSidecar(filePath) {
+		return nil
+	}
+	sidecarPath := manifest.SidecarFor(filePath)
+	if _, err := os.Stat(sidecarPath); err != nil {
+		du.logger.Debug("Arquivo sem manifesto sidecar", slog.String("path", filePath))
+		return nil
+	}
+	m, err := manifest.ReadFile(sidecarPath)
+	if err != nil {
+		du.logger.Warn("Não foi possível ler o manifesto do backup", slog.String("path", sidecarPath), slog.Any("error", err))
+		return nil
+	}
+	return m.DriveProperties()
+}
+
+// CheckSpace retorna *diskspace.InsufficientError se a cota do Google Drive
+// não comportar size bytes. Contas sem limite de cota sempre comportam.
+// Satisfaz watcher.SpaceChecker.
+func (du *DriveUploader) CheckSpace(ctx context.Context, size int64) error {
+	about, err := du.service.About.Get().Fields("storageQuota(limit,usage)").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("consultar cota do Google Drive falhou: %w", err)
+	}
+	quota := about.StorageQuota
+	if quota == nil || quota.Limit <= 0 {
+		du.logger.Debug("Conta do Google Drive sem limite de cota")
+		return nil
+	}
+	free := max(quota.Limit-quota.Usage, 0)
+	du.logger.Debug("Cota do Google Drive verificada",
+		slog.String("free", diskspace.FormatBytes(uint64(free))),
+		slog.String("required", diskspace.FormatBytes(uint64(size))))
+	if free < size {
+		return &diskspace.InsufficientError{Path: "Google Drive", Free: uint64(free), Required: uint64(size)}
+	}
+	return nil
+}
+
+// prepareFolder retorna a pasta do dia, criando-a se necessário, e remove a
+// pasta antiga.
+func (du *DriveUploader) prepareFolder(ctx context.Context) (string, error) {
 	// Create new backup folder
 	folderID, err := du.createBackupFolder(ctx)
 	if err != nil {
-		return fmt.Errorf("falha ao criar pasta de backup: %w", err)
+		return "", fmt.Errorf("falha ao criar pasta de backup: %w", err)
 	}
 
 	// Delete old backup folder
 	if err := du.deleteOldBackupFolder(ctx); err != nil {
 		du.logger.Warn("Falha ao deletar pasta antiga, continuando com upload", slog.Any("error", err))
 	}
+	return folderID, nil
+}
+
+// UploadFile envia um arquivo para o Google Drive. Satisfaz watcher.Uploader.
+func (du *DriveUploader) UploadFile(ctx context.Context, filePath string) error {
+	folderID, err := du.prepareFolder(ctx)
+	if err != nil {
+		return err
+	}
 
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -178,8 +274,9 @@ func (du *DriveUploader) UploadFile(ctx context.Context, filePath string) error
 	du.logger.Debug("Iniciando upload", logAttrs...)
 
 	driveFile := &drive.File{
-		Name:    filepath.Base(filePath),
-		Parents: []string{folderID},
+		Name:       filepath.Base(filePath),
+		Parents:    []string{folderID},
+		Properties: du.manifestProperties(filePath),
 	}
 
 	// Usa Context(ctx) para propagar cancelamento
@@ -197,3 +294,46 @@ func (du *DriveUploader) UploadFile(ctx context.Context, filePath string) error
 	du.logger.Info("Arquivo enviado com sucesso para o Google Drive", slog.String("path", filePath))
 	return nil
 }
+
+// Upload envia o conteúdo de r, de tamanho desconhecido, como name na pasta do
+// dia e retorna o ID do arquivo. O envio é retomável, em blocos: um erro na
+// leitura de r o interrompe sem criar o arquivo. Satisfaz backup.Uploader.
+func (du *DriveUploader) Upload(ctx context.Context, name string, r io.Reader) (string, error) {
+	folderID, err := du.prepareFolder(ctx)
+	if err != nil {
+		return "", err
+	}
+	du.logger.Debug("Iniciando upload em fluxo", slog.String("name", name))
+	f, err := du.service.Files.Create(&drive.File{Name: name, Parents: []string{folderID}}).
+		Media(r).
+		Fields("id").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", fmt.Errorf("upload de %s falhou: %w", name, err)
+	}
+	du.logger.Info("Arquivo enviado para o Google Drive", slog.String("name", name), slog.String("id", f.Id))
+	return f.Id, nil
+}
+
+// Publish renomeia o arquivo id para name e anexa properties. Satisfaz
+// backup.Uploader.
+func (du *DriveUploader) Publish(ctx context.Context, id, name string, properties map[string]string) error {
+	_, err := du.service.Files.Update(id, &drive.File{Name: name, Properties: properties}).
+		Fields("id").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("renomear arquivo %s para %s falhou: %w", id, name, err)
+	}
+	return nil
+}
+
+// Delete remove o arquivo id do Google Drive. Satisfaz backup.Uploader.
+func (du *DriveUploader) Delete(ctx context.Context, id string) error {
+	if err := du.service.Files.Delete(id).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("remover arquivo %s falhou: %w", id, err)
+	}
+	du.logger.Info("Arquivo removido do Google Drive", slog.String("id", id))
+	return nil
+}
@@ -0,0 +1,218 @@
+// Package hooks executa comandos e scripts T-SQL configurados pelo usuário
+// antes e depois do backup (ex: colocar uma aplicação em manutenção, copiar o
+// arquivo final para fita).
+package hooks
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Fases em que os hooks são executados.
+const (
+	PhasePre         = "pre"          // Antes do BACKUP
+	PhasePostSuccess = "post-success" // Após o arquivo final ser publicado
+	PhasePostFailure = "post-failure" // Após qualquer falha
+)
+
+// Status da execução informados aos hooks em DBBACKUP_STATUS.
+const (
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// DefaultTimeout é o tempo limite padrão de cada hook.
+const DefaultTimeout = 10 * time.Minute
+
+// maxOutput limita a saída de um comando registrada no log.
+const maxOutput = 4096
+
+// Hook é um comando e/ou script T-SQL associado a uma fase.
+type Hook struct {
+	Phase   string
+	Command string // Executado pelo shell do sistema (sh -c ou cmd /C)
+	SQLFile string // Script T-SQL; lotes separados por linhas "GO"
+}
+
+// Env descreve a execução do backup para os hooks.
+type Env struct {
+	Server   string
+	Database string
+	Status   string
+	Archive  string // Caminho do arquivo final (vazio antes de ser gerado e no modo -stream)
+	Error    string // Mensagem de erro (somente em post-failure)
+}
+
+// Vars retorna as variáveis de ambiente DBBACKUP_* da fase.
+func (e Env) Vars(phase string) []string {
+	return []string{
+		"DBBACKUP_PHASE=" + phase,
+		"DBBACKUP_SERVER=" + e.Server,
+		"DBBACKUP_DATABASE=" + e.Database,
+		"DBBACKUP_STATUS=" + e.Status,
+		"DBBACKUP_ARCHIVE=" + e.Archive,
+		"DBBACKUP_ERROR=" + e.Error,
+	}
+}
+
+// SQLExecutor é satisfeito por *sql.DB e *sql.Conn.
+type SQLExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Runner executa os hooks configurados.
+type Runner struct {
+	Hooks   []Hook
+	Timeout time.Duration // Tempo limite de cada hook (0 = DefaultTimeout)
+	Fatal   bool          // Falhas de hook interrompem o backup
+	DB      SQLExecutor   // Conexão para os scripts T-SQL; nil antes de conectar
+	Logger  *slog.Logger
+}
+
+// Has informa se há algum hook para a fase.
+func (r *Runner) Has(phase string) bool {
+	for _, h := range r.Hooks {
+		if h.Phase == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executa os hooks da fase em ordem: primeiro o script T-SQL, depois o
+// comando. Todos são executados mesmo que um falhe; os erros são combinados.
+func (r *Runner) Run(ctx context.Context, phase string, env Env) error {
+	var errs []error
+	for _, h := range r.Hooks {
+		if h.Phase != phase {
+			continue
+		}
+		if h.SQLFile != "" {
+			if err := r.runSQL(ctx, h.SQLFile); err != nil {
+				errs = append(errs, fmt.Errorf("hook %s (sql %s): %w", phase, h.SQLFile, err))
+			}
+		}
+		if h.Command != "" {
+			if err := r.runCommand(ctx, phase, h.Command, env); err != nil {
+				errs = append(errs, fmt.Errorf("hook %s (comando): %w", phase, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Runner) timeout() time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+	return DefaultTimeout
+}
+
+// runCommand executa command no shell do sistema com as variáveis DBBACKUP_*.
+func (r *Runner) runCommand(ctx context.Context, phase, command string, env Env) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout())
+	defer cancel()
+
+	cmd := shellCommand(ctx, command)
+	cmd.Env = append(os.Environ(), env.Vars(phase)...)
+	// Processos filhos do shell podem manter a saída aberta após o tempo limite
+	cmd.WaitDelay = time.Second
+
+	r.Logger.Info("Executando hook", slog.String("phase", phase), slog.String("command", command))
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	attrs := []any{
+		slog.String("phase", phase),
+		slog.Duration("duration", time.Since(start).Round(time.Millisecond)),
+		slog.String("output", truncate(string(output), maxOutput)),
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("tempo limite de %s excedido", r.timeout())
+		}
+		r.Logger.Warn("Hook falhou", append(attrs, slog.Any("error", err))...)
+		return err
+	}
+	r.Logger.Info("Hook concluído", attrs...)
+	return nil
+}
+
+// runSQL executa cada lote do script T-SQL em path.
+func (r *Runner) runSQL(ctx context.Context, path string) error {
+	if r.DB == nil {
+		return fmt.Errorf("sem conexão com o servidor")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout())
+	defer cancel()
+
+	batches := SplitBatches(string(data))
+	r.Logger.Info("Executando script de hook", slog.String("path", path), slog.Int("batches", len(batches)))
+	for i, batch := range batches {
+		if _, err := r.DB.ExecContext(ctx, batch); err != nil {
+			return fmt.Errorf("lote %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// goSeparator reconhece o separador de lotes do sqlcmd/SSMS (GO sozinho na linha).
+var goSeparator = regexp.MustCompile(`(?i)^\s*GO\s*(?:--.*)?$`)
+
+// SplitBatches divide um script T-SQL nos lotes separados por linhas "GO",
+// descartando lotes vazios.
+func SplitBatches(script string) []string {
+	var batches []string
+	var current strings.Builder
+	flush := func() {
+		if batch := strings.TrimSpace(current.String()); batch != "" {
+			batches = append(batches, batch)
+		}
+		current.Reset()
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if goSeparator.MatchString(line) {
+			flush()
+			continue
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+	flush()
+	return batches
+}
+
+// shellCommand monta a execução de command pelo shell do sistema.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+func truncate(s string, limit int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit] + "... (truncado)"
+}
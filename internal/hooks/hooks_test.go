@@ -0,0 +1,121 @@
+package hooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB registra os lotes executados e falha nos que contêm failOn.
+type fakeDB struct {
+	batches []string
+	failOn  string
+}
+
+func (f *fakeDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	f.batches = append(f.batches, query)
+	if f.failOn != "" && strings.Contains(query, f.failOn) {
+		return nil, errors.New("erro simulado")
+	}
+	return nil, nil
+}
+
+func newRunner(hooks ...Hook) *Runner {
+	return &Runner{Hooks: hooks, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+func skipWithoutShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("testes de comando usam sh")
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	script := "USE [SCM]\nGO\n\nUPDATE app SET manutencao = 1\ngo -- fim do lote\n  GO  \nSELECT 'GOOD'\n"
+	assert.Equal(t, []string{
+		"USE [SCM]",
+		"UPDATE app SET manutencao = 1",
+		"SELECT 'GOOD'",
+	}, SplitBatches(script))
+}
+
+func TestRun_CommandReceivesEnv(t *testing.T) {
+	skipWithoutShell(t)
+	out := filepath.Join(t.TempDir(), "env.txt")
+	r := newRunner(Hook{Phase: PhasePostSuccess, Command: `echo "$DBBACKUP_PHASE $DBBACKUP_DATABASE $DBBACKUP_STATUS $DBBACKUP_ARCHIVE" > ` + out})
+
+	err := r.Run(context.Background(), PhasePostSuccess, Env{Database: "SCM", Status: StatusSuccess, Archive: "/backups/SCM.zip"})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "post-success SCM success /backups/SCM.zip\n", string(data))
+}
+
+func TestRun_OnlyMatchingPhase(t *testing.T) {
+	skipWithoutShell(t)
+	r := newRunner(Hook{Phase: PhasePostFailure, Command: "exit 1"})
+
+	assert.NoError(t, r.Run(context.Background(), PhasePre, Env{}))
+	assert.False(t, r.Has(PhasePre))
+	assert.True(t, r.Has(PhasePostFailure))
+}
+
+func TestRun_CommandFailure(t *testing.T) {
+	skipWithoutShell(t)
+	r := newRunner(Hook{Phase: PhasePre, Command: "echo saindo; exit 3"})
+
+	err := r.Run(context.Background(), PhasePre, Env{})
+	assert.ErrorContains(t, err, "hook pre")
+}
+
+func TestRun_CommandTimeout(t *testing.T) {
+	skipWithoutShell(t)
+	r := newRunner(Hook{Phase: PhasePre, Command: "sleep 30"})
+	r.Timeout = 100 * time.Millisecond
+
+	err := r.Run(context.Background(), PhasePre, Env{})
+	assert.ErrorContains(t, err, "tempo limite")
+}
+
+func TestRun_SQL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pre.sql")
+	require.NoError(t, os.WriteFile(path, []byte("UPDATE a SET x = 1\nGO\nEXEC dbo.reindex\n"), 0600))
+
+	db := &fakeDB{}
+	r := newRunner(Hook{Phase: PhasePre, SQLFile: path})
+	r.DB = db
+
+	require.NoError(t, r.Run(context.Background(), PhasePre, Env{}))
+	assert.Equal(t, []string{"UPDATE a SET x = 1", "EXEC dbo.reindex"}, db.batches)
+}
+
+func TestRun_SQLFailureStopsScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pre.sql")
+	require.NoError(t, os.WriteFile(path, []byte("SELECT 1\nGO\nSELECT falha\nGO\nSELECT 3\n"), 0600))
+
+	db := &fakeDB{failOn: "falha"}
+	r := newRunner(Hook{Phase: PhasePre, SQLFile: path})
+	r.DB = db
+
+	err := r.Run(context.Background(), PhasePre, Env{})
+	assert.ErrorContains(t, err, "lote 2")
+	assert.Len(t, db.batches, 2)
+}
+
+func TestRun_SQLWithoutConnection(t *testing.T) {
+	r := newRunner(Hook{Phase: PhasePostFailure, SQLFile: "post.sql"})
+	assert.ErrorContains(t, r.Run(context.Background(), PhasePostFailure, Env{}), "sem conexão")
+}
@@ -0,0 +1,275 @@
+// Package lock impede que duas execuções do dbbackup façam backup do mesmo
+// banco ao mesmo tempo. O lock é um arquivo criado de forma exclusiva com o
+// PID, o host e o início da execução dona; locks deixados por processos que
+// morreram são detectados e removidos.
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Comportamentos quando o lock está em uso (flag -lock-mode).
+const (
+	ModeFail = "fail" // Encerra com erro (e notificação)
+	ModeSkip = "skip" // Encerra sem backup e sem erro
+	ModeWait = "wait" // Aguarda o lock ser liberado
+)
+
+// Modes lista os comportamentos aceitos.
+var Modes = []string{ModeFail, ModeSkip, ModeWait}
+
+// DefaultStaleAfter é a idade a partir da qual o lock de outro host (cujo
+// processo não pode ser verificado) é considerado abandonado.
+const DefaultStaleAfter = 24 * time.Hour
+
+// DefaultDir é o diretório dos arquivos de lock quando -lock-dir não é
+// informado. Fica fora de -zip-dir, que costuma ser monitorado pelo uploader.
+var DefaultDir = filepath.Join(os.TempDir(), "dbbackup")
+
+// PollInterval é o intervalo entre tentativas em Wait.
+var PollInterval = 5 * time.Second
+
+// incompleteAfter é o tempo após o qual um arquivo de lock ilegível (processo
+// morto entre a criação e a gravação) é considerado abandonado.
+const incompleteAfter = time.Minute
+
+// ErrLocked indica que outra execução detém o lock.
+var ErrLocked = errors.New("outra execução do backup está em andamento")
+
+// Info identifica a execução dona do lock.
+type Info struct {
+	PID      int       `json:"pid"`
+	Host     string    `json:"host"`
+	Database string    `json:"database"`
+	Started  time.Time `json:"started_at"`
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("processo %d em %s desde %s", i.PID, i.Host, i.Started.Format(time.RFC3339))
+}
+
+// HeldError é retornado quando o lock pertence a outra execução. Holder é nil
+// quando o dono não é conhecido (lock SQL ou arquivo ilegível).
+type HeldError struct {
+	Resource string
+	Holder   *Info
+}
+
+func (e *HeldError) Error() string {
+	if e.Holder == nil {
+		return fmt.Sprintf("%v: lock %s em uso", ErrLocked, e.Resource)
+	}
+	return fmt.Sprintf("%v: lock %s em uso pelo %s", ErrLocked, e.Resource, e.Holder)
+}
+
+func (e *HeldError) Unwrap() error {
+	return ErrLocked
+}
+
+// fileNameReplacer troca os caracteres que não podem aparecer em nomes de
+// arquivo no Windows (e o separador do Linux).
+var fileNameReplacer = strings.NewReplacer("/", "_", `\`, "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_")
+
+// FileName retorna o nome do arquivo de lock de database.
+func FileName(database string) string {
+	return "dbbackup-" + fileNameReplacer.Replace(database) + ".lock"
+}
+
+// AppLockResource retorna o nome do lock de aplicação (sp_getapplock) de database.
+func AppLockResource(database string) string {
+	return "MaisSaudeBackup/dbbackup/" + database
+}
+
+// FileLock é um lock obtido por TryFile.
+type FileLock struct {
+	Path string
+	Info Info
+	// Stale é o dono anterior de um lock abandonado que foi removido, se houver.
+	Stale *Info
+}
+
+// TryFile tenta obter o lock em path uma única vez. Se o lock pertencer a
+// outra execução viva, retorna *HeldError. Um lock é abandonado quando o
+// processo dono não existe mais neste host ou, para outros hosts, quando é
+// mais antigo que staleAfter.
+func TryFile(path, database string, staleAfter time.Duration) (*FileLock, error) {
+	host, _ := os.Hostname()
+	l := &FileLock{Path: path, Info: Info{PID: os.Getpid(), Host: host, Database: database, Started: time.Now()}}
+
+	// Novas tentativas após um lock abandonado ser removido ou liberado
+	for range 3 {
+		err := l.create()
+		if err == nil {
+			return l, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		holder, stale, err := inspect(path, host, staleAfter)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !stale {
+			return nil, &HeldError{Resource: path, Holder: holder}
+		}
+		removed, err := breakStale(path, host, staleAfter)
+		if err != nil {
+			return nil, err
+		}
+		if removed != nil {
+			l.Stale = removed
+		}
+	}
+	return nil, &HeldError{Resource: path}
+}
+
+// breakStale remove o lock abandonado em path e retorna o seu dono (Info vazia
+// se ilegível), ou nil se outra execução o removeu antes. A remoção é feita
+// com o arquivo path+".break" criado de forma exclusiva, e o lock é lido de
+// novo antes: assim duas execuções não removem uma o lock recém-criado pela
+// outra.
+func breakStale(path, host string, staleAfter time.Duration) (*Info, error) {
+	guard := path + ".break"
+	f, err := os.OpenFile(guard, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if errors.Is(err, fs.ErrExist) {
+		// Outra execução está removendo o lock; um arquivo antigo foi deixado
+		// por um processo que morreu durante a remoção
+		if st, statErr := os.Stat(guard); statErr == nil && time.Since(st.ModTime()) > incompleteAfter {
+			_ = os.Remove(guard)
+		}
+		return nil, &HeldError{Resource: path}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("criar %s falhou: %w", guard, err)
+	}
+	_ = f.Close()
+	defer os.Remove(guard)
+
+	holder, stale, err := inspect(path, host, staleAfter)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !stale {
+		return nil, &HeldError{Resource: path, Holder: holder}
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("remover lock abandonado %s falhou: %w", path, err)
+	}
+	if holder == nil {
+		holder = &Info{}
+	}
+	return holder, nil
+}
+
+// create cria o arquivo de lock de forma exclusiva e grava Info.
+func (l *FileLock) create() error {
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		return fmt.Errorf("criar lock %s falhou: %w", l.Path, err)
+	}
+	data, _ := json.Marshal(l.Info)
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(l.Path)
+		return fmt.Errorf("gravar lock %s falhou: %w", l.Path, err)
+	}
+	return nil
+}
+
+// inspect lê o dono do lock em path e decide se o lock foi abandonado. Retorna
+// fs.ErrNotExist se o lock foi liberado entre a tentativa de criação e a
+// leitura.
+func inspect(path, host string, staleAfter time.Duration) (holder *Info, stale bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("ler lock %s falhou: %w", path, err)
+	}
+
+	info := &Info{}
+	if err := json.Unmarshal(data, info); err != nil || info.PID == 0 {
+		st, statErr := os.Stat(path)
+		if statErr != nil {
+			return nil, false, statErr
+		}
+		return nil, time.Since(st.ModTime()) > incompleteAfter, nil
+	}
+
+	if info.Host == host {
+		// O próprio processo (modo serve) nunca abandona um lock
+		if info.PID == os.Getpid() {
+			return info, false, nil
+		}
+		return info, !processAlive(info.PID), nil
+	}
+	return info, staleAfter > 0 && time.Since(info.Started) > staleAfter, nil
+}
+
+// Release remove o arquivo de lock, desde que ainda pertença a esta execução.
+func (l *FileLock) Release() error {
+	data, err := os.ReadFile(l.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ler lock %s falhou: %w", l.Path, err)
+	}
+	var current Info
+	if json.Unmarshal(data, &current) != nil || current.PID != l.Info.PID || current.Host != l.Info.Host || !current.Started.Equal(l.Info.Started) {
+		return fmt.Errorf("lock %s não pertence mais a esta execução", l.Path)
+	}
+	if err := os.Remove(l.Path); err != nil {
+		return fmt.Errorf("remover lock %s falhou: %w", l.Path, err)
+	}
+	return nil
+}
+
+// Wait chama try até que ele não retorne ErrLocked, a cada PollInterval. Com
+// timeout > 0, desiste após esse tempo retornando o último erro.
+func Wait(ctx context.Context, timeout time.Duration, try func() error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for {
+		err := try()
+		if !errors.Is(err, ErrLocked) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("tempo limite de espera pelo lock excedido: %w", err)
+			}
+			return fmt.Errorf("espera pelo lock cancelada: %w", err)
+		case <-ticker.C:
+		}
+	}
+}
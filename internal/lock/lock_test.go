@@ -0,0 +1,213 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLock(t *testing.T, path string, info Info) {
+	t.Helper()
+	data, err := json.Marshal(info)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0640))
+}
+
+func TestTryFile_AcquireAndRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName("SCM"))
+
+	l, err := TryFile(path, "SCM", DefaultStaleAfter)
+	require.NoError(t, err)
+	assert.Nil(t, l.Stale)
+	assert.Equal(t, os.Getpid(), l.Info.PID)
+
+	// Segunda execução, ainda que do mesmo processo, encontra o lock em uso
+	_, err = TryFile(path, "SCM", DefaultStaleAfter)
+	var held *HeldError
+	require.ErrorAs(t, err, &held)
+	assert.ErrorIs(t, err, ErrLocked)
+	require.NotNil(t, held.Holder)
+	assert.Equal(t, os.Getpid(), held.Holder.PID)
+
+	require.NoError(t, l.Release())
+	assert.NoFileExists(t, path)
+
+	l, err = TryFile(path, "SCM", DefaultStaleAfter)
+	require.NoError(t, err)
+	require.NoError(t, l.Release())
+}
+
+func TestTryFile_DeadProcessIsStale(t *testing.T) {
+	cmd := exec.Command("true")
+	require.NoError(t, cmd.Run())
+	host, _ := os.Hostname()
+
+	path := filepath.Join(t.TempDir(), FileName("SCM"))
+	dead := Info{PID: cmd.Process.Pid, Host: host, Database: "SCM", Started: time.Now()}
+	writeLock(t, path, dead)
+
+	l, err := TryFile(path, "SCM", DefaultStaleAfter)
+	require.NoError(t, err)
+	require.NotNil(t, l.Stale)
+	assert.Equal(t, dead.PID, l.Stale.PID)
+	require.NoError(t, l.Release())
+}
+
+func TestTryFile_ConcurrentStaleBreak(t *testing.T) {
+	cmd := exec.Command("true")
+	require.NoError(t, cmd.Run())
+	host, _ := os.Hostname()
+	dir := t.TempDir()
+
+	for i := range 50 {
+		path := filepath.Join(dir, FileName(fmt.Sprintf("SCM%d", i)))
+		writeLock(t, path, Info{PID: cmd.Process.Pid, Host: host, Database: "SCM", Started: time.Now()})
+
+		// Várias execuções encontram o mesmo lock abandonado: só uma o obtém
+		var (
+			wg       sync.WaitGroup
+			acquired atomic.Int32
+		)
+		start := make(chan struct{})
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				_, err := TryFile(path, "SCM", DefaultStaleAfter)
+				if err == nil {
+					acquired.Add(1)
+				} else {
+					assert.ErrorIs(t, err, ErrLocked)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+		require.Equal(t, int32(1), acquired.Load(), "iteração %d", i)
+		assert.NoFileExists(t, path+".break")
+	}
+}
+
+func TestBreakStale_Rechecks(t *testing.T) {
+	host, _ := os.Hostname()
+	path := filepath.Join(t.TempDir(), FileName("SCM"))
+
+	// Lock visto como abandonado, mas já removido e obtido por outra execução
+	// antes da remoção: não pode ser removido
+	live := Info{PID: 1234, Host: "outro-servidor", Started: time.Now()}
+	writeLock(t, path, live)
+	_, err := breakStale(path, host, DefaultStaleAfter)
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.FileExists(t, path)
+
+	// Já liberado: nada a remover
+	require.NoError(t, os.Remove(path))
+	removed, err := breakStale(path, host, DefaultStaleAfter)
+	require.NoError(t, err)
+	assert.Nil(t, removed)
+}
+
+func TestTryFile_AbandonedBreakGuard(t *testing.T) {
+	cmd := exec.Command("true")
+	require.NoError(t, cmd.Run())
+	host, _ := os.Hostname()
+	path := filepath.Join(t.TempDir(), FileName("SCM"))
+	writeLock(t, path, Info{PID: cmd.Process.Pid, Host: host, Started: time.Now()})
+
+	// Remoção em andamento por outra execução
+	require.NoError(t, os.WriteFile(path+".break", nil, 0640))
+	_, err := TryFile(path, "SCM", DefaultStaleAfter)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// Deixado por um processo que morreu durante a remoção
+	old := time.Now().Add(-2 * incompleteAfter)
+	require.NoError(t, os.Chtimes(path+".break", old, old))
+	_, err = TryFile(path, "SCM", DefaultStaleAfter)
+	assert.ErrorIs(t, err, ErrLocked)
+	l, err := TryFile(path, "SCM", DefaultStaleAfter)
+	require.NoError(t, err)
+	assert.NotNil(t, l.Stale)
+}
+
+func TestTryFile_OtherHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName("SCM"))
+
+	// Processo de outro host não pode ser verificado: vale a idade do lock
+	writeLock(t, path, Info{PID: 1234, Host: "outro-servidor", Started: time.Now().Add(-time.Hour)})
+	_, err := TryFile(path, "SCM", DefaultStaleAfter)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	writeLock(t, path, Info{PID: 1234, Host: "outro-servidor", Started: time.Now().Add(-48 * time.Hour)})
+	l, err := TryFile(path, "SCM", DefaultStaleAfter)
+	require.NoError(t, err)
+	assert.Equal(t, "outro-servidor", l.Stale.Host)
+}
+
+func TestTryFile_Incomplete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName("SCM"))
+	require.NoError(t, os.WriteFile(path, nil, 0640))
+
+	// Recém-criado: a outra execução pode estar gravando o conteúdo
+	_, err := TryFile(path, "SCM", DefaultStaleAfter)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	old := time.Now().Add(-2 * incompleteAfter)
+	require.NoError(t, os.Chtimes(path, old, old))
+	l, err := TryFile(path, "SCM", DefaultStaleAfter)
+	require.NoError(t, err)
+	assert.NotNil(t, l.Stale)
+}
+
+func TestRelease_NotOwner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName("SCM"))
+	l, err := TryFile(path, "SCM", DefaultStaleAfter)
+	require.NoError(t, err)
+
+	// Lock removido como abandonado e obtido por outra execução
+	writeLock(t, path, Info{PID: 1234, Host: "outro-servidor", Started: time.Now()})
+	assert.Error(t, l.Release())
+	assert.FileExists(t, path)
+}
+
+func TestWait(t *testing.T) {
+	old := PollInterval
+	PollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { PollInterval = old })
+
+	attempts := 0
+	err := Wait(context.Background(), time.Second, func() error {
+		attempts++
+		if attempts < 3 {
+			return &HeldError{Resource: "x"}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	err = Wait(context.Background(), 50*time.Millisecond, func() error { return &HeldError{Resource: "x"} })
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.ErrorContains(t, err, "tempo limite")
+
+	// Erros que não são de lock encerram a espera
+	boom := errors.New("disco cheio")
+	assert.ErrorIs(t, Wait(context.Background(), 0, func() error { return boom }), boom)
+}
+
+func TestFileName(t *testing.T) {
+	assert.Equal(t, "dbbackup-SCM.lock", FileName("SCM"))
+	assert.Equal(t, "dbbackup-a_b_c.lock", FileName(`a/b\c`))
+	assert.Equal(t, "dbbackup-a_b_c_d_e_f_g_h.lock", FileName(`a:b*c?d"e<f>g|h`))
+}
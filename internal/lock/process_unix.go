@@ -0,0 +1,15 @@
+//go:build !windows
+
+package lock
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive informa se o processo pid existe. O sinal 0 só verifica a
+// existência; EPERM indica um processo vivo de outro usuário.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package lock
+
+import (
+	"errors"
+	"syscall"
+)
+
+const (
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+	errorAccessDenied              = syscall.Errno(5)
+)
+
+// processAlive informa se o processo pid existe e ainda não terminou. Acesso
+// negado indica um processo vivo de outro usuário.
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return errors.Is(err, errorAccessDenied)
+	}
+	defer syscall.CloseHandle(h)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// redactedValue substitui os segredos encontrados nas mensagens de log.
+const redactedValue = "****"
+
+// Redactor mascara segredos (ex: senhas) em textos. É usado pelo
+// RedactingHandler e pelas saídas que não passam pelo logger, como as
+// notificações e o relatório da execução.
+type Redactor struct {
+	replacer *strings.Replacer
+}
+
+// NewRedactor mascara qualquer ocorrência dos segredos informados. Segredos
+// vazios são ignorados.
+func NewRedactor(secrets ...string) *Redactor {
+	var pairs []string
+	for _, s := range secrets {
+		if s != "" {
+			pairs = append(pairs, s, redactedValue)
+		}
+	}
+	return &Redactor{replacer: strings.NewReplacer(pairs...)}
+}
+
+// Redact retorna s com os segredos mascarados.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// RedactingHandler é um slog.Handler que remove segredos (ex: senhas) da
+// mensagem e de todos os atributos antes de repassar o registro.
+type RedactingHandler struct {
+	next     slog.Handler
+	redactor *Redactor
+}
+
+// NewRedactingHandler envolve next, mascarando qualquer ocorrência dos segredos informados.
+// Segredos vazios são ignorados.
+func NewRedactingHandler(next slog.Handler, secrets ...string) *RedactingHandler {
+	return &RedactingHandler{next: next, redactor: NewRedactor(secrets...)}
+}
+
+// WithRedaction retorna um logger que mascara os segredos informados.
+func WithRedaction(l *slog.Logger, secrets ...string) *slog.Logger {
+	return WithRedactor(l, NewRedactor(secrets...))
+}
+
+// WithRedactor retorna um logger que mascara os segredos de r.
+func WithRedactor(l *slog.Logger, r *Redactor) *slog.Logger {
+	return slog.New(&RedactingHandler{next: l.Handler(), redactor: r})
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, h.redactor.Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redactAttr(a)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(redacted), redactor: h.redactor}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
+
+// redactAttr mascara segredos em strings, erros e valores formatáveis. Um
+// valor que não contém segredo é repassado sem alteração, mantendo o seu tipo
+// (ex: lista ou objeto no JSON).
+func (h *RedactingHandler) redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.redactor.Redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		redacted := make([]any, len(group))
+		for i, ga := range group {
+			redacted[i] = h.redactAttr(ga)
+		}
+		return slog.Group(a.Key, redacted...)
+	case slog.KindAny:
+		s := fmt.Sprint(v.Any())
+		if redacted := h.redactor.Redact(s); redacted != s {
+			return slog.String(a.Key, redacted)
+		}
+		return slog.Attr{Key: a.Key, Value: v}
+	default:
+		return a
+	}
+}
@@ -0,0 +1,165 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+)
+
+// EntryName é o nome do manifesto gravado dentro do arquivo (zip ou tar).
+const EntryName = "manifest.json"
+
+// SidecarSuffix é o sufixo do manifesto gravado ao lado do arquivo final.
+const SidecarSuffix = ".manifest.json"
+
+// File descreve um arquivo de backup (.bak) incluído no arquivo final.
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Archive descreve o arquivo final compactado. Só existe no sidecar, pois o
+// tamanho e o checksum do arquivo não são conhecidos enquanto ele é gravado.
+type Archive struct {
+	Name        string `json:"name"`
+	Format      string `json:"format"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	VolumeCount int    `json:"volume_count,omitempty"`
+}
+
+// Binlog são as coordenadas do log binário do MySQL/MariaDB no instante do
+// backup, usadas para configurar uma réplica ou para a recuperação até um ponto
+// no tempo.
+type Binlog struct {
+	File     string `json:"file"`
+	Position int64  `json:"position"`
+	GTID     string `json:"gtid,omitempty"`
+}
+
+// Manifest reúne os metadados de um backup para auditoria e para escolha
+// da cadeia de restauração.
+type Manifest struct {
+	ToolVersion      string    `json:"tool_version"`
+	Engine           string    `json:"engine,omitempty"` // sqlserver, postgres, mysql, sqlite ou files; vazio em manifestos antigos (sqlserver)
+	Server           string    `json:"server"`
+	Database         string    `json:"database"`
+	SQLServerVersion string    `json:"sqlserver_version,omitempty"`
+	ServerVersion    string    `json:"server_version,omitempty"` // Versão do servidor nos demais engines
+	BackupType       string    `json:"backup_type"`              // full, differential ou log
+	DumpFormat       string    `json:"dump_format,omitempty"`    // Formato do dump (ex: custom, basebackup, sql, vacuum, files)
+	FirstLSN         string    `json:"first_lsn,omitempty"`
+	LastLSN          string    `json:"last_lsn,omitempty"`
+	Binlog           *Binlog   `json:"binlog,omitempty"`
+	BackupStart      time.Time `json:"backup_start"`
+	BackupFinish     time.Time `json:"backup_finish"`
+	BakSize          int64     `json:"bak_size"`
+	Files            []File    `json:"files"`
+	Archive          *Archive  `json:"archive,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// SidecarName retorna o nome do manifesto sidecar de um arquivo final.
+func SidecarName(archiveName string) string {
+	return archiveName + SidecarSuffix
+}
+
+// IsSidecar indica se o caminho é um manifesto sidecar.
+func IsSidecar(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), SidecarSuffix)
+}
+
+// SidecarFor retorna o caminho do sidecar correspondente a um arquivo enviado
+// pelo uploader. Volumes (DB.zip.001) e manifestos de volumes
+// (DB.zip.volumes.json) apontam para o sidecar do arquivo lógico (DB.zip).
+func SidecarFor(filePath string) string {
+	logical := filePath
+	switch {
+	case archive.IsVolumeManifest(filePath):
+		logical = filePath[:len(filePath)-len(archive.VolumeManifestSuffix)]
+	case archive.IsVolumePart(filePath):
+		logical = strings.TrimSuffix(filePath, filepath.Ext(filePath))
+	}
+	return logical + SidecarSuffix
+}
+
+// Marshal serializa o manifesto em JSON indentado.
+func (m *Manifest) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("serializar manifesto falhou: %w", err)
+	}
+	return data, nil
+}
+
+// WriteFile grava o manifesto em path de forma atômica (arquivo temporário + rename).
+func (m *Manifest) WriteFile(path string) error {
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0640); err != nil {
+		return fmt.Errorf("gravar manifesto %s falhou: %w", tempPath, err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("renomear manifesto para %s falhou: %w", path, err)
+	}
+	return nil
+}
+
+// ReadFile lê um manifesto gravado em path.
+func ReadFile(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ler manifesto %s falhou: %w", path, err)
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("decodificar manifesto %s falhou: %w", path, err)
+	}
+	return m, nil
+}
+
+// DriveProperties retorna os campos principais do manifesto no formato de
+// properties de arquivo do Google Drive (chave + valor limitados a 124 bytes).
+func (m *Manifest) DriveProperties() map[string]string {
+	props := map[string]string{
+		"engine":        m.Engine,
+		"database":      m.Database,
+		"server":        m.Server,
+		"backup_type":   m.BackupType,
+		"first_lsn":     m.FirstLSN,
+		"last_lsn":      m.LastLSN,
+		"bak_size":      strconv.FormatInt(m.BakSize, 10),
+		"tool_version":  m.ToolVersion,
+		"backup_finish": m.BackupFinish.UTC().Format(time.RFC3339),
+	}
+	if m.Binlog != nil {
+		props["binlog_file"] = m.Binlog.File
+		props["binlog_position"] = strconv.FormatInt(m.Binlog.Position, 10)
+	}
+	if m.Archive != nil {
+		props["archive_sha256"] = m.Archive.SHA256
+		props["archive_size"] = strconv.FormatInt(m.Archive.Size, 10)
+	}
+
+	for k, v := range props {
+		if v == "" {
+			delete(props, k)
+			continue
+		}
+		if limit := 124 - len(k); len(v) > limit {
+			props[k] = v[:limit]
+		}
+	}
+	return props
+}
@@ -0,0 +1,69 @@
+package manifest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSidecarFor(t *testing.T) {
+	assert.Equal(t, "/backups/SCM.zip.manifest.json", SidecarFor("/backups/SCM.zip"))
+	assert.Equal(t, "/backups/SCM.bak.zst.manifest.json", SidecarFor("/backups/SCM.bak.zst"))
+	assert.Equal(t, "/backups/SCM.zip.manifest.json", SidecarFor("/backups/SCM.zip.002"))
+	assert.Equal(t, "/backups/SCM.zip.manifest.json", SidecarFor("/backups/SCM.zip.volumes.json"))
+}
+
+func TestManifest_WriteAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SidecarName("SCM_20250407_164500.zip"))
+	m := &Manifest{
+		ToolVersion:  "v1.0.0",
+		Server:       "SQLPROD01",
+		Database:     "SCM",
+		BackupType:   "full",
+		FirstLSN:     "37000000012800001",
+		LastLSN:      "37000000014400001",
+		BackupStart:  time.Date(2025, 4, 7, 16, 45, 0, 0, time.UTC),
+		BackupFinish: time.Date(2025, 4, 7, 16, 50, 0, 0, time.UTC),
+		BakSize:      1024,
+		Files:        []File{{Name: "SCM_20250407_164500.bak", Size: 1024, SHA256: "abc"}},
+		Archive:      &Archive{Name: "SCM_20250407_164500.zip", Format: "zip", Size: 512, SHA256: "def"},
+	}
+
+	require.NoError(t, m.WriteFile(path))
+	assert.True(t, IsSidecar(path))
+	assert.NoFileExists(t, path+".tmp")
+
+	got, err := ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, m.Database, got.Database)
+	assert.Equal(t, m.LastLSN, got.LastLSN)
+	assert.True(t, m.BackupFinish.Equal(got.BackupFinish))
+	assert.Equal(t, m.Files, got.Files)
+	assert.Equal(t, m.Archive, got.Archive)
+}
+
+func TestManifest_DriveProperties(t *testing.T) {
+	m := &Manifest{
+		Database:     "SCM",
+		Server:       strings.Repeat("s", 200),
+		BackupType:   "full",
+		BackupFinish: time.Date(2025, 4, 7, 16, 50, 0, 0, time.UTC),
+		BakSize:      2048,
+		Archive:      &Archive{SHA256: strings.Repeat("a", 64), Size: 100},
+	}
+
+	props := m.DriveProperties()
+	assert.Equal(t, "SCM", props["database"])
+	assert.Equal(t, "full", props["backup_type"])
+	assert.Equal(t, "2048", props["bak_size"])
+	assert.Equal(t, "2025-04-07T16:50:00Z", props["backup_finish"])
+	assert.Equal(t, strings.Repeat("a", 64), props["archive_sha256"])
+	assert.NotContains(t, props, "first_lsn", "campos vazios não devem ser enviados")
+	for k, v := range props {
+		assert.LessOrEqual(t, len(k)+len(v), 124, "property %s excede o limite do Drive", k)
+	}
+}
@@ -0,0 +1,49 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrAppLockHeld indica que o lock de aplicação pertence a outra sessão.
+var ErrAppLockHeld = errors.New("lock de aplicação em uso por outra sessão")
+
+// maxAppLockResource é o tamanho máximo de @Resource em sp_getapplock.
+const maxAppLockResource = 255
+
+// GetAppLock obtém um lock exclusivo de aplicação (sp_getapplock) com dono
+// Session, sem espera. O lock pertence à sessão de conn e é liberado por
+// ReleaseAppLock ou quando a sessão termina, inclusive se o processo morrer.
+func GetAppLock(ctx context.Context, conn *sql.Conn, resource string) error {
+	if resource == "" || len([]rune(resource)) > maxAppLockResource {
+		return fmt.Errorf("nome de lock de aplicação inválido %q", resource)
+	}
+	const query = `DECLARE @result int;
+EXEC @result = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0;
+SELECT @result`
+
+	var result int
+	if err := conn.QueryRowContext(ctx, query, resource).Scan(&result); err != nil {
+		return fmt.Errorf("sp_getapplock para %s falhou: %w", resource, err)
+	}
+	switch result {
+	case 0, 1:
+		return nil
+	case -1:
+		return fmt.Errorf("%w: %s", ErrAppLockHeld, resource)
+	default:
+		// -2 cancelado, -3 deadlock, -999 erro de parâmetro ou chamada
+		return fmt.Errorf("sp_getapplock para %s retornou %d", resource, result)
+	}
+}
+
+// ReleaseAppLock libera um lock obtido por GetAppLock na mesma sessão.
+func ReleaseAppLock(ctx context.Context, conn *sql.Conn, resource string) error {
+	_, err := conn.ExecContext(ctx, "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'", resource)
+	if err != nil {
+		return fmt.Errorf("sp_releaseapplock para %s falhou: %w", resource, err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package mssql
+
+import (
+	"fmt"
+	"net/url"
+	"runtime"
+	"strings"
+
+	"github.com/microsoft/go-mssqldb/azuread"
+	// Registra o autenticador Kerberos (authenticator=krb5) no driver
+	_ "github.com/microsoft/go-mssqldb/integratedauth/krb5"
+)
+
+// Modos de autenticação aceitos pelo flag -auth.
+const (
+	AuthSQL          = "sql"           // Login do SQL Server (usuário e senha)
+	AuthWindows      = "windows"       // Integrada: SSPI no Windows, NTLM (DOMINIO\usuário) nos demais
+	AuthKerberos     = "kerberos"      // Kerberos com keytab, cache de credenciais ou senha
+	AuthAzureSP      = "azure-sp"      // Azure AD service principal (client secret ou certificado)
+	AuthAzureMSI     = "azure-msi"     // Azure AD managed identity
+	AuthAzureDefault = "azure-default" // Cadeia padrão do Azure SDK (env, managed identity, Azure CLI)
+)
+
+// AuthModes lista os modos de autenticação suportados.
+var AuthModes = []string{AuthSQL, AuthWindows, AuthKerberos, AuthAzureSP, AuthAzureMSI, AuthAzureDefault}
+
+// ConnOptions reúne os dados necessários para conectar ao SQL Server.
+type ConnOptions struct {
+	Server   string // host, host\instância ou host,porta
+	Database string
+	User     string
+	Password string
+	Auth     string
+
+	Krb5ConfigFile string // krb5.conf
+	Krb5Keytab     string
+	Krb5CredCache  string
+	Krb5Realm      string
+
+	AzureTenantID   string // Opcional para azure-sp (usa o tenant do servidor se vazio)
+	AzureClientCert string // Certificado do service principal, alternativa à senha
+
+	Encrypt         string // strict, mandatory (padrão), optional ou disable
+	TrustServerCert bool   // Aceita qualquer certificado do servidor (não recomendado)
+	TLSCAFile       string // Bundle de CAs (PEM ou DER) para validar o certificado do servidor
+	TLSHostname     string // Nome esperado no certificado, quando difere de Server
+	TLSFingerprint  string // SHA-256 do certificado do servidor (fixação)
+}
+
+// Validate verifica se as credenciais exigidas pelo modo de autenticação foram
+// informadas e se as opções de TLS são coerentes.
+func (o ConnOptions) Validate() error {
+	switch o.Auth {
+	case AuthSQL:
+		if o.User == "" || o.Password == "" {
+			return fmt.Errorf("autenticação sql exige usuário e senha")
+		}
+	case AuthWindows:
+		// Fora do Windows a autenticação integrada usa NTLM e precisa das credenciais do domínio
+		if runtime.GOOS != "windows" && (o.User == "" || o.Password == "") {
+			return fmt.Errorf("autenticação windows fora do Windows (NTLM) exige usuário no formato DOMINIO\\usuário e senha")
+		}
+	case AuthKerberos:
+		if o.User == "" || o.Krb5Realm == "" || o.Krb5ConfigFile == "" {
+			return fmt.Errorf("autenticação kerberos exige usuário, realm e arquivo krb5.conf")
+		}
+		if o.Krb5Keytab == "" && o.Krb5CredCache == "" && o.Password == "" {
+			return fmt.Errorf("autenticação kerberos exige keytab, cache de credenciais ou senha")
+		}
+	case AuthAzureSP:
+		if o.User == "" {
+			return fmt.Errorf("autenticação azure-sp exige o client ID da aplicação como usuário")
+		}
+		if o.Password == "" && o.AzureClientCert == "" {
+			return fmt.Errorf("autenticação azure-sp exige client secret (senha) ou certificado")
+		}
+	case AuthAzureMSI, AuthAzureDefault:
+		// Usuário opcional (client ID de uma identidade atribuída pelo usuário)
+	default:
+		return fmt.Errorf("modo de autenticação inválido '%s' (use %s)", o.Auth, strings.Join(AuthModes, ", "))
+	}
+	return o.validateTLS()
+}
+
+// ConnString monta a connection string para o modo de autenticação escolhido
+// e retorna também o nome do driver database/sql a ser usado.
+func ConnString(o ConnOptions) (driverName, connString string, err error) {
+	if err := o.Validate(); err != nil {
+		return "", "", err
+	}
+
+	driverName = "sqlserver"
+	user, password := o.User, o.Password
+	params := url.Values{}
+	params.Set("database", o.Database)
+	o.setTLSParams(params)
+
+	switch o.Auth {
+	case AuthWindows:
+		if runtime.GOOS == "windows" && user == "" {
+			// Sem usuário o driver usa SSPI com a conta do processo
+			password = ""
+		}
+	case AuthKerberos:
+		params.Set("authenticator", "krb5")
+		params.Set("krb5-configfile", o.Krb5ConfigFile)
+		params.Set("krb5-realm", o.Krb5Realm)
+		if o.Krb5Keytab != "" {
+			params.Set("krb5-keytabfile", o.Krb5Keytab)
+			password = ""
+		} else if o.Krb5CredCache != "" {
+			params.Set("krb5-credcachefile", o.Krb5CredCache)
+			password = ""
+		}
+	case AuthAzureSP:
+		driverName = azuread.DriverName
+		params.Set("fedauth", azuread.ActiveDirectoryServicePrincipal)
+		if o.AzureTenantID != "" {
+			user = o.User + "@" + o.AzureTenantID
+		}
+		if o.AzureClientCert != "" {
+			params.Set("clientcertpath", o.AzureClientCert)
+		}
+	case AuthAzureMSI:
+		driverName = azuread.DriverName
+		params.Set("fedauth", azuread.ActiveDirectoryManagedIdentity)
+		password = ""
+	case AuthAzureDefault:
+		driverName = azuread.DriverName
+		params.Set("fedauth", azuread.ActiveDirectoryDefault)
+		user, password = "", ""
+	}
+
+	// net/url cuida do escape de caracteres especiais em usuário e senha
+	u := &url.URL{Scheme: "sqlserver", RawQuery: params.Encode()}
+	u.Host, u.Path = splitServer(o.Server)
+	if user != "" {
+		if password != "" {
+			u.User = url.UserPassword(user, password)
+		} else {
+			u.User = url.User(user)
+		}
+	}
+	return driverName, u.String(), nil
+}
+
+// splitServer converte os formatos aceitos em -server (host\instância ou
+// host,porta) em host e path da URL sqlserver://host:porta/instância.
+func splitServer(server string) (host, instance string) {
+	host = server
+	if i := strings.Index(host, "\\"); i >= 0 {
+		host, instance = host[:i], host[i+1:]
+	}
+	if i := strings.LastIndex(host, ","); i >= 0 {
+		host = host[:i] + ":" + strings.TrimSpace(host[i+1:])
+	}
+	return host, instance
+}
@@ -0,0 +1,126 @@
+package mssql
+
+import (
+	"net/url"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseConnString(t *testing.T, connString string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(connString)
+	require.NoError(t, err)
+	return u
+}
+
+func TestConnOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ConnOptions
+		wantErr bool
+	}{
+		{name: "sql com credenciais", opts: ConnOptions{Auth: AuthSQL, User: "backup", Password: "x"}},
+		{name: "sql sem senha", opts: ConnOptions{Auth: AuthSQL, User: "backup"}, wantErr: true},
+		{name: "kerberos com keytab", opts: ConnOptions{Auth: AuthKerberos, User: "svc", Krb5Realm: "EMPRESA.LOCAL", Krb5ConfigFile: "/etc/krb5.conf", Krb5Keytab: "/etc/svc.keytab"}},
+		{name: "kerberos sem realm", opts: ConnOptions{Auth: AuthKerberos, User: "svc", Krb5ConfigFile: "/etc/krb5.conf", Krb5Keytab: "/etc/svc.keytab"}, wantErr: true},
+		{name: "kerberos sem credencial", opts: ConnOptions{Auth: AuthKerberos, User: "svc", Krb5Realm: "EMPRESA.LOCAL", Krb5ConfigFile: "/etc/krb5.conf"}, wantErr: true},
+		{name: "azure-sp com certificado", opts: ConnOptions{Auth: AuthAzureSP, User: "client-id", AzureClientCert: "/etc/sp.pem"}},
+		{name: "azure-sp sem segredo", opts: ConnOptions{Auth: AuthAzureSP, User: "client-id"}, wantErr: true},
+		{name: "azure-msi sem usuário", opts: ConnOptions{Auth: AuthAzureMSI}},
+		{name: "modo desconhecido", opts: ConnOptions{Auth: "ldap"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConnString_SQL(t *testing.T) {
+	driver, connString, err := ConnString(ConnOptions{Server: "sqlprod01", Database: "SCM", User: "backup", Password: "segredo", Auth: AuthSQL})
+	require.NoError(t, err)
+	assert.Equal(t, "sqlserver", driver)
+
+	u := parseConnString(t, connString)
+	assert.Equal(t, "backup", u.User.Username())
+	assert.Equal(t, "SCM", u.Query().Get("database"))
+}
+
+func TestConnString_Kerberos(t *testing.T) {
+	driver, connString, err := ConnString(ConnOptions{
+		Server: "sqlprod01", Database: "SCM", User: "svc_backup", Auth: AuthKerberos,
+		Krb5ConfigFile: "/etc/krb5.conf", Krb5Realm: "EMPRESA.LOCAL", Krb5Keytab: "/etc/svc.keytab",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "sqlserver", driver)
+
+	q := parseConnString(t, connString).Query()
+	assert.Equal(t, "krb5", q.Get("authenticator"))
+	assert.Equal(t, "/etc/svc.keytab", q.Get("krb5-keytabfile"))
+	assert.Equal(t, "EMPRESA.LOCAL", q.Get("krb5-realm"))
+}
+
+func TestConnString_Azure(t *testing.T) {
+	driver, connString, err := ConnString(ConnOptions{
+		Server: "empresa.database.windows.net", Database: "SCM", User: "client-id", Password: "secret",
+		Auth: AuthAzureSP, AzureTenantID: "tenant-id",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "azuresql", driver)
+	u := parseConnString(t, connString)
+	assert.Equal(t, "client-id@tenant-id", u.User.Username())
+	assert.Equal(t, "ActiveDirectoryServicePrincipal", u.Query().Get("fedauth"))
+
+	driver, connString, err = ConnString(ConnOptions{Server: "empresa.database.windows.net", Database: "SCM", Auth: AuthAzureMSI})
+	require.NoError(t, err)
+	assert.Equal(t, "azuresql", driver)
+	u = parseConnString(t, connString)
+	assert.Nil(t, u.User)
+	assert.Equal(t, "ActiveDirectoryManagedIdentity", u.Query().Get("fedauth"))
+}
+
+func TestConnString_WindowsNTLM(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no Windows a autenticação integrada usa SSPI")
+	}
+	_, _, err := ConnString(ConnOptions{Server: "sqlprod01", Database: "SCM", Auth: AuthWindows})
+	assert.Error(t, err, "NTLM exige credenciais do domínio")
+}
+
+func TestConnString_EscapesSpecialCharacters(t *testing.T) {
+	password := `p@ss;word=1&x/y?z#"'\`
+	_, connString, err := ConnString(ConnOptions{Server: "sqlprod01", Database: "SCM;Initial", User: `dom\backup`, Password: password, Auth: AuthSQL})
+	require.NoError(t, err)
+
+	u := parseConnString(t, connString)
+	got, ok := u.User.Password()
+	require.True(t, ok)
+	assert.Equal(t, password, got)
+	assert.Equal(t, `dom\backup`, u.User.Username())
+	assert.Equal(t, "SCM;Initial", u.Query().Get("database"))
+}
+
+func TestSplitServer(t *testing.T) {
+	tests := []struct {
+		server, host, instance string
+	}{
+		{"sqlprod01", "sqlprod01", ""},
+		{`sqlprod01\SQLEXPRESS`, "sqlprod01", "SQLEXPRESS"},
+		{"sqlprod01,1433", "sqlprod01:1433", ""},
+		{"sqlprod01, 14330", "sqlprod01:14330", ""},
+	}
+	for _, tt := range tests {
+		host, instance := splitServer(tt.server)
+		assert.Equal(t, tt.host, host, tt.server)
+		assert.Equal(t, tt.instance, instance, tt.server)
+	}
+}
@@ -0,0 +1,91 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Origens de uma estimativa de tamanho de backup.
+const (
+	EstimateHistory = "history" // Último backup do mesmo tipo em msdb.dbo.backupset
+	EstimateUsed    = "used"    // Páginas em uso nos arquivos de dados (como sp_spaceused)
+	EstimateLog     = "log"     // Log gerado desde o último backup de log (sys.dm_db_log_stats)
+)
+
+// SizeEstimate é o tamanho estimado do .bak de um backup.
+type SizeEstimate struct {
+	Bytes  int64
+	Source string
+}
+
+// backupTypeCode converte um tipo de backup no código de msdb.dbo.backupset.type.
+func backupTypeCode(backupType string) string {
+	switch backupType {
+	case BackupDifferential:
+		return "I"
+	case BackupLog:
+		return "L"
+	default:
+		return "D"
+	}
+}
+
+// EstimateBackupSize estima o tamanho do .bak do próximo backup de database.
+// Backups de log usam o log gerado desde o último backup de log; os demais, o
+// último backup do mesmo tipo registrado em msdb (comprimido, com compressed)
+// ou, sem histórico, o espaço em uso nos arquivos de dados, que é um limite
+// superior para o backup full e o diferencial.
+func EstimateBackupSize(ctx context.Context, db *sql.DB, database, backupType string, compressed bool) (*SizeEstimate, error) {
+	if backupType == BackupLog {
+		if est, err := queryLogEstimate(ctx, db, database); err == nil {
+			return est, nil
+		}
+	}
+
+	est, err := queryHistoryEstimate(ctx, db, database, backupType, compressed)
+	if err == nil {
+		return est, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) || backupType == BackupLog {
+		return nil, fmt.Errorf("consultar histórico de backups de %s falhou: %w", database, err)
+	}
+
+	var used sql.NullInt64
+	query := "SELECT CAST(SUM(used_pages) AS bigint) * 8192 FROM " + QuoteIdentifier(database) + ".sys.allocation_units"
+	if err := db.QueryRowContext(ctx, query).Scan(&used); err != nil {
+		return nil, fmt.Errorf("consultar espaço em uso de %s falhou: %w", database, err)
+	}
+	return &SizeEstimate{Bytes: used.Int64, Source: EstimateUsed}, nil
+}
+
+func queryHistoryEstimate(ctx context.Context, db *sql.DB, database, backupType string, compressed bool) (*SizeEstimate, error) {
+	const query = `SELECT TOP 1 CAST(backup_size AS bigint), CAST(ISNULL(compressed_backup_size, backup_size) AS bigint)
+FROM msdb.dbo.backupset
+WHERE database_name = @p1 AND type = @p2
+ORDER BY backup_finish_date DESC`
+
+	var size, compressedSize int64
+	if err := db.QueryRowContext(ctx, query, database, backupTypeCode(backupType)).Scan(&size, &compressedSize); err != nil {
+		return nil, err
+	}
+	if compressed {
+		size = compressedSize
+	}
+	return &SizeEstimate{Bytes: size, Source: EstimateHistory}, nil
+}
+
+func queryLogEstimate(ctx context.Context, db *sql.DB, database string) (*SizeEstimate, error) {
+	var mb sql.NullFloat64
+	err := db.QueryRowContext(ctx,
+		"SELECT log_since_last_log_backup_mb FROM sys.dm_db_log_stats(DB_ID(@p1))", database,
+	).Scan(&mb)
+	if err != nil {
+		return nil, err
+	}
+	if !mb.Valid {
+		return nil, sql.ErrNoRows
+	}
+	return &SizeEstimate{Bytes: int64(mb.Float64 * 1024 * 1024), Source: EstimateLog}, nil
+}
@@ -0,0 +1,84 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ServerInfo contém a identificação da instância SQL Server.
+type ServerInfo struct {
+	Name    string // @@SERVERNAME
+	Version string // SERVERPROPERTY('ProductVersion')
+}
+
+// BackupSet contém os dados de um backup registrado em msdb.dbo.backupset.
+type BackupSet struct {
+	Type   string // full, differential ou log
+	First  string // first_lsn
+	Last   string // last_lsn
+	Start  time.Time
+	Finish time.Time
+	Size   int64 // backup_size (bytes)
+}
+
+// QueryServerInfo obtém o nome e a versão da instância conectada.
+func QueryServerInfo(ctx context.Context, db *sql.DB) (*ServerInfo, error) {
+	info := &ServerInfo{}
+	var name sql.NullString
+	err := db.QueryRowContext(ctx,
+		"SELECT @@SERVERNAME, CAST(SERVERPROPERTY('ProductVersion') AS nvarchar(128))",
+	).Scan(&name, &info.Version)
+	if err != nil {
+		return nil, fmt.Errorf("consultar versão do servidor falhou: %w", err)
+	}
+	info.Name = name.String
+	return info, nil
+}
+
+// QueryBackupSet obtém os dados do backup mais recente gravado no dispositivo
+// devicePath (o mesmo caminho usado em TO DISK = '...').
+func QueryBackupSet(ctx context.Context, db *sql.DB, devicePath string) (*BackupSet, error) {
+	const query = `SELECT TOP 1 bs.type,
+	CONVERT(varchar(32), bs.first_lsn), CONVERT(varchar(32), bs.last_lsn),
+	bs.backup_start_date, bs.backup_finish_date, CAST(bs.backup_size AS bigint)
+FROM msdb.dbo.backupset bs
+JOIN msdb.dbo.backupmediafamily bmf ON bmf.media_set_id = bs.media_set_id
+WHERE bmf.physical_device_name = @p1
+ORDER BY bs.backup_set_id DESC`
+
+	set := &BackupSet{}
+	var typeCode string
+	var first, last sql.NullString
+	err := db.QueryRowContext(ctx, query, devicePath).Scan(&typeCode, &first, &last, &set.Start, &set.Finish, &set.Size)
+	if err != nil {
+		return nil, fmt.Errorf("consultar msdb.dbo.backupset para %s falhou: %w", devicePath, err)
+	}
+	set.Type = BackupTypeName(typeCode)
+	set.First = first.String
+	set.Last = last.String
+	return set, nil
+}
+
+// BackupTypeName converte o código de msdb.dbo.backupset.type em um nome legível.
+func BackupTypeName(code string) string {
+	switch code {
+	case "D":
+		return "full"
+	case "I":
+		return "differential"
+	case "L":
+		return "log"
+	case "F":
+		return "file"
+	case "G":
+		return "differential_file"
+	case "P":
+		return "partial"
+	case "Q":
+		return "differential_partial"
+	default:
+		return code
+	}
+}
@@ -0,0 +1,358 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-sql/sqlexp"
+)
+
+// DefaultProgressInterval é o intervalo padrão entre consultas de progresso.
+const DefaultProgressInterval = 30 * time.Second
+
+// Progress é o andamento de um comando longo (BACKUP, RESTORE) em execução no servidor.
+type Progress struct {
+	SessionID          int
+	Command            string        // Ex: BACKUP DATABASE
+	PercentComplete    float64       // 0 a 100
+	EstimatedRemaining time.Duration // Estimativa do servidor para a conclusão
+	Elapsed            time.Duration
+	LastMessage        string // Última mensagem informativa (ex: STATS)
+	UpdatedAt          time.Time
+}
+
+// ProgressTracker guarda o progresso mais recente de forma segura para leitura
+// concorrente (ex: por um endpoint de status).
+type ProgressTracker struct {
+	mu      sync.Mutex
+	current Progress
+	active  bool
+}
+
+// Snapshot retorna uma cópia do progresso atual e se há um comando em andamento.
+func (t *ProgressTracker) Snapshot() (Progress, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current, t.active
+}
+
+func (t *ProgressTracker) start(sessionID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current = Progress{SessionID: sessionID, UpdatedAt: time.Now()}
+	t.active = true
+}
+
+func (t *ProgressTracker) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active = false
+}
+
+// update aplica fn ao progresso atual e retorna o resultado.
+func (t *ProgressTracker) update(fn func(p *Progress)) Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.current)
+	t.current.UpdatedAt = time.Now()
+	return t.current
+}
+
+// ProgressOptions configura ExecWithProgress.
+type ProgressOptions struct {
+	Interval   time.Duration       // Intervalo entre consultas a sys.dm_exec_requests
+	Tracker    *ProgressTracker    // Opcional; recebe o progresso para consulta externa
+	OnProgress func(Progress)      // Chamado a cada consulta de progresso bem-sucedida
+	OnMessage  func(msg string)    // Chamado para cada mensagem informativa (PRINT, STATS)
+	OnPollErr  func(err error)     // Chamado quando a consulta de progresso falha
+	OnSession  func(sessionID int) // Chamado com o @@SPID da sessão antes do comando
+}
+
+// statsMessage reconhece as mensagens de BACKUP/RESTORE ... WITH STATS.
+var statsMessage = regexp.MustCompile(`(?i)^\s*(\d+)\s+percent\s+processed`)
+
+// parseStatsPercent extrai o percentual de uma mensagem de STATS.
+func parseStatsPercent(msg string) (float64, bool) {
+	m := statsMessage.FindStringSubmatch(msg)
+	if m == nil {
+		return 0, false
+	}
+	percent, err := strconv.ParseFloat(m[1], 64)
+	return percent, err == nil
+}
+
+// killTimeout limita o tempo de espera pelo KILL após um cancelamento.
+const killTimeout = 30 * time.Second
+
+// session é a conexão dedicada de um comando; sqlSession a implementa sobre
+// um *sql.Conn e os testes usam uma sessão falsa.
+type session interface {
+	// spid retorna o @@SPID da sessão.
+	spid(ctx context.Context) (int, error)
+	// run executa stmt entregando as mensagens informativas a onMessage.
+	run(ctx context.Context, stmt string, onMessage func(string)) error
+	// exec executa um comando sem resultado.
+	exec(ctx context.Context, stmt string) error
+	Close() error
+}
+
+// sessionPool abre sessões dedicadas e consulta o progresso de uma sessão.
+type sessionPool interface {
+	session(ctx context.Context) (session, error)
+	requestProgress(ctx context.Context, sessionID int) (*RequestProgress, error)
+}
+
+// ExecWithProgress executa stmt em uma conexão dedicada e, em paralelo,
+// consulta sys.dm_exec_requests para acompanhar percent_complete e
+// estimated_completion_time da sessão. As mensagens informativas do servidor
+// (ex: "10 percent processed.") são repassadas a OnMessage.
+//
+// A consulta de progresso exige a permissão VIEW SERVER STATE; sem ela o
+// comando é executado normalmente e apenas OnPollErr é chamado. Se ctx for
+// cancelado (sinal ou tempo limite), a sessão do comando recebe um KILL
+// (exige ALTER ANY CONNECTION), enviado por outra conexão enquanto o comando
+// ainda está em execução.
+func ExecWithProgress(ctx context.Context, db *sql.DB, stmt string, opts ProgressOptions) error {
+	return execWithProgress(ctx, sqlPool{db}, stmt, opts)
+}
+
+func execWithProgress(ctx context.Context, pool sessionPool, stmt string, opts ProgressOptions) error {
+	conn, err := pool.session(ctx)
+	if err != nil {
+		return fmt.Errorf("obter conexão falhou: %w", err)
+	}
+	defer conn.Close()
+
+	sessionID, err := conn.spid(ctx)
+	if err != nil {
+		return fmt.Errorf("obter @@SPID falhou: %w", err)
+	}
+	if opts.OnSession != nil {
+		opts.OnSession(sessionID)
+	}
+
+	tracker := opts.Tracker
+	if tracker == nil {
+		tracker = &ProgressTracker{}
+	}
+	tracker.start(sessionID)
+	defer tracker.finish()
+
+	pollCtx, stopPolling := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pollProgress(pollCtx, pool, sessionID, tracker, opts)
+	}()
+	defer func() {
+		stopPolling()
+		wg.Wait()
+	}()
+
+	// O driver envia um attention ao cancelar, mas o BACKUP pode demorar a
+	// reagir ou ignorá-lo, e a chamada só retorna quando o servidor responde. O
+	// KILL é enviado assim que ctx é cancelado, enquanto conn ainda está em uso:
+	// assim ele sai por outra conexão, e não pela própria sessão.
+	stopKill := killOnCancel(ctx, pool, sessionID)
+
+	err = conn.run(ctx, stmt, func(msg string) {
+		tracker.update(func(p *Progress) {
+			p.LastMessage = msg
+			if percent, ok := parseStatsPercent(msg); ok && percent > p.PercentComplete {
+				p.PercentComplete = percent
+			}
+		})
+		if opts.OnMessage != nil {
+			opts.OnMessage(msg)
+		}
+	})
+	if killErr := stopKill(); err != nil && killErr != nil {
+		return errors.Join(err, killErr)
+	}
+	return err
+}
+
+// killOnCancel envia KILL sessionID por uma nova sessão de pool quando ctx é
+// cancelado. A função retornada encerra a espera, aguarda o KILL em andamento
+// e retorna o seu erro.
+func killOnCancel(ctx context.Context, pool sessionPool, sessionID int) func() error {
+	done := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		select {
+		case <-done:
+			result <- nil
+		case <-ctx.Done():
+			result <- killSession(pool, sessionID)
+		}
+	}()
+	return func() error {
+		close(done)
+		return <-result
+	}
+}
+
+// killSession encerra a sessão sessionID no servidor por uma nova conexão.
+func killSession(pool sessionPool, sessionID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), killTimeout)
+	defer cancel()
+	conn, err := pool.session(ctx)
+	if err == nil {
+		err = conn.exec(ctx, KillStatement(sessionID))
+		_ = conn.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("KILL da sessão %d falhou: %w", sessionID, err)
+	}
+	return nil
+}
+
+// KillStatement monta o comando que encerra a sessão sessionID no servidor
+// (exige ALTER ANY CONNECTION).
+func KillStatement(sessionID int) string {
+	// KILL não aceita parâmetros; sessionID é inteiro, então não há risco de injeção
+	return "KILL " + strconv.Itoa(sessionID)
+}
+
+// sqlPool implementa sessionPool sobre um *sql.DB.
+type sqlPool struct{ db *sql.DB }
+
+func (p sqlPool) session(ctx context.Context) (session, error) {
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sqlSession{conn}, nil
+}
+
+func (p sqlPool) requestProgress(ctx context.Context, sessionID int) (*RequestProgress, error) {
+	return QueryRequestProgress(ctx, p.db, sessionID)
+}
+
+// sqlSession implementa session sobre uma conexão dedicada do pool.
+type sqlSession struct{ conn *sql.Conn }
+
+func (s sqlSession) spid(ctx context.Context) (int, error) {
+	var sessionID int
+	err := s.conn.QueryRowContext(ctx, "SELECT @@SPID").Scan(&sessionID)
+	return sessionID, err
+}
+
+func (s sqlSession) run(ctx context.Context, stmt string, onMessage func(string)) error {
+	return execWithMessages(ctx, s.conn, stmt, onMessage)
+}
+
+func (s sqlSession) exec(ctx context.Context, stmt string) error {
+	_, err := s.conn.ExecContext(ctx, stmt)
+	return err
+}
+
+func (s sqlSession) Close() error { return s.conn.Close() }
+
+// execWithMessages executa stmt entregando as mensagens informativas a onMessage.
+func execWithMessages(ctx context.Context, conn *sql.Conn, stmt string, onMessage func(string)) error {
+	retmsg := &sqlexp.ReturnMessage{}
+	rows, err := conn.QueryContext(ctx, stmt, retmsg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var execErr error
+	for active := true; active; {
+		switch m := retmsg.Message(ctx).(type) {
+		case sqlexp.MsgNotice:
+			onMessage(m.Message.String())
+		case sqlexp.MsgError:
+			execErr = errors.Join(execErr, m.Error)
+		case sqlexp.MsgNext:
+			for rows.Next() {
+				// BACKUP não retorna linhas; descarta qualquer resultado inesperado
+			}
+		case sqlexp.MsgNextResultSet:
+			active = rows.NextResultSet()
+		}
+	}
+	if execErr != nil {
+		return execErr
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// pollProgress consulta o progresso da sessão a cada intervalo até ctx ser cancelado.
+func pollProgress(ctx context.Context, pool sessionPool, sessionID int, tracker *ProgressTracker, opts ProgressOptions) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		req, err := pool.requestProgress(ctx, sessionID)
+		if err != nil {
+			if ctx.Err() == nil && opts.OnPollErr != nil {
+				opts.OnPollErr(err)
+			}
+			continue
+		}
+		if req == nil {
+			continue // O comando ainda não começou ou já terminou
+		}
+		p := tracker.update(func(p *Progress) {
+			p.Command = req.Command
+			p.PercentComplete = req.PercentComplete
+			p.EstimatedRemaining = req.EstimatedRemaining
+			p.Elapsed = req.Elapsed
+		})
+		if opts.OnProgress != nil {
+			opts.OnProgress(p)
+		}
+	}
+}
+
+// RequestProgress é uma linha de sys.dm_exec_requests.
+type RequestProgress struct {
+	Command            string
+	PercentComplete    float64
+	EstimatedRemaining time.Duration
+	Elapsed            time.Duration
+}
+
+// QueryRequestProgress consulta o andamento do comando da sessão sessionID.
+// Retorna nil se a sessão não tiver um comando em execução.
+func QueryRequestProgress(ctx context.Context, db *sql.DB, sessionID int) (*RequestProgress, error) {
+	const query = `SELECT command, CAST(percent_complete AS float),
+	CAST(estimated_completion_time AS bigint), CAST(total_elapsed_time AS bigint)
+FROM sys.dm_exec_requests
+WHERE session_id = @p1`
+
+	req := &RequestProgress{}
+	var remainingMs, elapsedMs int64
+	err := db.QueryRowContext(ctx, query, sessionID).Scan(&req.Command, &req.PercentComplete, &remainingMs, &elapsedMs)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("consultar sys.dm_exec_requests falhou: %w", err)
+	}
+	req.EstimatedRemaining = time.Duration(remainingMs) * time.Millisecond
+	req.Elapsed = time.Duration(elapsedMs) * time.Millisecond
+	return req, nil
+}
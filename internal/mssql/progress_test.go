@@ -0,0 +1,149 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatsPercent(t *testing.T) {
+	percent, ok := parseStatsPercent("10 percent processed.")
+	assert.True(t, ok)
+	assert.Equal(t, 10.0, percent)
+
+	percent, ok = parseStatsPercent("100 percent processed.")
+	assert.True(t, ok)
+	assert.Equal(t, 100.0, percent)
+
+	_, ok = parseStatsPercent("Processed 1234 pages for database 'SCM', file 'SCM' on file 1.")
+	assert.False(t, ok)
+}
+
+func TestProgressTracker(t *testing.T) {
+	tracker := &ProgressTracker{}
+	_, active := tracker.Snapshot()
+	assert.False(t, active)
+
+	tracker.start(57)
+	tracker.update(func(p *Progress) {
+		p.PercentComplete = 42.5
+		p.Command = "BACKUP DATABASE"
+	})
+
+	p, active := tracker.Snapshot()
+	assert.True(t, active)
+	assert.Equal(t, 57, p.SessionID)
+	assert.Equal(t, 42.5, p.PercentComplete)
+	assert.Equal(t, "BACKUP DATABASE", p.Command)
+	assert.False(t, p.UpdatedAt.IsZero())
+
+	tracker.finish()
+	p, active = tracker.Snapshot()
+	assert.False(t, active)
+	assert.Equal(t, 42.5, p.PercentComplete, "o último progresso continua disponível")
+}
+
+func TestKillStatement(t *testing.T) {
+	assert.Equal(t, "KILL 57", KillStatement(57))
+}
+
+// fakePool simula o servidor: cada sessão tem o seu @@SPID, e o BACKUP
+// ignora o cancelamento (attention) e só termina com um KILL da sessão.
+type fakePool struct {
+	mu       sync.Mutex
+	nextID   int
+	sessions map[int]*fakeSession
+	kills    []fakeKill
+}
+
+type fakeKill struct {
+	target, from int  // Sessão encerrada e sessão que enviou o KILL
+	running      bool // O BACKUP ainda estava em execução
+}
+
+type fakeSession struct {
+	pool    *fakePool
+	id      int
+	running bool
+	killed  chan struct{}
+}
+
+func (p *fakePool) session(ctx context.Context) (session, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextID++
+	s := &fakeSession{pool: p, id: 50 + p.nextID, killed: make(chan struct{})}
+	if p.sessions == nil {
+		p.sessions = map[int]*fakeSession{}
+	}
+	p.sessions[s.id] = s
+	return s, nil
+}
+
+func (p *fakePool) requestProgress(ctx context.Context, sessionID int) (*RequestProgress, error) {
+	return nil, nil
+}
+
+func (s *fakeSession) spid(ctx context.Context) (int, error) { return s.id, nil }
+
+func (s *fakeSession) run(ctx context.Context, stmt string, onMessage func(string)) error {
+	s.pool.mu.Lock()
+	s.running = true
+	s.pool.mu.Unlock()
+	<-s.killed
+	s.pool.mu.Lock()
+	s.running = false
+	s.pool.mu.Unlock()
+	return errors.New("sessão encerrada pelo KILL")
+}
+
+func (s *fakeSession) exec(ctx context.Context, stmt string) error {
+	var target int
+	if _, err := fmt.Sscanf(stmt, "KILL %d", &target); err != nil {
+		return err
+	}
+	s.pool.mu.Lock()
+	defer s.pool.mu.Unlock()
+	if target == s.id {
+		return errors.New("Cannot use KILL to kill your own process")
+	}
+	victim := s.pool.sessions[target]
+	s.pool.kills = append(s.pool.kills, fakeKill{target: target, from: s.id, running: victim.running})
+	close(victim.killed)
+	return nil
+}
+
+func (s *fakeSession) Close() error { return nil }
+
+func TestExecWithProgress_KillOnCancel(t *testing.T) {
+	pool := &fakePool{}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	var sessionID int
+	err := execWithProgress(ctx, pool, "BACKUP DATABASE [SCM] TO DISK = N'x.bak'", ProgressOptions{
+		Interval:  time.Hour,
+		OnSession: func(id int) { sessionID = id },
+	})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "KILL da sessão")
+
+	require.Len(t, pool.kills, 1)
+	kill := pool.kills[0]
+	assert.Equal(t, sessionID, kill.target)
+	assert.NotEqual(t, sessionID, kill.from, "o KILL sai por outra conexão")
+	assert.True(t, kill.running, "o KILL é enviado enquanto o BACKUP ainda está em execução")
+}
+
+func TestExecWithProgress_NoKillWithoutCancel(t *testing.T) {
+	pool := &fakePool{}
+	stop := killOnCancel(context.Background(), pool, 57)
+	require.NoError(t, stop())
+	assert.Empty(t, pool.kills)
+}
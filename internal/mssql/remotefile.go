@@ -0,0 +1,176 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+)
+
+// DefaultChunkSize é o tamanho padrão de cada bloco lido por RemoteFile.
+const DefaultChunkSize = 8 << 20
+
+// MaxRemoteFileSize é o maior arquivo que OPENROWSET(BULK ..., SINGLE_BLOB)
+// consegue ler: o conteúdo é um varbinary(max), limitado a 2 GiB - 1 byte.
+const MaxRemoteFileSize = 1<<31 - 1
+
+// maxRemoteChunks limita a quantidade de blocos de um arquivo. Cada bloco
+// relê o arquivo inteiro no servidor (SUBSTRING sobre o BulkColumn), então o
+// bloco é aumentado em arquivos grandes para que o custo não cresça com o
+// quadrado do tamanho.
+const maxRemoteChunks = 32
+
+// errLOBTooLarge é o erro do SQL Server ao ultrapassar o tamanho de um LOB.
+const errLOBTooLarge = 7119
+
+// ErrRemoteFileTooLarge indica um arquivo maior que MaxRemoteFileSize.
+var ErrRemoteFileTooLarge = errors.New("arquivo maior que o limite de 2 GiB da leitura pela conexão SQL (OPENROWSET SINGLE_BLOB); use -fetch-mode local")
+
+// blobSource lê o conteúdo do arquivo no servidor.
+type blobSource interface {
+	// length retorna o tamanho do arquivo.
+	length(ctx context.Context) (int64, error)
+	// chunk retorna os n bytes a partir de offset (base 0).
+	chunk(ctx context.Context, offset, n int64) ([]byte, error)
+}
+
+// RemoteFile lê um arquivo do sistema de arquivos do servidor SQL Server pela
+// própria conexão, em blocos, usando OPENROWSET(BULK ..., SINGLE_BLOB). Permite
+// buscar o .bak sem compartilhamento de rede entre o servidor e o dbbackup.
+// Arquivos maiores que MaxRemoteFileSize retornam ErrRemoteFileTooLarge. Como
+// cada bloco relê o arquivo inteiro no servidor, é indicado apenas para bancos
+// pequenos.
+//
+// O login precisa da permissão ADMINISTER BULK OPERATIONS (ou do papel bulkadmin)
+// e a conta de serviço do SQL Server precisa de leitura no arquivo.
+type RemoteFile struct {
+	ctx       context.Context
+	src       blobSource
+	path      string
+	chunkSize int64
+
+	size   int64 // Tamanho do arquivo; -1 até ser consultado
+	offset int64 // Próximo byte a ser lido (base 0)
+	buf    []byte
+	eof    bool
+}
+
+// OpenRemoteFile prepara a leitura do arquivo path (caminho no servidor).
+func OpenRemoteFile(ctx context.Context, db *sql.DB, path string, chunkSize int64) *RemoteFile {
+	return newRemoteFile(ctx, &sqlBlob{db: db, path: path}, path, chunkSize)
+}
+
+func newRemoteFile(ctx context.Context, src blobSource, path string, chunkSize int64) *RemoteFile {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return &RemoteFile{ctx: ctx, src: src, path: path, chunkSize: chunkSize, size: -1}
+}
+
+// Size consulta o tamanho do arquivo no servidor. Exige uma leitura completa do
+// arquivo pelo SQL Server; o resultado é guardado e usado também por Read.
+func (rf *RemoteFile) Size() (int64, error) {
+	if rf.size >= 0 {
+		return rf.size, nil
+	}
+	size, err := rf.src.length(rf.ctx)
+	if err != nil {
+		return 0, fmt.Errorf("consultar tamanho de %s no servidor falhou: %w", rf.path, err)
+	}
+	if size > MaxRemoteFileSize {
+		return 0, fmt.Errorf("%s (%d bytes): %w", rf.path, size, ErrRemoteFileTooLarge)
+	}
+	rf.size = size
+	if least := (size + maxRemoteChunks - 1) / maxRemoteChunks; rf.chunkSize < least {
+		rf.chunkSize = least
+	}
+	return size, nil
+}
+
+// Read implementa io.Reader buscando o próximo bloco do servidor quando o
+// buffer local se esgota.
+func (rf *RemoteFile) Read(p []byte) (int, error) {
+	if len(rf.buf) == 0 {
+		if rf.eof {
+			return 0, io.EOF
+		}
+		if err := rf.fetch(); err != nil {
+			return 0, err
+		}
+		if len(rf.buf) == 0 {
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, rf.buf)
+	rf.buf = rf.buf[n:]
+	return n, nil
+}
+
+// fetch lê o bloco que começa em rf.offset. O tamanho é consultado antes do
+// primeiro bloco, para recusar arquivos acima do limite sem lê-los.
+func (rf *RemoteFile) fetch() error {
+	if _, err := rf.Size(); err != nil {
+		return err
+	}
+	n := min(rf.chunkSize, rf.size-rf.offset)
+	if n <= 0 {
+		rf.eof = true
+		return nil
+	}
+	chunk, err := rf.src.chunk(rf.ctx, rf.offset, n)
+	if err != nil {
+		return fmt.Errorf("ler bloco de %s no offset %d falhou: %w", rf.path, rf.offset, err)
+	}
+	if int64(len(chunk)) != n {
+		return fmt.Errorf("bloco de %s no offset %d com %d bytes, esperado %d (arquivo alterado durante a leitura?)", rf.path, rf.offset, len(chunk), n)
+	}
+	rf.offset += n
+	rf.buf = chunk
+	rf.eof = rf.offset >= rf.size
+	return nil
+}
+
+// Close existe para que RemoteFile satisfaça io.ReadCloser; não há recursos a liberar.
+func (rf *RemoteFile) Close() error {
+	rf.buf = nil
+	rf.eof = true
+	return nil
+}
+
+// sqlBlob lê o arquivo com OPENROWSET(BULK ..., SINGLE_BLOB).
+type sqlBlob struct {
+	db   *sql.DB
+	path string
+}
+
+func (b *sqlBlob) length(ctx context.Context) (int64, error) {
+	var size int64
+	query := "SELECT CAST(DATALENGTH(BulkColumn) AS bigint) FROM " + BulkSource(b.path, "f")
+	if err := b.db.QueryRowContext(ctx, query).Scan(&size); err != nil {
+		return 0, lobError(err)
+	}
+	return size, nil
+}
+
+func (b *sqlBlob) chunk(ctx context.Context, offset, n int64) ([]byte, error) {
+	var chunk []byte
+	// SUBSTRING usa posições com base 1
+	query := "SELECT SUBSTRING(BulkColumn, @p1, @p2) FROM " + BulkSource(b.path, "f")
+	if err := b.db.QueryRowContext(ctx, query, offset+1, n).Scan(&chunk); err != nil {
+		return nil, lobError(err)
+	}
+	return chunk, nil
+}
+
+// lobError converte o erro do servidor para um arquivo acima de 2 GiB em
+// ErrRemoteFileTooLarge.
+func lobError(err error) error {
+	var sqlErr mssqldb.Error
+	if errors.As(err, &sqlErr) && sqlErr.Number == errLOBTooLarge {
+		return fmt.Errorf("%w: %v", ErrRemoteFileTooLarge, err)
+	}
+	return err
+}
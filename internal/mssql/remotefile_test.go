@@ -0,0 +1,119 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBlob simula o arquivo no servidor e registra os blocos pedidos.
+type fakeBlob struct {
+	data      []byte
+	size      int64 // Tamanho informado; padrão: len(data)
+	lengthErr error
+	lengths   int
+	chunks    [][2]int64 // offset e tamanho de cada bloco
+}
+
+func (f *fakeBlob) length(ctx context.Context) (int64, error) {
+	f.lengths++
+	if f.lengthErr != nil {
+		return 0, f.lengthErr
+	}
+	if f.size > 0 {
+		return f.size, nil
+	}
+	return int64(len(f.data)), nil
+}
+
+func (f *fakeBlob) chunk(ctx context.Context, offset, n int64) ([]byte, error) {
+	f.chunks = append(f.chunks, [2]int64{offset, n})
+	end := min(offset+n, int64(len(f.data)))
+	return f.data[offset:end], nil
+}
+
+func testData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func TestRemoteFile_Chunks(t *testing.T) {
+	for _, size := range []int{0, 1, 9, 10, 25} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			src := &fakeBlob{data: testData(size)}
+			rf := newRemoteFile(context.Background(), src, "C:\\Backups\\SCM.bak", 10)
+
+			got, err := io.ReadAll(rf)
+			require.NoError(t, err)
+			assert.Equal(t, src.data, got)
+			assert.Equal(t, 1, src.lengths, "tamanho consultado uma vez")
+
+			// Blocos contíguos, sem consulta além do fim do arquivo
+			var offset int64
+			for _, c := range src.chunks {
+				assert.Equal(t, offset, c[0])
+				assert.Equal(t, min(10, int64(size)-offset), c[1])
+				offset += c[1]
+			}
+			assert.Equal(t, int64(size), offset)
+			assert.Len(t, src.chunks, (size+9)/10)
+		})
+	}
+}
+
+func TestRemoteFile_SizeCached(t *testing.T) {
+	src := &fakeBlob{data: testData(25)}
+	rf := newRemoteFile(context.Background(), src, "SCM.bak", 10)
+
+	size, err := rf.Size()
+	require.NoError(t, err)
+	assert.Equal(t, int64(25), size)
+	_, err = io.ReadAll(rf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, src.lengths)
+}
+
+func TestRemoteFile_ChunkSizeGrows(t *testing.T) {
+	src := &fakeBlob{data: testData(maxRemoteChunks * 100)}
+	rf := newRemoteFile(context.Background(), src, "SCM.bak", 10)
+
+	got, err := io.ReadAll(rf)
+	require.NoError(t, err)
+	assert.Equal(t, src.data, got)
+	assert.Len(t, src.chunks, maxRemoteChunks, "bloco aumentado para limitar as releituras do arquivo")
+}
+
+func TestRemoteFile_TooLarge(t *testing.T) {
+	src := &fakeBlob{size: MaxRemoteFileSize + 1}
+	rf := newRemoteFile(context.Background(), src, "SCM.bak", 0)
+
+	_, err := rf.Read(make([]byte, 10))
+	assert.ErrorIs(t, err, ErrRemoteFileTooLarge)
+	assert.Empty(t, src.chunks, "nenhum bloco lido")
+
+	_, err = rf.Size()
+	assert.ErrorIs(t, err, ErrRemoteFileTooLarge)
+
+	// Erro do servidor ao ler o arquivo como varbinary(max)
+	src = &fakeBlob{lengthErr: lobError(mssqldb.Error{Number: errLOBTooLarge, Message: "Attempting to grow LOB beyond maximum allowed size"})}
+	_, err = newRemoteFile(context.Background(), src, "SCM.bak", 0).Size()
+	assert.ErrorIs(t, err, ErrRemoteFileTooLarge)
+
+	other := errors.New("permissão negada")
+	assert.Equal(t, other, lobError(other))
+}
+
+func TestRemoteFile_ShortChunk(t *testing.T) {
+	src := &fakeBlob{data: testData(15), size: 25}
+	_, err := io.ReadAll(newRemoteFile(context.Background(), src, "SCM.bak", 10))
+	assert.ErrorContains(t, err, "esperado 10")
+}
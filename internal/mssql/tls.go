@@ -0,0 +1,225 @@
+package mssql
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	mssqldb "github.com/microsoft/go-mssqldb"
+	"github.com/microsoft/go-mssqldb/msdsn"
+)
+
+// Modos de criptografia aceitos pelo flag -encrypt.
+const (
+	EncryptStrict    = "strict"    // TDS 8.0: TLS antes de qualquer troca, certificado sempre validado
+	EncryptMandatory = "mandatory" // Todo o tráfego criptografado
+	EncryptOptional  = "optional"  // Apenas o login é criptografado, se o servidor suportar
+	EncryptDisable   = "disable"   // Sem TLS
+)
+
+// EncryptModes lista os modos de criptografia suportados.
+var EncryptModes = []string{EncryptStrict, EncryptMandatory, EncryptOptional, EncryptDisable}
+
+// validateTLS verifica a combinação das opções de TLS.
+func (o ConnOptions) validateTLS() error {
+	switch o.Encrypt {
+	case "", EncryptStrict, EncryptMandatory, EncryptOptional, EncryptDisable:
+	default:
+		return fmt.Errorf("modo de criptografia inválido '%s' (use %s)", o.Encrypt, strings.Join(EncryptModes, ", "))
+	}
+	if o.Encrypt == EncryptDisable && (o.TLSCAFile != "" || o.TLSFingerprint != "" || o.TrustServerCert) {
+		return fmt.Errorf("opções de certificado não se aplicam com criptografia desativada")
+	}
+	if o.Encrypt == EncryptStrict && o.TrustServerCert {
+		return fmt.Errorf("o modo strict sempre valida o certificado do servidor; use -tls-ca-file ou -tls-fingerprint")
+	}
+	if o.TLSFingerprint != "" {
+		if _, err := ParseFingerprint(o.TLSFingerprint); err != nil {
+			return err
+		}
+		if strings.HasPrefix(o.Auth, "azure-") {
+			return fmt.Errorf("fixação de certificado não é suportada com autenticação %s", o.Auth)
+		}
+	}
+	return nil
+}
+
+// setTLSParams adiciona as opções de TLS à connection string.
+func (o ConnOptions) setTLSParams(params url.Values) {
+	encrypt := o.Encrypt
+	if encrypt == "" {
+		encrypt = EncryptMandatory
+	}
+	params.Set("encrypt", encrypt)
+	if encrypt == EncryptDisable {
+		return
+	}
+	// Com fixação o certificado é validado pela impressão digital, não pela cadeia (ver Open)
+	params.Set("TrustServerCertificate", strconv.FormatBool(o.TrustServerCert))
+	if o.TLSCAFile != "" {
+		params.Set("certificate", o.TLSCAFile)
+	}
+	if o.TLSHostname != "" {
+		params.Set("hostNameInCertificate", o.TLSHostname)
+	}
+}
+
+// ErrUntrustedCertificate indica que a conexão falhou na validação do
+// certificado do servidor, em geral o certificado autoassinado que o SQL
+// Server gera na instalação.
+var ErrUntrustedCertificate = errors.New("certificado do servidor não validado; se o servidor usa o certificado autoassinado padrão do SQL Server, informe -tls-fingerprint (recomendado), -tls-ca-file ou, para manter o comportamento das versões anteriores, -trust-server-cert")
+
+// ExplainTLSError acrescenta ErrUntrustedCertificate, com as opções para
+// aceitar o certificado, a um erro de conexão causado pela validação do
+// certificado do servidor. Outros erros são retornados sem alteração.
+func ExplainTLSError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+	)
+	if errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid) ||
+		strings.Contains(err.Error(), "x509: ") { // O driver nem sempre preserva o erro original
+		return fmt.Errorf("%w: %w", ErrUntrustedCertificate, err)
+	}
+	return err
+}
+
+// ParseFingerprint converte uma impressão digital SHA-256 em hexadecimal,
+// com ou sem separadores ':' (formato do openssl x509 -fingerprint -sha256).
+func ParseFingerprint(s string) ([]byte, error) {
+	clean := strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(s))
+	fp, err := hex.DecodeString(clean)
+	if err != nil || len(fp) != sha256.Size {
+		return nil, fmt.Errorf("impressão digital '%s' inválida: informe o SHA-256 do certificado em hexadecimal", s)
+	}
+	return fp, nil
+}
+
+// Fingerprint retorna o SHA-256 do certificado DER em hexadecimal separado por ':'.
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// TLSState guarda o estado TLS negociado na conexão mais recente.
+type TLSState struct {
+	mu    sync.Mutex
+	state *tls.ConnectionState
+}
+
+// Last retorna o estado TLS da última conexão estabelecida, se houver.
+func (s *TLSState) Last() (tls.ConnectionState, bool) {
+	if s == nil {
+		return tls.ConnectionState{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == nil {
+		return tls.ConnectionState{}, false
+	}
+	return *s.state, true
+}
+
+func (s *TLSState) record(cs tls.ConnectionState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = &cs
+}
+
+// verifyPinned retorna uma verificação que exige que o certificado do servidor
+// tenha a impressão digital informada.
+func verifyPinned(fingerprint []byte) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("servidor não apresentou certificado")
+		}
+		sum := sha256.Sum256(cs.PeerCertificates[0].Raw)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), hex.EncodeToString(fingerprint)) {
+			return fmt.Errorf("certificado do servidor (%s) não corresponde à impressão digital fixada", Fingerprint(cs.PeerCertificates[0].Raw))
+		}
+		return nil
+	}
+}
+
+// Open prepara o pool de conexões com o SQL Server. Para os modos que não usam
+// Azure AD, o estado TLS de cada conexão é registrado no TLSState retornado e a
+// impressão digital do certificado é verificada quando configurada.
+func Open(o ConnOptions) (*sql.DB, *TLSState, error) {
+	driverName, connString, err := ConnString(o)
+	if err != nil {
+		return nil, nil, err
+	}
+	if driverName != "sqlserver" {
+		// O driver azuresql monta a própria configuração TLS
+		db, err := sql.Open(driverName, connString)
+		return db, nil, err
+	}
+
+	dsn, err := msdsn.Parse(connString)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connection string inválida: %w", err)
+	}
+
+	state := &TLSState{}
+	if dsn.TLSConfig != nil {
+		var pinned func(tls.ConnectionState) error
+		if o.TLSFingerprint != "" {
+			fingerprint, err := ParseFingerprint(o.TLSFingerprint)
+			if err != nil {
+				return nil, nil, err
+			}
+			pinned = verifyPinned(fingerprint)
+			// A impressão digital substitui a validação da cadeia (certificados autoassinados)
+			dsn.TLSConfig.InsecureSkipVerify = true
+		}
+		dsn.TLSConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			if pinned != nil {
+				if err := pinned(cs); err != nil {
+					return err
+				}
+			}
+			state.record(cs)
+			return nil
+		}
+	}
+	return sql.OpenDB(mssqldb.NewConnectorConfig(dsn)), state, nil
+}
+
+// ConnSecurity descreve a conexão atual segundo sys.dm_exec_connections.
+type ConnSecurity struct {
+	Encrypted  bool
+	AuthScheme string // SQL, NTLM, KERBEROS
+	Transport  string // TCP, Shared memory, Named pipe
+}
+
+// QueryConnSecurity consulta se a sessão atual está criptografada. Exige a
+// permissão VIEW SERVER STATE.
+func QueryConnSecurity(ctx context.Context, db *sql.DB) (*ConnSecurity, error) {
+	sec := &ConnSecurity{}
+	var encrypt string
+	err := db.QueryRowContext(ctx,
+		"SELECT encrypt_option, auth_scheme, net_transport FROM sys.dm_exec_connections WHERE session_id = @@SPID",
+	).Scan(&encrypt, &sec.AuthScheme, &sec.Transport)
+	if err != nil {
+		return nil, fmt.Errorf("consultar sys.dm_exec_connections falhou: %w", err)
+	}
+	sec.Encrypted = strings.EqualFold(encrypt, "TRUE")
+	return sec, nil
+}
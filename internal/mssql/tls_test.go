@@ -0,0 +1,124 @@
+package mssql
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnString_TLS(t *testing.T) {
+	_, connString, err := ConnString(ConnOptions{
+		Server: "sqlprod01", Database: "SCM", User: "backup", Password: "x", Auth: AuthSQL,
+		Encrypt: EncryptStrict, TLSCAFile: "/etc/ssl/empresa-ca.pem", TLSHostname: "sqlprod01.empresa.local",
+	})
+	require.NoError(t, err)
+
+	q := parseConnString(t, connString).Query()
+	assert.Equal(t, "strict", q.Get("encrypt"))
+	assert.Equal(t, "false", q.Get("TrustServerCertificate"))
+	assert.Equal(t, "/etc/ssl/empresa-ca.pem", q.Get("certificate"))
+	assert.Equal(t, "sqlprod01.empresa.local", q.Get("hostNameInCertificate"))
+}
+
+func TestConnString_EncryptDefault(t *testing.T) {
+	_, connString, err := ConnString(ConnOptions{Server: "sqlprod01", Database: "SCM", User: "backup", Password: "x", Auth: AuthSQL})
+	require.NoError(t, err)
+
+	q := parseConnString(t, connString).Query()
+	assert.Equal(t, EncryptMandatory, q.Get("encrypt"))
+	assert.Equal(t, "false", q.Get("TrustServerCertificate"))
+}
+
+func TestConnOptions_ValidateTLS(t *testing.T) {
+	base := ConnOptions{Auth: AuthSQL, User: "backup", Password: "x"}
+	fingerprint := strings.Repeat("AB:", 31) + "AB"
+
+	tests := []struct {
+		name    string
+		modify  func(o *ConnOptions)
+		wantErr bool
+	}{
+		{name: "padrão", modify: func(o *ConnOptions) {}},
+		{name: "fixação", modify: func(o *ConnOptions) { o.TLSFingerprint = fingerprint }},
+		{name: "modo inválido", modify: func(o *ConnOptions) { o.Encrypt = "always" }, wantErr: true},
+		{name: "impressão digital curta", modify: func(o *ConnOptions) { o.TLSFingerprint = "AB:CD" }, wantErr: true},
+		{name: "strict com trust", modify: func(o *ConnOptions) { o.Encrypt = EncryptStrict; o.TrustServerCert = true }, wantErr: true},
+		{name: "disable com CA", modify: func(o *ConnOptions) { o.Encrypt = EncryptDisable; o.TLSCAFile = "ca.pem" }, wantErr: true},
+		{name: "fixação com azure", modify: func(o *ConnOptions) { o.Auth = AuthAzureMSI; o.TLSFingerprint = fingerprint }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := base
+			tt.modify(&o)
+			err := o.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyPinned(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	cert := srv.Certificate()
+	cs := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+	fingerprint, err := ParseFingerprint(Fingerprint(cert.Raw))
+	require.NoError(t, err)
+	assert.NoError(t, verifyPinned(fingerprint)(cs))
+
+	other, err := ParseFingerprint(strings.Repeat("00", 32))
+	require.NoError(t, err)
+	assert.ErrorContains(t, verifyPinned(other)(cs), "impressão digital")
+	assert.Error(t, verifyPinned(fingerprint)(tls.ConnectionState{}))
+}
+
+func TestParseFingerprint(t *testing.T) {
+	fp, err := ParseFingerprint(strings.Repeat("ab", 32))
+	require.NoError(t, err)
+	assert.Len(t, fp, 32)
+
+	_, err = ParseFingerprint("zz")
+	assert.Error(t, err)
+}
+
+func TestOpen_RecordsTLSState(t *testing.T) {
+	db, state, err := Open(ConnOptions{
+		Server: "sqlprod01", Database: "SCM", User: "backup", Password: "x", Auth: AuthSQL,
+		TLSFingerprint: strings.Repeat("ab", 32),
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NotNil(t, state)
+	_, ok := state.Last()
+	assert.False(t, ok, "nenhuma conexão foi estabelecida ainda")
+}
+
+func TestExplainTLSError(t *testing.T) {
+	assert.NoError(t, ExplainTLSError(nil))
+
+	other := errors.New("login failed for user 'backup'")
+	assert.Equal(t, other, ExplainTLSError(other))
+
+	wrapped := fmt.Errorf("TLS Handshake failed: %w", x509.UnknownAuthorityError{})
+	err := ExplainTLSError(wrapped)
+	assert.ErrorIs(t, err, ErrUntrustedCertificate)
+	assert.ErrorIs(t, err, wrapped)
+	assert.ErrorContains(t, err, "-trust-server-cert")
+
+	// Erro convertido em texto pelo driver
+	flat := errors.New("TLS Handshake failed: tls: failed to verify certificate: x509: certificate signed by unknown authority")
+	assert.ErrorIs(t, ExplainTLSError(flat), ErrUntrustedCertificate)
+}
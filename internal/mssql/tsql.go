@@ -0,0 +1,193 @@
+package mssql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tipos de backup aceitos por BackupStatement. Os nomes coincidem com os
+// retornados por BackupTypeName.
+const (
+	BackupFull         = "full"
+	BackupDifferential = "differential"
+	BackupLog          = "log"
+)
+
+// BackupTypes lista os tipos de backup suportados.
+var BackupTypes = []string{BackupFull, BackupDifferential, BackupLog}
+
+// maxIdentifierLength é o tamanho máximo de um identificador (sysname) no SQL Server.
+const maxIdentifierLength = 128
+
+// QuoteIdentifier delimita name como identificador T-SQL ([nome]), duplicando
+// os colchetes de fechamento, equivalente a QUOTENAME(name).
+func QuoteIdentifier(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+// QuoteString retorna s como literal Unicode T-SQL (N'...'), duplicando os apóstrofos.
+func QuoteString(s string) string {
+	return "N'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// validateIdentifier rejeita nomes que o SQL Server não aceitaria como sysname.
+func validateIdentifier(name string) error {
+	if name == "" {
+		return fmt.Errorf("nome do banco de dados não pode ser vazio")
+	}
+	if len([]rune(name)) > maxIdentifierLength {
+		return fmt.Errorf("nome do banco de dados excede %d caracteres", maxIdentifierLength)
+	}
+	if strings.ContainsRune(name, 0) {
+		return fmt.Errorf("nome do banco de dados contém caractere nulo")
+	}
+	return nil
+}
+
+// validateDisks exige ao menos um dispositivo e rejeita caminhos com caractere nulo.
+func validateDisks(disks []string) error {
+	if len(disks) == 0 {
+		return fmt.Errorf("nenhum dispositivo de backup informado")
+	}
+	for _, disk := range disks {
+		if disk == "" || strings.ContainsRune(disk, 0) {
+			return fmt.Errorf("caminho de dispositivo de backup inválido %q", disk)
+		}
+	}
+	return nil
+}
+
+// diskList monta a lista "DISK = N'...', DISK = N'...'".
+func diskList(disks []string) string {
+	parts := make([]string, len(disks))
+	for i, disk := range disks {
+		parts[i] = "DISK = " + QuoteString(disk)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// BackupOptions descreve um comando BACKUP DATABASE/LOG.
+type BackupOptions struct {
+	Database        string
+	Type            string   // full (padrão), differential ou log
+	Disks           []string // Um caminho por stripe, no sistema de arquivos do servidor
+	Compression     bool
+	MaxTransferSize int // 0 usa o padrão do servidor
+	BufferCount     int // 0 usa o padrão do servidor
+	Stats           int // STATS = n: mensagem de progresso a cada n% (0 desativa)
+}
+
+// BackupStatement monta o comando BACKUP com nomes e caminhos devidamente escapados.
+func BackupStatement(o BackupOptions) (string, error) {
+	if err := validateIdentifier(o.Database); err != nil {
+		return "", err
+	}
+	if err := validateDisks(o.Disks); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	var options []string
+	switch o.Type {
+	case "", BackupFull:
+		b.WriteString("BACKUP DATABASE ")
+	case BackupDifferential:
+		b.WriteString("BACKUP DATABASE ")
+		options = append(options, "DIFFERENTIAL")
+	case BackupLog:
+		b.WriteString("BACKUP LOG ")
+	default:
+		return "", fmt.Errorf("tipo de backup inválido '%s' (use %s, %s ou %s)", o.Type, BackupFull, BackupDifferential, BackupLog)
+	}
+	b.WriteString(QuoteIdentifier(o.Database))
+	b.WriteString(" TO ")
+	b.WriteString(diskList(o.Disks))
+
+	if o.Compression {
+		options = append(options, "COMPRESSION")
+	}
+	if o.MaxTransferSize > 0 {
+		options = append(options, fmt.Sprintf("MAXTRANSFERSIZE = %d", o.MaxTransferSize))
+	}
+	if o.BufferCount > 0 {
+		options = append(options, fmt.Sprintf("BUFFERCOUNT = %d", o.BufferCount))
+	}
+	if o.Stats > 0 {
+		options = append(options, fmt.Sprintf("STATS = %d", o.Stats))
+	}
+	if len(options) > 0 {
+		b.WriteString(" WITH ")
+		b.WriteString(strings.Join(options, ", "))
+	}
+	return b.String(), nil
+}
+
+// VerifyStatement monta RESTORE VERIFYONLY para os dispositivos de um backup.
+func VerifyStatement(disks []string) (string, error) {
+	if err := validateDisks(disks); err != nil {
+		return "", err
+	}
+	return "RESTORE VERIFYONLY FROM " + diskList(disks), nil
+}
+
+// DeleteBackupFileStatement monta a chamada a xp_delete_file que remove um
+// arquivo de backup no servidor. O SQL Server só apaga arquivos cujo cabeçalho
+// seja de backup, o que evita remover outros arquivos por engano. Exige
+// sysadmin.
+func DeleteBackupFileStatement(path string) (string, error) {
+	if err := validateDisks([]string{path}); err != nil {
+		return "", err
+	}
+	// Tipo 0 = arquivo de backup
+	return "EXECUTE master.dbo.xp_delete_file 0, " + QuoteString(path), nil
+}
+
+// FileMove relocaliza um arquivo lógico do backup durante o restore (WITH MOVE).
+type FileMove struct {
+	LogicalName  string
+	PhysicalPath string
+}
+
+// RestoreOptions descreve um comando RESTORE DATABASE.
+type RestoreOptions struct {
+	Database   string
+	Disks      []string
+	Moves      []FileMove
+	Replace    bool
+	NoRecovery bool
+}
+
+// RestoreStatement monta o comando RESTORE DATABASE com nomes e caminhos devidamente escapados.
+func RestoreStatement(o RestoreOptions) (string, error) {
+	if err := validateIdentifier(o.Database); err != nil {
+		return "", err
+	}
+	if err := validateDisks(o.Disks); err != nil {
+		return "", err
+	}
+
+	stmt := "RESTORE DATABASE " + QuoteIdentifier(o.Database) + " FROM " + diskList(o.Disks)
+
+	var options []string
+	for _, move := range o.Moves {
+		if move.LogicalName == "" || move.PhysicalPath == "" {
+			return "", fmt.Errorf("MOVE exige nome lógico e caminho físico")
+		}
+		options = append(options, "MOVE "+QuoteString(move.LogicalName)+" TO "+QuoteString(move.PhysicalPath))
+	}
+	if o.Replace {
+		options = append(options, "REPLACE")
+	}
+	if o.NoRecovery {
+		options = append(options, "NORECOVERY")
+	}
+	if len(options) > 0 {
+		stmt += " WITH " + strings.Join(options, ", ")
+	}
+	return stmt, nil
+}
+
+// BulkSource monta a cláusula OPENROWSET(BULK ...) usada para ler um arquivo do servidor.
+func BulkSource(path, alias string) string {
+	return "OPENROWSET(BULK " + QuoteString(path) + ", SINGLE_BLOB) AS " + QuoteIdentifier(alias)
+}
@@ -0,0 +1,136 @@
+package mssql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	assert.Equal(t, "[SCM]", QuoteIdentifier("SCM"))
+	assert.Equal(t, "[a]]b]", QuoteIdentifier("a]b"))
+	assert.Equal(t, "[x]]; DROP DATABASE [master]]]", QuoteIdentifier("x]; DROP DATABASE [master]"))
+}
+
+func TestQuoteString(t *testing.T) {
+	assert.Equal(t, "N'C:\\Backups\\SCM.bak'", QuoteString(`C:\Backups\SCM.bak`))
+	assert.Equal(t, "N'O''Brien'", QuoteString("O'Brien"))
+	assert.Equal(t, "N''''", QuoteString("'"))
+}
+
+func TestBackupStatement(t *testing.T) {
+	stmt, err := BackupStatement(BackupOptions{
+		Database:        "SCM",
+		Disks:           []string{`C:\Backups\SCM_1of2.bak`, `C:\Backups\SCM_2of2.bak`},
+		Compression:     true,
+		MaxTransferSize: 4194304,
+		BufferCount:     64,
+		Stats:           10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `BACKUP DATABASE [SCM] TO DISK = N'C:\Backups\SCM_1of2.bak', DISK = N'C:\Backups\SCM_2of2.bak' WITH COMPRESSION, MAXTRANSFERSIZE = 4194304, BUFFERCOUNT = 64, STATS = 10`, stmt)
+}
+
+func TestBackupStatement_Types(t *testing.T) {
+	stmt, err := BackupStatement(BackupOptions{Database: "SCM", Type: BackupDifferential, Disks: []string{"d.bak"}})
+	require.NoError(t, err)
+	assert.Equal(t, "BACKUP DATABASE [SCM] TO DISK = N'd.bak' WITH DIFFERENTIAL", stmt)
+
+	stmt, err = BackupStatement(BackupOptions{Database: "SCM", Type: BackupLog, Disks: []string{"l.trn"}})
+	require.NoError(t, err)
+	assert.Equal(t, "BACKUP LOG [SCM] TO DISK = N'l.trn'", stmt)
+
+	_, err = BackupStatement(BackupOptions{Database: "SCM", Type: "copy", Disks: []string{"d.bak"}})
+	assert.Error(t, err)
+}
+
+func TestBackupStatement_HostileNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		database string
+		disk     string
+		want     string
+	}{
+		{
+			name:     "colchete no nome",
+			database: "SCM]; DROP TABLE x; --",
+			disk:     `C:\Backups\a.bak`,
+			want:     `BACKUP DATABASE [SCM]]; DROP TABLE x; --] TO DISK = N'C:\Backups\a.bak'`,
+		},
+		{
+			name:     "apóstrofo no caminho",
+			database: "SCM",
+			disk:     `C:\Backups\x'; EXEC xp_cmdshell 'dir'; --.bak`,
+			want:     `BACKUP DATABASE [SCM] TO DISK = N'C:\Backups\x''; EXEC xp_cmdshell ''dir''; --.bak'`,
+		},
+		{
+			name:     "unicode",
+			database: "Saúde Município",
+			disk:     `D:\Cópias\Saúde.bak`,
+			want:     `BACKUP DATABASE [Saúde Município] TO DISK = N'D:\Cópias\Saúde.bak'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := BackupStatement(BackupOptions{Database: tt.database, Disks: []string{tt.disk}})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, stmt)
+		})
+	}
+}
+
+func TestBackupStatement_Invalid(t *testing.T) {
+	_, err := BackupStatement(BackupOptions{Database: "", Disks: []string{"a.bak"}})
+	assert.Error(t, err)
+
+	_, err = BackupStatement(BackupOptions{Database: strings.Repeat("a", 129), Disks: []string{"a.bak"}})
+	assert.Error(t, err)
+
+	_, err = BackupStatement(BackupOptions{Database: "a\x00b", Disks: []string{"a.bak"}})
+	assert.Error(t, err)
+
+	_, err = BackupStatement(BackupOptions{Database: "SCM"})
+	assert.Error(t, err)
+
+	_, err = BackupStatement(BackupOptions{Database: "SCM", Disks: []string{"a\x00.bak"}})
+	assert.Error(t, err)
+}
+
+func TestVerifyStatement(t *testing.T) {
+	stmt, err := VerifyStatement([]string{`C:\Backups\O'Neil.bak`})
+	require.NoError(t, err)
+	assert.Equal(t, `RESTORE VERIFYONLY FROM DISK = N'C:\Backups\O''Neil.bak'`, stmt)
+}
+
+func TestDeleteBackupFileStatement(t *testing.T) {
+	stmt, err := DeleteBackupFileStatement(`C:\Backups\O'Neil.bak`)
+	require.NoError(t, err)
+	assert.Equal(t, `EXECUTE master.dbo.xp_delete_file 0, N'C:\Backups\O''Neil.bak'`, stmt)
+
+	_, err = DeleteBackupFileStatement("")
+	assert.Error(t, err)
+}
+
+func TestRestoreStatement(t *testing.T) {
+	stmt, err := RestoreStatement(RestoreOptions{
+		Database: "SCM_teste]",
+		Disks:    []string{`C:\Backups\SCM.bak`},
+		Moves: []FileMove{
+			{LogicalName: "SCM", PhysicalPath: `D:\Data\SCM_teste.mdf`},
+			{LogicalName: "SCM_log", PhysicalPath: `D:\Log\it's.ldf`},
+		},
+		Replace: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `RESTORE DATABASE [SCM_teste]]] FROM DISK = N'C:\Backups\SCM.bak' WITH MOVE N'SCM' TO N'D:\Data\SCM_teste.mdf', MOVE N'SCM_log' TO N'D:\Log\it''s.ldf', REPLACE`, stmt)
+
+	_, err = RestoreStatement(RestoreOptions{Database: "SCM", Disks: []string{"a.bak"}, Moves: []FileMove{{LogicalName: "SCM"}}})
+	assert.Error(t, err)
+}
+
+func TestBulkSource(t *testing.T) {
+	assert.Equal(t, `OPENROWSET(BULK N'C:\B\x''y.bak', SINGLE_BLOB) AS [f]`, BulkSource(`C:\B\x'y.bak`, "f"))
+}
@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+)
+
+// Coordinates é a posição do log binário no instante do backup.
+type Coordinates struct {
+	File     string
+	Position int64
+	GTID     string // Conjunto de GTIDs (MySQL) ou posição GTID (MariaDB), se houver
+}
+
+var (
+	// -- CHANGE MASTER TO MASTER_LOG_FILE='mysql-bin.000003', MASTER_LOG_POS=154;
+	// -- CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='binlog.000002', SOURCE_LOG_POS=157;
+	dumpBinlogPattern = regexp.MustCompile(`(?:MASTER|SOURCE)_LOG_FILE='([^']+)',\s*(?:MASTER|SOURCE)_LOG_POS=(\d+)`)
+	// SET @@GLOBAL.GTID_PURGED=/*!80000 '+'*/ 'uuid:1-5';  (MySQL)
+	// -- SET GLOBAL gtid_slave_pos='0-1-2';                (MariaDB com --gtid)
+	dumpGTIDPattern = regexp.MustCompile(`(?i)(?:GTID_PURGED=(?:/\*!\d+ '\+'\*/ )?|gtid_slave_pos=)'([^']*)'`)
+	// [00] ... MySQL binlog position: filename 'mariadb-bin.000002', position '342', GTID of the last change '0-1-3'
+	mariabackupBinlogPattern = regexp.MustCompile(`binlog position: filename '([^']+)', position '?(\d+)'?(?:, GTID of the last change '([^']*)')?`)
+)
+
+// Tamanhos do início e do fim do dump guardados por DumpInspector.
+const (
+	dumpHeadSize = 64 << 10
+	dumpTailSize = 512
+)
+
+// dumpCompleted é o comentário gravado pelo mysqldump ao concluir o dump.
+var dumpCompleted = []byte("-- Dump completed")
+
+// DumpInspector recebe a saída do mysqldump enquanto ela é comprimida e guarda
+// o início (onde ficam as coordenadas do log binário) e o fim (onde fica a
+// marca de conclusão), sem reter o dump.
+type DumpInspector struct {
+	head []byte
+	tail []byte
+}
+
+func (d *DumpInspector) Write(p []byte) (int, error) {
+	if room := dumpHeadSize - len(d.head); room > 0 {
+		d.head = append(d.head, p[:min(room, len(p))]...)
+	}
+	if len(p) >= dumpTailSize {
+		d.tail = append(d.tail[:0], p[len(p)-dumpTailSize:]...)
+	} else {
+		d.tail = append(d.tail, p...)
+		if extra := len(d.tail) - dumpTailSize; extra > 0 {
+			d.tail = append(d.tail[:0], d.tail[extra:]...)
+		}
+	}
+	return len(p), nil
+}
+
+// Binlog retorna as coordenadas gravadas no início do dump, ou nil se o dump
+// não as contém (log binário desativado).
+func (d *DumpInspector) Binlog() *Coordinates {
+	m := dumpBinlogPattern.FindSubmatch(d.head)
+	if m == nil {
+		return nil
+	}
+	pos, err := strconv.ParseInt(string(m[2]), 10, 64)
+	if err != nil {
+		return nil
+	}
+	c := &Coordinates{File: string(m[1]), Position: pos}
+	if g := dumpGTIDPattern.FindSubmatch(d.head); g != nil {
+		c.GTID = string(g[1])
+	}
+	return c
+}
+
+// Completed indica se o dump termina com a marca de conclusão do mysqldump.
+// Um dump sem ela foi truncado.
+func (d *DumpInspector) Completed() bool {
+	return bytes.Contains(d.tail, dumpCompleted)
+}
+
+// ParseMariabackupLine extrai as coordenadas do log binário de uma linha da
+// saída de erro do mariabackup.
+func ParseMariabackupLine(line string) (*Coordinates, bool) {
+	m := mariabackupBinlogPattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, false
+	}
+	pos, err := strconv.ParseInt(m[2], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &Coordinates{File: m[1], Position: pos, GTID: m[3]}, true
+}
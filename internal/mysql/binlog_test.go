@@ -0,0 +1,63 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dumpHeader = `-- MySQL dump 10.13  Distrib 8.0.36, for Linux (x86_64)
+--
+-- Host: db01    Database: scm
+SET @@GLOBAL.GTID_PURGED=/*!80000 '+'*/ '3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5';
+
+--
+-- Position to start replication or point-in-time recovery from
+--
+
+-- CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='binlog.000002', SOURCE_LOG_POS=157;
+`
+
+func TestDumpInspector(t *testing.T) {
+	d := &DumpInspector{}
+	// Escritas em pedaços pequenos e grandes, como as do compressor
+	d.Write([]byte(dumpHeader[:40]))
+	d.Write([]byte(dumpHeader[40:]))
+	d.Write([]byte(strings.Repeat("INSERT INTO t VALUES (1);\n", 10000)))
+	assert.False(t, d.Completed())
+	d.Write([]byte("-- Dump completed on 2025-04-07 16:45:12\n"))
+	assert.True(t, d.Completed())
+
+	c := d.Binlog()
+	require.NotNil(t, c)
+	assert.Equal(t, "binlog.000002", c.File)
+	assert.Equal(t, int64(157), c.Position)
+	assert.Equal(t, "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5", c.GTID)
+}
+
+func TestDumpInspector_MasterData(t *testing.T) {
+	d := &DumpInspector{}
+	d.Write([]byte("-- CHANGE MASTER TO MASTER_LOG_FILE='mariadb-bin.000001', MASTER_LOG_POS=328;\n"))
+	c := d.Binlog()
+	require.NotNil(t, c)
+	assert.Equal(t, "mariadb-bin.000001", c.File)
+	assert.Equal(t, int64(328), c.Position)
+	assert.Empty(t, c.GTID)
+
+	assert.Nil(t, (&DumpInspector{}).Binlog())
+}
+
+func TestParseMariabackupLine(t *testing.T) {
+	c, ok := ParseMariabackupLine("[00] 2025-04-07 16:45:12 MySQL binlog position: filename 'mariadb-bin.000002', position '342', GTID of the last change '0-1-3'")
+	require.True(t, ok)
+	assert.Equal(t, &Coordinates{File: "mariadb-bin.000002", Position: 342, GTID: "0-1-3"}, c)
+
+	c, ok = ParseMariabackupLine("mariabackup: MySQL binlog position: filename 'mysql-bin.000007', position '4'")
+	require.True(t, ok)
+	assert.Equal(t, &Coordinates{File: "mysql-bin.000007", Position: 4}, c)
+
+	_, ok = ParseMariabackupLine("[00] 2025-04-07 16:45:12 completed OK!")
+	assert.False(t, ok)
+}
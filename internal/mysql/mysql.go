@@ -0,0 +1,209 @@
+// Package mysql reúne o acesso ao MySQL/MariaDB usado pelo dbbackup: conexão,
+// consultas de versão e tamanho, lock nomeado (GET_LOCK), a montagem das
+// linhas de comando de mysqldump e mariabackup e a leitura das coordenadas do
+// log binário.
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+)
+
+// DefaultPort é a porta padrão do MySQL/MariaDB.
+const DefaultPort = 3306
+
+// Modos de TLS aceitos (flag -mysql-tls), com o significado do driver.
+var TLSModes = []string{"preferred", "true", "skip-verify", "false"}
+
+// ConnOptions configura a conexão com o servidor. As mesmas opções valem para
+// o driver e para as ferramentas (via arquivo de opções).
+type ConnOptions struct {
+	Host           string // Nome, IP ou caminho do socket Unix
+	Port           int
+	Database       string
+	User           string
+	Password       string
+	TLS            string
+	ConnectTimeout time.Duration
+}
+
+// ParseServer separa o valor de -server em host e porta. Aceita host,
+// host:porta, [IPv6]:porta e o caminho de um socket Unix.
+func ParseServer(server string) (host string, port int, err error) {
+	if strings.HasPrefix(server, "/") {
+		return server, DefaultPort, nil
+	}
+	host, portStr, splitErr := net.SplitHostPort(server)
+	if splitErr != nil {
+		return strings.Trim(server, "[]"), DefaultPort, nil
+	}
+	port, err = strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("porta inválida em '%s'", server)
+	}
+	return host, port, nil
+}
+
+func (o ConnOptions) socket() bool {
+	return strings.HasPrefix(o.Host, "/")
+}
+
+// DSN retorna a connection string usada pelo driver.
+func (o ConnOptions) DSN() string {
+	cfg := driver.NewConfig()
+	cfg.User = o.User
+	cfg.Passwd = o.Password
+	cfg.DBName = o.Database
+	cfg.Timeout = o.ConnectTimeout
+	cfg.TLSConfig = o.TLS
+	cfg.ConnectionAttributes = "program_name:MaisSaudeBackup"
+	if o.socket() {
+		cfg.Net, cfg.Addr = "unix", o.Host
+	} else {
+		cfg.Net, cfg.Addr = "tcp", net.JoinHostPort(o.Host, strconv.Itoa(o.Port))
+	}
+	return cfg.FormatDSN()
+}
+
+// Open prepara o pool de conexões. A conexão só é estabelecida de fato no
+// primeiro uso (ex: PingContext).
+func Open(o ConnOptions) (*sql.DB, error) {
+	db, err := sql.Open("mysql", o.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("preparar conexão com o MySQL falhou: %w", err)
+	}
+	return db, nil
+}
+
+// WriteOptionFile grava em um arquivo temporário (permissão 0600) a seção
+// [client] com as credenciais, usada pelas ferramentas com
+// --defaults-extra-file para que a senha não apareça na lista de processos.
+// mariadb indica as ferramentas do MariaDB, cujas opções de TLS diferem das do
+// MySQL. O chamador deve remover o arquivo.
+func WriteOptionFile(o ConnOptions, mariadb bool) (string, error) {
+	var b strings.Builder
+	b.WriteString("[client]\n")
+	if o.User != "" {
+		fmt.Fprintf(&b, "user=%s\n", quoteOption(o.User))
+	}
+	if o.Password != "" {
+		fmt.Fprintf(&b, "password=%s\n", quoteOption(o.Password))
+	}
+	if o.socket() {
+		fmt.Fprintf(&b, "socket=%s\n", quoteOption(o.Host))
+	} else {
+		fmt.Fprintf(&b, "host=%s\nport=%d\nprotocol=tcp\n", quoteOption(o.Host), o.Port)
+	}
+	// Opções desconhecidas em [client] interrompem as ferramentas
+	for _, opt := range toolTLSOptions(o.TLS, mariadb) {
+		b.WriteString(opt + "\n")
+	}
+
+	f, err := os.CreateTemp("", "dbbackup-*.cnf")
+	if err != nil {
+		return "", fmt.Errorf("criar arquivo de opções falhou: %w", err)
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("gravar arquivo de opções falhou: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("gravar arquivo de opções falhou: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// toolTLSOptions converte o modo de TLS do driver nas opções das ferramentas.
+func toolTLSOptions(mode string, mariadb bool) []string {
+	if mariadb {
+		switch mode {
+		case "true":
+			return []string{"ssl", "ssl-verify-server-cert"}
+		case "skip-verify":
+			return []string{"ssl"}
+		case "false":
+			return []string{"skip-ssl"}
+		}
+		return nil
+	}
+	switch mode {
+	case "true":
+		return []string{"ssl-mode=VERIFY_IDENTITY"}
+	case "skip-verify":
+		return []string{"ssl-mode=REQUIRED"}
+	case "false":
+		return []string{"ssl-mode=DISABLED"}
+	case "preferred":
+		return []string{"ssl-mode=PREFERRED"}
+	}
+	return nil
+}
+
+// quoteOption coloca o valor entre aspas duplas, escapando \ e ", como aceito
+// pelos arquivos de opções do MySQL.
+func quoteOption(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return `"` + v + `"`
+}
+
+// ServerInfo descreve o servidor.
+type ServerInfo struct {
+	Version string // Ex: 8.0.36 ou 10.11.6-MariaDB
+	MariaDB bool
+	LogBin  bool // Log binário ativo
+}
+
+// QueryServerInfo consulta a versão do servidor e se o log binário está ativo.
+func QueryServerInfo(ctx context.Context, db *sql.DB) (*ServerInfo, error) {
+	info := &ServerInfo{}
+	if err := db.QueryRowContext(ctx, "SELECT VERSION(), @@log_bin").Scan(&info.Version, &info.LogBin); err != nil {
+		return nil, fmt.Errorf("consultar versão do MySQL falhou: %w", err)
+	}
+	info.MariaDB = strings.Contains(strings.ToLower(info.Version), "mariadb")
+	return info, nil
+}
+
+// QueryDatabaseSize retorna o tamanho de dados e índices de database, ou de
+// todos os bancos se database for vazio.
+func QueryDatabaseSize(ctx context.Context, db *sql.DB, database string) (int64, error) {
+	var size int64
+	var err error
+	if database == "" {
+		err = db.QueryRowContext(ctx, "SELECT CAST(COALESCE(SUM(data_length + index_length), 0) AS SIGNED) FROM information_schema.tables").Scan(&size)
+	} else {
+		err = db.QueryRowContext(ctx, "SELECT CAST(COALESCE(SUM(data_length + index_length), 0) AS SIGNED) FROM information_schema.tables WHERE table_schema = ?", database).Scan(&size)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("consultar tamanho do banco falhou: %w", err)
+	}
+	return size, nil
+}
+
+// TryLock tenta obter, sem espera, o lock nomeado name na conexão conn.
+// Retorna false se outra sessão o detém.
+func TryLock(ctx context.Context, conn *sql.Conn, name string) (bool, error) {
+	var ok sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&ok); err != nil {
+		return false, fmt.Errorf("GET_LOCK falhou: %w", err)
+	}
+	return ok.Valid && ok.Int64 == 1, nil
+}
+
+// ReleaseLock libera o lock nomeado name obtido em conn.
+func ReleaseLock(ctx context.Context, conn *sql.Conn, name string) error {
+	if _, err := conn.ExecContext(ctx, "DO RELEASE_LOCK(?)", name); err != nil {
+		return fmt.Errorf("RELEASE_LOCK falhou: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package mysql
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServer(t *testing.T) {
+	tests := []struct {
+		server   string
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{server: "db01", wantHost: "db01", wantPort: DefaultPort},
+		{server: "db01:3307", wantHost: "db01", wantPort: 3307},
+		{server: "[::1]:3307", wantHost: "::1", wantPort: 3307},
+		{server: "/var/run/mysqld/mysqld.sock", wantHost: "/var/run/mysqld/mysqld.sock", wantPort: DefaultPort},
+		{server: "db01:0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			host, port, err := ParseServer(tt.server)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHost, host)
+			assert.Equal(t, tt.wantPort, port)
+		})
+	}
+}
+
+func TestConnOptions_DSN(t *testing.T) {
+	o := ConnOptions{Host: "db01", Port: 3307, Database: "scm", User: "backup", Password: "s3cr@t:/", TLS: "skip-verify", ConnectTimeout: 30 * time.Second}
+	cfg, err := driver.ParseDSN(o.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, "tcp", cfg.Net)
+	assert.Equal(t, "db01:3307", cfg.Addr)
+	assert.Equal(t, "scm", cfg.DBName)
+	assert.Equal(t, "backup", cfg.User)
+	assert.Equal(t, "s3cr@t:/", cfg.Passwd)
+	assert.Equal(t, 30*time.Second, cfg.Timeout)
+
+	o = ConnOptions{Host: "/var/run/mysqld/mysqld.sock", Database: "scm"}
+	cfg, err = driver.ParseDSN(o.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, "unix", cfg.Net)
+	assert.Equal(t, "/var/run/mysqld/mysqld.sock", cfg.Addr)
+}
+
+func TestWriteOptionFile(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	path, err := WriteOptionFile(ConnOptions{Host: "db01", Port: 3306, User: "backup", Password: `a"b\c`, TLS: "false"}, false)
+	require.NoError(t, err)
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[client]\nuser=\"backup\"\npassword=\"a\\\"b\\\\c\"\nhost=\"db01\"\nport=3306\nprotocol=tcp\nssl-mode=DISABLED\n", string(data))
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// As ferramentas do MariaDB não conhecem ssl-mode
+	path, err = WriteOptionFile(ConnOptions{Host: "/tmp/mysql.sock", TLS: "true"}, true)
+	require.NoError(t, err)
+	defer os.Remove(path)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[client]\nsocket=\"/tmp/mysql.sock\"\nssl\nssl-verify-server-cert\n", string(data))
+}
@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Ferramentas de backup (flag -mysql-tool).
+const (
+	ToolMysqldump   = "mysqldump"   // Dump lógico (SQL) de um banco, consistente com --single-transaction
+	ToolMariabackup = "mariabackup" // Cópia física da instância inteira (MariaDB), em fluxo xbstream
+)
+
+// Tools lista as ferramentas aceitas.
+var Tools = []string{ToolMysqldump, ToolMariabackup}
+
+// BinlogOption retorna a opção do mysqldump que grava as coordenadas do log
+// binário no dump (source-data a partir do MySQL 8.0.26, master-data antes
+// dele e no MariaDB), ou "" se o log binário estiver desativado.
+func BinlogOption(info *ServerInfo) string {
+	if !info.LogBin {
+		return ""
+	}
+	if info.MariaDB || versionBefore(info.Version, 8, 0, 26) {
+		return "master-data"
+	}
+	return "source-data"
+}
+
+// versionBefore indica se a versão v (ex: 8.0.36-log) é anterior a
+// major.minor.patch. Versões ilegíveis são tratadas como antigas.
+func versionBefore(v string, major, minor, patch int) bool {
+	if i := strings.IndexFunc(v, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	want := []int{major, minor, patch}
+	for i, w := range want {
+		if i >= len(parts) {
+			return true
+		}
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return true
+		}
+		if n != w {
+			return n < w
+		}
+	}
+	return false
+}
+
+// DumpArgs retorna os argumentos do mysqldump para gravar database na saída
+// padrão em uma única transação. O arquivo de opções deve ser o primeiro
+// argumento. binlog é o retorno de BinlogOption.
+func DumpArgs(optionFile, database, binlog string) []string {
+	args := []string{
+		"--defaults-extra-file=" + optionFile,
+		"--single-transaction",
+		"--quick",
+		"--routines",
+		"--triggers",
+		"--events",
+		"--hex-blob",
+		"--default-character-set=utf8mb4",
+	}
+	if binlog != "" {
+		// Com =2 a instrução CHANGE MASTER/REPLICATION SOURCE vai comentada
+		args = append(args, "--"+binlog+"=2")
+	}
+	return append(args, database)
+}
+
+// MariabackupArgs retorna os argumentos do mariabackup para gravar a cópia da
+// instância em formato xbstream na saída padrão. targetDir recebe apenas
+// arquivos temporários.
+func MariabackupArgs(optionFile, targetDir string) []string {
+	return []string{
+		"--defaults-extra-file=" + optionFile,
+		"--backup",
+		"--stream=xbstream",
+		"--target-dir=" + targetDir,
+	}
+}
@@ -0,0 +1,28 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinlogOption(t *testing.T) {
+	assert.Empty(t, BinlogOption(&ServerInfo{Version: "8.0.36"}))
+	assert.Equal(t, "source-data", BinlogOption(&ServerInfo{Version: "8.0.36", LogBin: true}))
+	assert.Equal(t, "source-data", BinlogOption(&ServerInfo{Version: "8.4.0-log", LogBin: true}))
+	assert.Equal(t, "master-data", BinlogOption(&ServerInfo{Version: "8.0.25", LogBin: true}))
+	assert.Equal(t, "master-data", BinlogOption(&ServerInfo{Version: "5.7.44-log", LogBin: true}))
+	assert.Equal(t, "master-data", BinlogOption(&ServerInfo{Version: "11.4.2-MariaDB", MariaDB: true, LogBin: true}))
+}
+
+func TestDumpArgs(t *testing.T) {
+	args := DumpArgs("/tmp/x.cnf", "scm", "source-data")
+	assert.Equal(t, "--defaults-extra-file=/tmp/x.cnf", args[0])
+	assert.Contains(t, args, "--single-transaction")
+	assert.Contains(t, args, "--source-data=2")
+	assert.Equal(t, "scm", args[len(args)-1])
+
+	for _, arg := range DumpArgs("/tmp/x.cnf", "scm", "") {
+		assert.NotContains(t, arg, "-data=")
+	}
+}
@@ -0,0 +1,167 @@
+// Package postgres reúne o acesso ao PostgreSQL usado pelo dbbackup: conexão
+// (driver pgx), consultas de versão e tamanho, lock consultivo e a montagem
+// das linhas de comando de pg_dump, pg_restore e pg_basebackup.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // Driver PostgreSQL (registra "pgx")
+)
+
+// DefaultPort é a porta padrão do PostgreSQL.
+const DefaultPort = 5432
+
+// Modos de SSL aceitos (flag -pg-sslmode), com o significado da libpq.
+var SSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// ConnOptions configura a conexão com o servidor. As mesmas opções valem para
+// o driver e para as ferramentas de linha de comando (via variáveis PG*).
+type ConnOptions struct {
+	Host           string // Nome, IP ou diretório do socket Unix
+	Port           int
+	Database       string
+	User           string
+	Password       string // Vazio usa ~/.pgpass ou autenticação sem senha
+	SSLMode        string
+	ConnectTimeout time.Duration
+}
+
+// ParseServer separa o valor de -server em host e porta. Aceita host,
+// host:porta, [IPv6]:porta e o diretório de um socket Unix.
+func ParseServer(server string) (host string, port int, err error) {
+	if strings.HasPrefix(server, "/") {
+		return server, DefaultPort, nil
+	}
+	host, portStr, splitErr := net.SplitHostPort(server)
+	if splitErr != nil {
+		// Sem porta (inclusive IPv6 sem colchetes)
+		return strings.Trim(server, "[]"), DefaultPort, nil
+	}
+	port, err = strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("porta inválida em '%s'", server)
+	}
+	return host, port, nil
+}
+
+// Env retorna as variáveis de ambiente que configuram a conexão das
+// ferramentas da libpq. A senha vai em PGPASSWORD, nunca na linha de comando.
+func (o ConnOptions) Env() []string {
+	env := []string{
+		"PGHOST=" + o.Host,
+		"PGPORT=" + strconv.Itoa(o.Port),
+		"PGDATABASE=" + o.Database,
+		"PGAPPNAME=MaisSaudeBackup",
+	}
+	if o.User != "" {
+		env = append(env, "PGUSER="+o.User)
+	}
+	if o.Password != "" {
+		env = append(env, "PGPASSWORD="+o.Password)
+	}
+	if o.SSLMode != "" {
+		env = append(env, "PGSSLMODE="+o.SSLMode)
+	}
+	if o.ConnectTimeout > 0 {
+		env = append(env, "PGCONNECT_TIMEOUT="+strconv.Itoa(max(int(o.ConnectTimeout.Seconds()), 2)))
+	}
+	return env
+}
+
+// DSN retorna a connection string (URL) usada pelo driver pgx.
+func (o ConnOptions) DSN() string {
+	q := url.Values{}
+	q.Set("application_name", "MaisSaudeBackup")
+	if o.SSLMode != "" {
+		q.Set("sslmode", o.SSLMode)
+	}
+	if o.ConnectTimeout > 0 {
+		q.Set("connect_timeout", strconv.Itoa(max(int(o.ConnectTimeout.Seconds()), 2)))
+	}
+	u := &url.URL{Scheme: "postgres", Path: "/" + o.Database}
+	if strings.HasPrefix(o.Host, "/") {
+		// Socket Unix: o diretório vai no parâmetro host
+		q.Set("host", o.Host)
+		q.Set("port", strconv.Itoa(o.Port))
+	} else {
+		u.Host = net.JoinHostPort(o.Host, strconv.Itoa(o.Port))
+	}
+	if o.User != "" {
+		if o.Password != "" {
+			u.User = url.UserPassword(o.User, o.Password)
+		} else {
+			u.User = url.User(o.User)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// Open prepara o pool de conexões. A conexão só é estabelecida de fato no
+// primeiro uso (ex: PingContext).
+func Open(o ConnOptions) (*sql.DB, error) {
+	db, err := sql.Open("pgx", o.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("preparar conexão com o PostgreSQL falhou: %w", err)
+	}
+	return db, nil
+}
+
+// QueryServerVersion retorna a versão do servidor (ex: 16.4).
+func QueryServerVersion(ctx context.Context, db *sql.DB) (string, error) {
+	var v string
+	if err := db.QueryRowContext(ctx, "SHOW server_version").Scan(&v); err != nil {
+		return "", fmt.Errorf("consultar versão do PostgreSQL falhou: %w", err)
+	}
+	return v, nil
+}
+
+// QueryDatabaseSize retorna o tamanho em disco de database, ou de todos os
+// bancos do cluster se database for vazio.
+func QueryDatabaseSize(ctx context.Context, db *sql.DB, database string) (int64, error) {
+	var size int64
+	var err error
+	if database == "" {
+		err = db.QueryRowContext(ctx, "SELECT COALESCE(SUM(pg_database_size(oid)), 0)::bigint FROM pg_database WHERE datallowconn").Scan(&size)
+	} else {
+		err = db.QueryRowContext(ctx, "SELECT pg_database_size($1)::bigint", database).Scan(&size)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("consultar tamanho do banco falhou: %w", err)
+	}
+	return size, nil
+}
+
+// AdvisoryLockKey converte o nome de um recurso na chave de pg_advisory_lock.
+func AdvisoryLockKey(resource string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(resource))
+	return int64(h.Sum64())
+}
+
+// TryAdvisoryLock tenta obter, sem espera, o lock consultivo de sessão key na
+// conexão conn. Retorna false se outra sessão o detém.
+func TryAdvisoryLock(ctx context.Context, conn *sql.Conn, key int64) (bool, error) {
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok); err != nil {
+		return false, fmt.Errorf("pg_try_advisory_lock falhou: %w", err)
+	}
+	return ok, nil
+}
+
+// AdvisoryUnlock libera o lock consultivo key obtido em conn.
+func AdvisoryUnlock(ctx context.Context, conn *sql.Conn, key int64) error {
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key); err != nil {
+		return fmt.Errorf("pg_advisory_unlock falhou: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServer(t *testing.T) {
+	tests := []struct {
+		server   string
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{server: "db01", wantHost: "db01", wantPort: DefaultPort},
+		{server: "db01:5433", wantHost: "db01", wantPort: 5433},
+		{server: "[::1]:5433", wantHost: "::1", wantPort: 5433},
+		{server: "::1", wantHost: "::1", wantPort: DefaultPort},
+		{server: "/var/run/postgresql", wantHost: "/var/run/postgresql", wantPort: DefaultPort},
+		{server: "db01:porta", wantErr: true},
+		{server: "db01:70000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			host, port, err := ParseServer(tt.server)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHost, host)
+			assert.Equal(t, tt.wantPort, port)
+		})
+	}
+}
+
+func TestConnOptions_DSN(t *testing.T) {
+	o := ConnOptions{Host: "db01", Port: 5433, Database: "scm", User: "backup", Password: "s3cr@t", SSLMode: "require", ConnectTimeout: 30 * time.Second}
+	u, err := url.Parse(o.DSN())
+	require.NoError(t, err)
+	assert.Equal(t, "postgres", u.Scheme)
+	assert.Equal(t, "db01:5433", u.Host)
+	assert.Equal(t, "/scm", u.Path)
+	assert.Equal(t, "backup", u.User.Username())
+	password, _ := u.User.Password()
+	assert.Equal(t, "s3cr@t", password)
+	assert.Equal(t, "require", u.Query().Get("sslmode"))
+	assert.Equal(t, "30", u.Query().Get("connect_timeout"))
+
+	// Socket Unix: o diretório vai no parâmetro host
+	o = ConnOptions{Host: "/var/run/postgresql", Port: DefaultPort, Database: "scm"}
+	u, err = url.Parse(o.DSN())
+	require.NoError(t, err)
+	assert.Empty(t, u.Host)
+	assert.Equal(t, "/var/run/postgresql", u.Query().Get("host"))
+}
+
+func TestConnOptions_Env(t *testing.T) {
+	env := ConnOptions{Host: "db01", Port: DefaultPort, Database: "scm", User: "backup", Password: "x", ConnectTimeout: 500 * time.Millisecond}.Env()
+	assert.Contains(t, env, "PGHOST=db01")
+	assert.Contains(t, env, "PGPORT=5432")
+	assert.Contains(t, env, "PGDATABASE=scm")
+	assert.Contains(t, env, "PGUSER=backup")
+	assert.Contains(t, env, "PGPASSWORD=x")
+	assert.Contains(t, env, "PGCONNECT_TIMEOUT=2")
+
+	// Sem senha, as ferramentas usam ~/.pgpass
+	env = ConnOptions{Host: "db01", Port: DefaultPort, Database: "scm"}.Env()
+	for _, v := range env {
+		assert.NotContains(t, v, "PGPASSWORD")
+	}
+}
+
+func TestDumpArgs(t *testing.T) {
+	assert.Equal(t,
+		[]string{"--no-password", "--verbose", "--compress=0", "--file=/tmp/scm.dump", "--format=custom"},
+		DumpArgs(FormatCustom, "/tmp/scm.dump", 4))
+	assert.Equal(t,
+		[]string{"--no-password", "--verbose", "--compress=0", "--file=/tmp/scm.dir", "--format=directory", "--jobs=4"},
+		DumpArgs(FormatDirectory, "/tmp/scm.dir", 4))
+	assert.NotContains(t, DumpArgs(FormatDirectory, "/tmp/scm.dir", 1), "--jobs=1")
+	// Sem arquivo, o dump vai para a saída padrão (modo -stream)
+	assert.Equal(t,
+		[]string{"--no-password", "--verbose", "--compress=0", "--format=custom"},
+		DumpArgs(FormatCustom, "", 0))
+}
+
+func TestAdvisoryLockKey(t *testing.T) {
+	assert.Equal(t, AdvisoryLockKey("dbbackup:scm"), AdvisoryLockKey("dbbackup:scm"))
+	assert.NotEqual(t, AdvisoryLockKey("dbbackup:scm"), AdvisoryLockKey("dbbackup:rh"))
+}
@@ -0,0 +1,48 @@
+package postgres
+
+import "strconv"
+
+// Formatos do backup (flag -pg-format).
+const (
+	FormatCustom     = "custom"     // pg_dump -Fc: um arquivo, restaurado com pg_restore
+	FormatDirectory  = "directory"  // pg_dump -Fd: um diretório por tabela, com dump paralelo
+	FormatBasebackup = "basebackup" // pg_basebackup -Ft: cópia física do cluster inteiro
+)
+
+// Formats lista os formatos aceitos.
+var Formats = []string{FormatCustom, FormatDirectory, FormatBasebackup}
+
+// DumpArgs retorna os argumentos de pg_dump para gravar database em out no
+// formato custom ou directory. Com out vazio o dump custom vai para a saída
+// padrão. O dump não é comprimido pelo pg_dump, pois o arquivo final já é
+// comprimido pelo dbbackup. jobs > 1 só vale para directory.
+func DumpArgs(format, out string, jobs int) []string {
+	args := []string{"--no-password", "--verbose", "--compress=0"}
+	if out != "" {
+		args = append(args, "--file="+out)
+	}
+	switch format {
+	case FormatDirectory:
+		args = append(args, "--format=directory")
+		if jobs > 1 {
+			args = append(args, "--jobs="+strconv.Itoa(jobs))
+		}
+	default:
+		args = append(args, "--format=custom")
+	}
+	// O banco vem de PGDATABASE
+	return args
+}
+
+// BasebackupArgs retorna os argumentos de pg_basebackup para gravar o cluster
+// em out (base.tar, pg_wal.tar e backup_manifest), com o WAL necessário para
+// que a cópia seja consistente.
+func BasebackupArgs(out string) []string {
+	return []string{"--no-password", "--verbose", "--pgdata=" + out, "--format=tar", "--wal-method=stream", "--checkpoint=fast"}
+}
+
+// RestoreListArgs retorna os argumentos de pg_restore que leem o índice do
+// dump em path, verificando que ele é legível.
+func RestoreListArgs(path string) []string {
+	return []string{"--list", path}
+}
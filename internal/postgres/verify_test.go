@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTar grava os arquivos em um tar, como o pg_basebackup -Ft.
+func writeTar(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+}
+
+func crc32cHex(content string) string {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], crc32.Checksum([]byte(content), crc32.MakeTable(crc32.Castagnoli)))
+	return hex.EncodeToString(b[:])
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeManifest(t *testing.T, dir string, files []ManifestFile) {
+	t.Helper()
+	data, err := json.Marshal(BackupManifest{
+		Files:     files,
+		WALRanges: []WALRange{{Timeline: 1, StartLSN: "0/2000028", EndLSN: "0/2000100"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestName), data, 0600))
+}
+
+func newBaseBackup(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeTar(t, filepath.Join(dir, "base.tar"), map[string]string{
+		"PG_VERSION":        "16\n",
+		"global/pg_control": "controle",
+		"backup_label":      "START WAL LOCATION: 0/2000028",
+	})
+	writeTar(t, filepath.Join(dir, "16385.tar"), map[string]string{"PG_16_202307071/5/16386": "tabela"})
+	writeTar(t, filepath.Join(dir, "pg_wal.tar"), map[string]string{"000000010000000000000002": "wal"})
+	writeManifest(t, dir, []ManifestFile{
+		{Path: "PG_VERSION", Size: 3, ChecksumAlgorithm: "CRC32C", Checksum: crc32cHex("16\n")},
+		{Path: "global/pg_control", Size: 8, ChecksumAlgorithm: "SHA256", Checksum: sha256Hex("controle")},
+		{EncodedPath: hex.EncodeToString([]byte("backup_label")), Size: 29, ChecksumAlgorithm: "NONE"},
+		{Path: "pg_tblspc/16385/PG_16_202307071/5/16386", Size: 6, ChecksumAlgorithm: "CRC32C", Checksum: crc32cHex("tabela")},
+	})
+	return dir
+}
+
+func TestVerifyBaseBackup(t *testing.T) {
+	dir := newBaseBackup(t)
+	require.NoError(t, VerifyBaseBackup(dir))
+
+	m, err := ReadBackupManifest(filepath.Join(dir, ManifestName))
+	require.NoError(t, err)
+	require.Len(t, m.WALRanges, 1)
+	assert.Equal(t, "0/2000028", m.WALRanges[0].StartLSN)
+}
+
+func TestVerifyBaseBackup_Errors(t *testing.T) {
+	t.Run("checksum divergente", func(t *testing.T) {
+		dir := newBaseBackup(t)
+		writeTar(t, filepath.Join(dir, "16385.tar"), map[string]string{"PG_16_202307071/5/16386": "tabelx"})
+		err := VerifyBaseBackup(dir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "checksum CRC32C")
+	})
+
+	t.Run("tamanho divergente", func(t *testing.T) {
+		dir := newBaseBackup(t)
+		writeTar(t, filepath.Join(dir, "16385.tar"), map[string]string{"PG_16_202307071/5/16386": "tabela grande"})
+		err := VerifyBaseBackup(dir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "tamanho 13, esperado 6")
+	})
+
+	t.Run("arquivo ausente", func(t *testing.T) {
+		dir := newBaseBackup(t)
+		require.NoError(t, os.Remove(filepath.Join(dir, "16385.tar")))
+		err := VerifyBaseBackup(dir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "pg_tblspc/16385/PG_16_202307071/5/16386")
+	})
+
+	t.Run("sem manifesto", func(t *testing.T) {
+		dir := newBaseBackup(t)
+		require.NoError(t, os.Remove(filepath.Join(dir, ManifestName)))
+		assert.Error(t, VerifyBaseBackup(dir))
+	})
+}
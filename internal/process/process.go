@@ -0,0 +1,126 @@
+// Package process executa as ferramentas externas de backup (mysqldump,
+// pg_dump etc.) entregando a saída padrão como fluxo, para que ela seja
+// comprimida enquanto o processo ainda está em execução.
+package process
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Tool retorna o caminho da ferramenta name (ex: pg_dump) em binDir, ou
+// apenas o nome (procurado no PATH) se binDir for vazio.
+func Tool(binDir, name string) string {
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	if binDir == "" {
+		return name
+	}
+	return filepath.Join(binDir, name)
+}
+
+// Stream é a saída padrão de uma ferramenta em execução. Close espera o fim do
+// processo e retorna sua falha, com as últimas linhas da saída de erro.
+type Stream struct {
+	cmd    *exec.Cmd
+	name   string
+	stdout io.ReadCloser
+	cancel context.CancelFunc
+	eof    bool
+
+	stderrDone chan struct{}
+	tail       []string
+}
+
+// Start inicia a ferramenta path com args, acrescentando env ao ambiente
+// atual. Cada linha da saída de erro é entregue a onLine, a partir de outra
+// goroutine.
+func Start(ctx context.Context, path string, args, env []string, onLine func(string)) (*Stream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = append(os.Environ(), env...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("iniciar %s falhou: %w", filepath.Base(path), err)
+	}
+
+	s := &Stream{cmd: cmd, name: filepath.Base(path), stdout: stdout, cancel: cancel, stderrDone: make(chan struct{})}
+	go func() {
+		defer close(s.stderrDone)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if onLine != nil {
+				onLine(line)
+			}
+			if s.tail = append(s.tail, line); len(s.tail) > 5 {
+				s.tail = s.tail[1:]
+			}
+		}
+		_, _ = io.Copy(io.Discard, stderr)
+	}()
+	return s, nil
+}
+
+func (s *Stream) Read(p []byte) (int, error) {
+	n, err := s.stdout.Read(p)
+	if errors.Is(err, io.EOF) {
+		s.eof = true
+	}
+	return n, err
+}
+
+// Close encerra o processo, interrompendo-o se a saída não foi lida até o fim.
+func (s *Stream) Close() error {
+	defer s.cancel()
+	if !s.eof {
+		s.cancel()
+	}
+	// A saída de erro é lida até o fim antes do Wait, que fecha os pipes
+	<-s.stderrDone
+	err := s.cmd.Wait()
+	if err == nil && !s.eof {
+		return fmt.Errorf("%s interrompido antes do fim da saída", s.name)
+	}
+	if err != nil {
+		if len(s.tail) > 0 {
+			return fmt.Errorf("%s falhou: %w: %s", s.name, err, strings.Join(s.tail, " | "))
+		}
+		return fmt.Errorf("%s falhou: %w", s.name, err)
+	}
+	return nil
+}
+
+// Run executa a ferramenta path com args até o fim, descartando a saída
+// padrão. Cada linha da saída de erro é entregue a onLine (as ferramentas
+// informam o progresso nela); em caso de falha o erro inclui as últimas linhas.
+func Run(ctx context.Context, path string, args, env []string, onLine func(string)) error {
+	s, err := Start(ctx, path, args, env, onLine)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, s); err != nil {
+		_ = s.Close()
+		return fmt.Errorf("ler saída de %s falhou: %w", s.name, err)
+	}
+	return s.Close()
+}
@@ -0,0 +1,100 @@
+package process
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript grava uma ferramenta falsa em sh.
+func writeScript(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("ferramentas falsas escritas em sh")
+	}
+	path := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755))
+	return path
+}
+
+func TestStream(t *testing.T) {
+	t.Run("sucesso", func(t *testing.T) {
+		path := writeScript(t, "echo progresso >&2\necho dados\n")
+		var lines []string
+		s, err := Start(context.Background(), path, nil, nil, func(line string) { lines = append(lines, line) })
+		require.NoError(t, err)
+		data, err := io.ReadAll(s)
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+		assert.Equal(t, "dados\n", string(data))
+		assert.Equal(t, []string{"progresso"}, lines)
+	})
+
+	t.Run("falha inclui a saída de erro", func(t *testing.T) {
+		path := writeScript(t, "echo parcial\necho 'Access denied for user' >&2\nexit 2\n")
+		s, err := Start(context.Background(), path, nil, nil, nil)
+		require.NoError(t, err)
+		_, err = io.ReadAll(s)
+		require.NoError(t, err)
+		err = s.Close()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Access denied for user")
+	})
+
+	t.Run("fechar antes do fim interrompe o processo", func(t *testing.T) {
+		path := writeScript(t, "while true; do echo dados; done\n")
+		s, err := Start(context.Background(), path, nil, nil, nil)
+		require.NoError(t, err)
+		_, err = io.ReadFull(s, make([]byte, 10))
+		require.NoError(t, err)
+		assert.Error(t, s.Close())
+	})
+}
+
+func TestStream_StartError(t *testing.T) {
+	_, err := Start(context.Background(), filepath.Join(t.TempDir(), "inexistente"), nil, nil, nil)
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "iniciar inexistente falhou"))
+}
+
+func TestStart_Env(t *testing.T) {
+	path := writeScript(t, "echo \"$PGDATABASE\"\n")
+	s, err := Start(context.Background(), path, nil, []string{"PGDATABASE=scm"}, nil)
+	require.NoError(t, err)
+	data, err := io.ReadAll(s)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+	assert.Equal(t, "scm\n", string(data))
+}
+
+func TestRun(t *testing.T) {
+	t.Run("sucesso", func(t *testing.T) {
+		path := writeScript(t, "echo dados\necho 'pg_dump: dumping contents of table' >&2\n")
+		var lines []string
+		require.NoError(t, Run(context.Background(), path, nil, nil, func(line string) { lines = append(lines, line) }))
+		assert.Equal(t, []string{"pg_dump: dumping contents of table"}, lines)
+	})
+
+	t.Run("falha inclui a saída de erro", func(t *testing.T) {
+		path := writeScript(t, "echo 'FATAL: password authentication failed' >&2\nexit 1\n")
+		err := Run(context.Background(), path, nil, nil, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "FATAL: password authentication failed")
+	})
+}
+
+func TestTool(t *testing.T) {
+	exe := ""
+	if runtime.GOOS == "windows" {
+		exe = ".exe"
+	}
+	assert.Equal(t, "pg_dump"+exe, Tool("", "pg_dump"))
+	assert.Equal(t, filepath.Join("bin", "mysqldump"+exe), Tool("bin", "mysqldump"))
+}
@@ -0,0 +1,282 @@
+// Package report gera o relatório JSON de uma execução do dbbackup e define os
+// códigos de saída usados por agendadores (Agendador de Tarefas, systemd, cron).
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Códigos de saída do dbbackup por classe de falha.
+const (
+	ExitOK        = 0
+	ExitFailure   = 1   // Falha sem classe específica
+	ExitConfig    = 2   // Flags ou configuração inválidas
+	ExitConnect   = 3   // Falha ao conectar ao servidor
+	ExitBackup    = 4   // Falha no comando BACKUP
+	ExitArchive   = 5   // Falha ao ler o .bak ou gravar o arquivo final
+	ExitRename    = 6   // Falha ao publicar o arquivo final (manifesto, rename)
+	ExitHook      = 7   // Hook com -hook-fatal falhou
+	ExitVerify    = 8   // RESTORE VERIFYONLY rejeitou o backup
+	ExitLocked    = 9   // Outra execução do mesmo banco em andamento (-lock-mode fail ou wait)
+	ExitPreflight = 10  // Verificação prévia falhou (ex: espaço em disco insuficiente)
+	ExitCanceled  = 130 // Interrompido por SIGINT/SIGTERM
+)
+
+// Fases de uma execução, na ordem em que ocorrem.
+const (
+	PhaseConnect   = "connect"
+	PhaseLock      = "lock"
+	PhasePreflight = "preflight"
+	PhasePreHooks  = "pre-hooks"
+	PhaseBackup    = "backup"
+	PhaseVerify    = "verify"
+	PhaseArchive   = "archive"
+	PhaseFinalize  = "finalize"
+	PhaseRetention = "retention"
+	PhasePostHooks = "post-hooks"
+)
+
+// Status de uma fase ou da execução.
+const (
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+	StatusSkipped = "skipped"
+)
+
+// ExitCodeFor retorna o código de saída de uma falha na fase informada.
+func ExitCodeFor(phase string) int {
+	switch phase {
+	case PhaseConnect:
+		return ExitConnect
+	case PhaseLock:
+		return ExitLocked
+	case PhasePreflight:
+		return ExitPreflight
+	case PhasePreHooks, PhasePostHooks:
+		return ExitHook
+	case PhaseBackup:
+		return ExitBackup
+	case PhaseVerify:
+		return ExitVerify
+	case PhaseArchive:
+		return ExitArchive
+	case PhaseFinalize:
+		return ExitRename
+	default:
+		return ExitFailure
+	}
+}
+
+// Phase registra o resultado de uma fase.
+type Phase struct {
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	Start      time.Time `json:"start"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Report é o relatório de uma execução do dbbackup.
+type Report struct {
+	ToolVersion string    `json:"tool_version"`
+	Server      string    `json:"server"`
+	Database    string    `json:"database"`
+	Status      string    `json:"status"`
+	ExitCode    int       `json:"exit_code"`
+	FailedPhase string    `json:"failed_phase,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	SkipReason  string    `json:"skip_reason,omitempty"`
+	Canceled    bool      `json:"canceled,omitempty"`
+	Start       time.Time `json:"start"`
+	Finish      time.Time `json:"finish"`
+	DurationMS  int64     `json:"duration_ms"`
+	Phases      []*Phase  `json:"phases"`
+
+	EstimatedSize int64    `json:"estimated_size,omitempty"`
+	BakSize       int64    `json:"bak_size,omitempty"`
+	ArchivePath   string   `json:"archive_path,omitempty"`
+	ArchiveSize   int64    `json:"archive_size,omitempty"`
+	ArchiveSHA256 string   `json:"archive_sha256,omitempty"`
+	VolumeCount   int      `json:"volume_count,omitempty"`
+	RemoteID      string   `json:"remote_id,omitempty"` // Arquivo enviado no modo -stream
+	HookErrors    []string `json:"hook_errors,omitempty"`
+	Warnings      []string `json:"warnings,omitempty"`
+
+	// Redact mascara segredos (ex: senhas) nos textos de erro e avisos antes de
+	// registrá-los; opcional.
+	Redact func(string) string `json:"-"`
+
+	mu      sync.Mutex
+	current *Phase
+}
+
+// New inicia o relatório de uma execução.
+func New(toolVersion, server, database string) *Report {
+	return &Report{
+		ToolVersion: toolVersion,
+		Server:      server,
+		Database:    database,
+		Status:      StatusRunning,
+		Start:       time.Now(),
+	}
+}
+
+// StartPhase encerra com sucesso a fase em andamento (se houver) e inicia name.
+func (r *Report) StartPhase(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endCurrent(nil)
+	r.current = &Phase{Name: name, Status: StatusRunning, Start: time.Now()}
+	r.Phases = append(r.Phases, r.current)
+}
+
+// CurrentPhase retorna o nome da fase em andamento.
+func (r *Report) CurrentPhase() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current == nil {
+		return ""
+	}
+	return r.current.Name
+}
+
+// AddHookError registra uma falha de hook que não interrompeu a execução.
+func (r *Report) AddHookError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.HookErrors = append(r.HookErrors, r.redact(err.Error()))
+}
+
+// AddWarning registra um problema que não interrompeu a execução (ex: falha
+// ao remover backups antigos).
+func (r *Report) AddWarning(msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, r.redact(msg))
+}
+
+// Fail marca a fase em andamento e a execução como falhas e define o código de
+// saída. Com canceled, o código é ExitCanceled independentemente da fase.
+func (r *Report) Fail(err error, canceled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Status = StatusFailure
+	r.Error = r.redact(err.Error())
+	r.Canceled = canceled
+	r.ExitCode = ExitFailure
+	if r.current != nil {
+		r.FailedPhase = r.current.Name
+		r.ExitCode = ExitCodeFor(r.current.Name)
+	}
+	if canceled {
+		r.ExitCode = ExitCanceled
+	}
+	r.endCurrent(err)
+	r.finish()
+}
+
+// Succeed encerra a fase em andamento e marca a execução como bem-sucedida.
+func (r *Report) Succeed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endCurrent(nil)
+	r.Status = StatusSuccess
+	r.ExitCode = ExitOK
+	r.finish()
+}
+
+// Skip encerra a execução sem backup e sem erro (ex: outra execução em
+// andamento com -lock-mode skip). A fase em andamento é marcada como ignorada.
+func (r *Report) Skip(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current != nil {
+		r.endCurrent(nil)
+		r.Phases[len(r.Phases)-1].Status = StatusSkipped
+	}
+	r.Status = StatusSkipped
+	r.SkipReason = r.redact(reason)
+	r.ExitCode = ExitOK
+	r.finish()
+}
+
+func (r *Report) endCurrent(err error) {
+	if r.current == nil {
+		return
+	}
+	r.current.DurationMS = time.Since(r.current.Start).Milliseconds()
+	r.current.Status = StatusSuccess
+	if err != nil {
+		r.current.Status = StatusFailure
+		r.current.Error = r.redact(err.Error())
+	}
+	r.current = nil
+}
+
+func (r *Report) redact(s string) string {
+	if r.Redact == nil {
+		return s
+	}
+	return r.Redact(s)
+}
+
+func (r *Report) finish() {
+	r.Finish = time.Now()
+	r.DurationMS = r.Finish.Sub(r.Start).Milliseconds()
+}
+
+// Marshal serializa o relatório em JSON indentado.
+func (r *Report) Marshal() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// Write grava o relatório em path, ou na saída padrão se path for "-". O
+// arquivo é gravado com nome temporário e renomeado, para que leitores nunca
+// vejam um relatório incompleto.
+func (r *Report) Write(path string, stdout io.Writer) error {
+	data, err := r.Marshal()
+	if err != nil {
+		return fmt.Errorf("serializar relatório falhou: %w", err)
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err := stdout.Write(data)
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("criar diretório do relatório falhou: %w", err)
+	}
+	// Temporário exclusivo: execuções simultâneas podem gravar no mesmo diretório
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("criar arquivo temporário do relatório falhou: %w", err)
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0640)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("gravar relatório %s falhou: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("renomear relatório para %s falhou: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_Success(t *testing.T) {
+	r := New("v1.2.3", "sqlprod01", "SCM")
+	r.StartPhase(PhaseConnect)
+	r.StartPhase(PhaseBackup)
+	r.StartPhase(PhaseArchive)
+	r.ArchiveSize = 1024
+	r.Succeed()
+
+	assert.Equal(t, StatusSuccess, r.Status)
+	assert.Equal(t, ExitOK, r.ExitCode)
+	require.Len(t, r.Phases, 3)
+	for _, p := range r.Phases {
+		assert.Equal(t, StatusSuccess, p.Status, p.Name)
+	}
+	assert.False(t, r.Finish.IsZero())
+}
+
+func TestReport_FailureExitCodes(t *testing.T) {
+	tests := []struct {
+		phase string
+		code  int
+	}{
+		{PhaseConnect, ExitConnect},
+		{PhaseLock, ExitLocked},
+		{PhasePreflight, ExitPreflight},
+		{PhasePreHooks, ExitHook},
+		{PhaseBackup, ExitBackup},
+		{PhaseArchive, ExitArchive},
+		{PhaseVerify, ExitVerify},
+		{PhaseFinalize, ExitRename},
+		{"", ExitFailure},
+	}
+
+	for _, tt := range tests {
+		r := New("dev", "sqlprod01", "SCM")
+		if tt.phase != "" {
+			r.StartPhase(tt.phase)
+		}
+		r.Fail(errors.New("falhou"), false)
+
+		assert.Equal(t, StatusFailure, r.Status, tt.phase)
+		assert.Equal(t, tt.code, r.ExitCode, tt.phase)
+		assert.Equal(t, tt.phase, r.FailedPhase)
+		assert.Equal(t, "falhou", r.Error)
+	}
+}
+
+func TestReport_Canceled(t *testing.T) {
+	r := New("dev", "sqlprod01", "SCM")
+	r.StartPhase(PhaseConnect)
+	r.StartPhase(PhaseBackup)
+	r.Fail(errors.New("context canceled"), true)
+
+	assert.Equal(t, ExitCanceled, r.ExitCode)
+	assert.True(t, r.Canceled)
+	assert.Equal(t, PhaseBackup, r.FailedPhase)
+	assert.Equal(t, StatusSuccess, r.Phases[0].Status)
+	assert.Equal(t, StatusFailure, r.Phases[1].Status)
+	assert.Equal(t, "context canceled", r.Phases[1].Error)
+}
+
+func TestReport_Skip(t *testing.T) {
+	r := New("dev", "sqlprod01", "SCM")
+	r.StartPhase(PhaseConnect)
+	r.StartPhase(PhaseLock)
+	r.Skip("outra execução em andamento")
+
+	assert.Equal(t, StatusSkipped, r.Status)
+	assert.Equal(t, ExitOK, r.ExitCode)
+	assert.Equal(t, "outra execução em andamento", r.SkipReason)
+	assert.Empty(t, r.FailedPhase)
+	assert.Equal(t, StatusSuccess, r.Phases[0].Status)
+	assert.Equal(t, StatusSkipped, r.Phases[1].Status)
+}
+
+func TestReport_Write(t *testing.T) {
+	r := New("dev", "sqlprod01", "SCM")
+	r.StartPhase(PhaseConnect)
+	r.AddHookError(errors.New("hook pre: exit status 1"))
+	r.Succeed()
+
+	path := filepath.Join(t.TempDir(), "relatorios", "run.json")
+	require.NoError(t, r.Write(path, nil))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "success", decoded["status"])
+	assert.Equal(t, "SCM", decoded["database"])
+	assert.Len(t, decoded["phases"], 1)
+	assert.Len(t, decoded["hook_errors"], 1)
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "nenhum temporário deve sobrar")
+	assert.Equal(t, "run.json", entries[0].Name())
+
+	var stdout bytes.Buffer
+	require.NoError(t, r.Write("-", &stdout))
+	assert.JSONEq(t, string(data), stdout.String())
+}
+
+func TestReport_Redact(t *testing.T) {
+	r := New("dev", "sqlprod01", "SCM")
+	r.Redact = func(s string) string { return strings.ReplaceAll(s, "s3gr3d0", "[REDACTED]") }
+	r.StartPhase(PhaseConnect)
+	r.AddHookError(errors.New("hook pre: senha s3gr3d0"))
+	r.AddWarning("aviso com s3gr3d0")
+	r.Fail(errors.New("login failed: s3gr3d0"), false)
+
+	path := filepath.Join(t.TempDir(), "run.json")
+	require.NoError(t, r.Write(path, nil))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3gr3d0")
+	assert.Equal(t, "login failed: [REDACTED]", r.Error)
+	assert.Equal(t, "login failed: [REDACTED]", r.Phases[0].Error)
+	assert.Equal(t, []string{"hook pre: senha [REDACTED]"}, r.HookErrors)
+	assert.Equal(t, []string{"aviso com [REDACTED]"}, r.Warnings)
+}
+
+func TestReport_WriteConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := New("dev", "sqlprod01", "SCM")
+			r.Succeed()
+			errs[i] = r.Write(path, nil)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, json.Valid(data))
+}
@@ -0,0 +1,130 @@
+// Package retention remove backups antigos do diretório local do dbbackup
+// (-zip-dir), mantendo os N mais recentes e/ou os dos últimos dias.
+package retention
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tipos de backup identificados pelo sufixo do nome (ver backup.BaseName).
+const (
+	TypeFull         = "full"
+	TypeDifferential = "differential"
+	TypeLog          = "log"
+)
+
+// Policy define quais backups manter. Um backup só é removido quando não é
+// protegido por nenhum dos critérios; com ambos zerados nada é removido.
+type Policy struct {
+	KeepLast int // Backups mantidos por banco e tipo (0 = sem limite)
+	KeepDays int // Backups dos últimos n dias são sempre mantidos (0 = sem limite)
+}
+
+// Enabled informa se a política remove algum backup.
+func (p Policy) Enabled() bool {
+	return p.KeepLast > 0 || p.KeepDays > 0
+}
+
+// Set é um backup no diretório local: arquivo final (ou volumes) e manifestos.
+type Set struct {
+	Base  string // DB_timestamp, com _diff ou _log para diferenciais e logs
+	Type  string
+	Time  time.Time
+	Files []string
+}
+
+// Scan lista os backups de database em dir, do mais recente ao mais antigo.
+// Arquivos temporários (.tmp) de backups em andamento são ignorados.
+func Scan(dir, database string) ([]Set, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("listar %s falhou: %w", dir, err)
+	}
+
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(database) + `_(\d{8}_\d{6})(_diff|_log)?\.`)
+	sets := map[string]*Set{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		m := pattern.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		t, err := time.ParseInLocation("20060102_150405", m[1], time.Local)
+		if err != nil {
+			continue
+		}
+		base := database + "_" + m[1] + m[2]
+		set, ok := sets[base]
+		if !ok {
+			set = &Set{Base: base, Type: typeFromSuffix(m[2]), Time: t}
+			sets[base] = set
+		}
+		set.Files = append(set.Files, filepath.Join(dir, name))
+	}
+
+	result := make([]Set, 0, len(sets))
+	for _, set := range sets {
+		sort.Strings(set.Files)
+		result = append(result, *set)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Time.After(result[j].Time) })
+	return result, nil
+}
+
+func typeFromSuffix(suffix string) string {
+	switch suffix {
+	case "_diff":
+		return TypeDifferential
+	case "_log":
+		return TypeLog
+	default:
+		return TypeFull
+	}
+}
+
+// Expired retorna os backups de sets (ordenados do mais recente ao mais
+// antigo, como em Scan) que a política não protege. Cada tipo de backup é
+// contado separadamente, para que logs frequentes não removam os fulls.
+func Expired(sets []Set, p Policy, now time.Time) []Set {
+	if !p.Enabled() {
+		return nil
+	}
+	var expired []Set
+	seen := map[string]int{}
+	for _, set := range sets {
+		index := seen[set.Type]
+		seen[set.Type]++
+		keep := (p.KeepLast > 0 && index < p.KeepLast) ||
+			(p.KeepDays > 0 && now.Sub(set.Time) < time.Duration(p.KeepDays)*24*time.Hour)
+		if !keep {
+			expired = append(expired, set)
+		}
+	}
+	return expired
+}
+
+// Remove apaga os arquivos dos backups e retorna os arquivos removidos.
+func Remove(sets []Set) ([]string, error) {
+	var removed []string
+	var errs []error
+	for _, set := range sets {
+		for _, path := range set.Files {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+				continue
+			}
+			removed = append(removed, path)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
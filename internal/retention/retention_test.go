@@ -0,0 +1,81 @@
+package retention
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func touch(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("x"), 0640))
+	}
+}
+
+func bases(sets []Set) []string {
+	var names []string
+	for _, s := range sets {
+		names = append(names, s.Base)
+	}
+	return names
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir,
+		"SCM_20250405_020000.zip", "SCM_20250405_020000.zip.manifest.json",
+		"SCM_20250406_020000.zip.001", "SCM_20250406_020000.zip.002", "SCM_20250406_020000.zip.volumes.json",
+		"SCM_20250406_031500_log.bak.zst",
+		"SCM_20250407_020000.tmp",  // Em andamento
+		"SCM2_20250407_020000.zip", // Outro banco
+		"dbbackup-SCM.lock", "notas.txt",
+	)
+
+	sets, err := Scan(dir, "SCM")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SCM_20250406_031500_log", "SCM_20250406_020000", "SCM_20250405_020000"}, bases(sets))
+	assert.Equal(t, TypeLog, sets[0].Type)
+	assert.Len(t, sets[1].Files, 3)
+	assert.Equal(t, TypeFull, sets[2].Type)
+	assert.Equal(t, time.Date(2025, 4, 5, 2, 0, 0, 0, time.Local), sets[2].Time)
+}
+
+func TestExpired(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 4, d, 2, 0, 0, 0, time.Local) }
+	sets := []Set{
+		{Base: "log5", Type: TypeLog, Time: day(5)},
+		{Base: "full5", Type: TypeFull, Time: day(5)},
+		{Base: "log4", Type: TypeLog, Time: day(4)},
+		{Base: "full4", Type: TypeFull, Time: day(4)},
+		{Base: "full3", Type: TypeFull, Time: day(3)},
+		{Base: "full1", Type: TypeFull, Time: day(1)},
+	}
+	now := day(5).Add(time.Hour)
+
+	assert.Empty(t, Expired(sets, Policy{}, now))
+	assert.Equal(t, []string{"log4", "full4", "full3", "full1"}, bases(Expired(sets, Policy{KeepLast: 1}, now)))
+	assert.Equal(t, []string{"full3", "full1"}, bases(Expired(sets, Policy{KeepDays: 2}, now)))
+	// Os dois critérios protegem: full3 está entre os 3 últimos fulls
+	assert.Equal(t, []string{"full1"}, bases(Expired(sets, Policy{KeepLast: 3, KeepDays: 1}, now)))
+}
+
+func TestRemove(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "SCM_20250405_020000.zip", "SCM_20250405_020000.zip.manifest.json", "SCM_20250406_020000.zip")
+
+	sets, err := Scan(dir, "SCM")
+	require.NoError(t, err)
+	removed, err := Remove(Expired(sets, Policy{KeepLast: 1}, time.Now()))
+	require.NoError(t, err)
+	assert.Len(t, removed, 2)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "SCM_20250406_020000.zip", entries[0].Name())
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/mssql"
+	"github.com/robfig/cron/v3"
+)
+
+// Entry é um agendamento: um banco, um tipo de backup e uma expressão cron.
+type Entry struct {
+	Name     string `json:"name,omitempty"` // Padrão: banco-tipo
+	Database string `json:"database"`
+	Type     string `json:"type,omitempty"` // full (padrão), differential ou log
+	Schedule string `json:"schedule"`       // Expressão cron de 5 campos ou @daily, @hourly, @every 15m...
+	Jitter   string `json:"jitter,omitempty"`
+	Source   string `json:"source,omitempty"` // Arquivo ou diretório de origem (engines sqlite e files); padrão: -source
+
+	sched  cron.Schedule
+	jitter time.Duration
+}
+
+// Next retorna o próximo horário do agendamento após t.
+func (e *Entry) Next(t time.Time) time.Time {
+	return e.sched.Next(t)
+}
+
+// validate preenche os valores padrão e interpreta a expressão cron e o jitter.
+func (e *Entry) validate() error {
+	if e.Database == "" {
+		return fmt.Errorf("agendamento sem banco de dados")
+	}
+	if e.Type == "" {
+		e.Type = mssql.BackupFull
+	}
+	if !slices.Contains(mssql.BackupTypes, e.Type) {
+		return fmt.Errorf("tipo de backup inválido '%s' (use %s)", e.Type, strings.Join(mssql.BackupTypes, ", "))
+	}
+	if e.Name == "" {
+		e.Name = e.Database + "-" + e.Type
+	}
+
+	sched, err := cron.ParseStandard(e.Schedule)
+	if err != nil {
+		return fmt.Errorf("expressão cron inválida '%s': %w", e.Schedule, err)
+	}
+	e.sched = sched
+
+	if e.Jitter != "" {
+		jitter, err := time.ParseDuration(e.Jitter)
+		if err != nil || jitter < 0 {
+			return fmt.Errorf("jitter inválido '%s'", e.Jitter)
+		}
+		e.jitter = jitter
+	}
+	return nil
+}
+
+// ParseEntries decodifica e valida a lista de agendamentos em JSON. Os nomes
+// precisam ser únicos, pois identificam o agendamento no arquivo de estado.
+func ParseEntries(data []byte) ([]Entry, error) {
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decodificar agendamentos falhou: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("nenhum agendamento definido")
+	}
+
+	names := make(map[string]bool, len(entries))
+	for i := range entries {
+		if err := entries[i].validate(); err != nil {
+			return nil, fmt.Errorf("agendamento %d: %w", i+1, err)
+		}
+		if names[entries[i].Name] {
+			return nil, fmt.Errorf("agendamento %d: nome '%s' repetido", i+1, entries[i].Name)
+		}
+		names[entries[i].Name] = true
+	}
+	return entries, nil
+}
+
+// LoadFile lê os agendamentos do arquivo JSON em path.
+func LoadFile(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ler arquivo de agendamentos %s falhou: %w", path, err)
+	}
+	entries, err := ParseEntries(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
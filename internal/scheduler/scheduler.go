@@ -0,0 +1,195 @@
+// Package scheduler executa backups recorrentes segundo expressões cron, para
+// que um único processo (dbbackup serve) substitua o cron ou o Agendador de
+// Tarefas. Cada agendamento tem proteção contra sobreposição, os backups de um
+// mesmo banco rodam um de cada vez e execuções perdidas durante uma parada do
+// serviço são recuperadas na inicialização.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// JobFunc executa o backup de um agendamento.
+type JobFunc func(ctx context.Context, e Entry) error
+
+// Scheduler dispara Job para cada agendamento de Entries.
+type Scheduler struct {
+	Entries []Entry
+	Job     JobFunc
+	State   *State        // Opcional; sem estado não há recuperação de execuções perdidas
+	CatchUp bool          // Executa uma vez os agendamentos perdidos durante a parada
+	Jitter  time.Duration // Atraso aleatório máximo para agendamentos sem jitter próprio
+	Logger  *slog.Logger
+	Now     func() time.Time // Opcional
+
+	mu      sync.Mutex
+	running map[string]bool          // Agendamentos em andamento (ou aguardando o banco)
+	dbLocks map[string]chan struct{} // Um backup por banco de cada vez
+	wg      sync.WaitGroup
+}
+
+// Run dispara os agendamentos até ctx ser cancelado e então aguarda os
+// backups em andamento, que recebem o mesmo ctx e são interrompidos.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.Entries) == 0 {
+		return errors.New("nenhum agendamento definido")
+	}
+	if s.Now == nil {
+		s.Now = time.Now
+	}
+	if s.State == nil {
+		s.State, _ = LoadState("")
+	}
+	s.running = map[string]bool{}
+	s.dbLocks = map[string]chan struct{}{}
+
+	now := s.Now()
+	next := make([]time.Time, len(s.Entries))
+	for i := range s.Entries {
+		e := s.Entries[i]
+		if missed, ok := s.missed(e, now); ok {
+			s.Logger.Warn("Execução perdida durante a parada do serviço; executando agora",
+				slog.String("schedule", e.Name), slog.Time("scheduled", missed))
+			s.dispatch(ctx, e, missed)
+		}
+		next[i] = e.Next(now)
+		s.Logger.Info("Agendamento carregado",
+			slog.String("schedule", e.Name),
+			slog.String("database", e.Database),
+			slog.String("type", e.Type),
+			slog.String("cron", e.Schedule),
+			slog.Time("next_run", next[i]))
+	}
+
+	for {
+		earliest := next[0]
+		for _, t := range next[1:] {
+			if t.Before(earliest) {
+				earliest = t
+			}
+		}
+
+		timer := time.NewTimer(earliest.Sub(s.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.Logger.Info("Encerrando agendador; aguardando backups em andamento")
+			s.wg.Wait()
+			return nil
+		case <-timer.C:
+		}
+
+		now := s.Now()
+		for i := range s.Entries {
+			if next[i].After(now) {
+				continue
+			}
+			s.dispatch(ctx, s.Entries[i], next[i])
+			// A partir de now, e não de next[i], para não disparar em rajada após
+			// uma suspensão da máquina
+			next[i] = s.Entries[i].Next(now)
+			s.Logger.Debug("Próxima execução", slog.String("schedule", s.Entries[i].Name), slog.Time("next_run", next[i]))
+		}
+	}
+}
+
+// missed retorna o horário mais recente de e que deveria ter sido executado
+// entre a última execução registrada e now.
+func (s *Scheduler) missed(e Entry, now time.Time) (time.Time, bool) {
+	if !s.CatchUp {
+		return time.Time{}, false
+	}
+	last, ok := s.State.LastRun(e.Name)
+	if !ok {
+		return time.Time{}, false
+	}
+	missed := e.Next(last)
+	if missed.After(now) {
+		return time.Time{}, false
+	}
+	for n := e.Next(missed); !n.After(now); n = e.Next(n) {
+		missed = n
+	}
+	return missed, true
+}
+
+// dispatch inicia o backup de e em segundo plano, a menos que a execução
+// anterior do mesmo agendamento ainda esteja em andamento.
+func (s *Scheduler) dispatch(ctx context.Context, e Entry, scheduled time.Time) {
+	s.mu.Lock()
+	if s.running[e.Name] {
+		s.mu.Unlock()
+		s.Logger.Warn("Execução anterior ainda em andamento; execução ignorada",
+			slog.String("schedule", e.Name), slog.Time("scheduled", scheduled))
+		return
+	}
+	s.running[e.Name] = true
+	lock, ok := s.dbLocks[e.Database]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		s.dbLocks[e.Database] = lock
+	}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, e.Name)
+			s.mu.Unlock()
+		}()
+
+		if delay := s.jitter(e); delay > 0 {
+			s.Logger.Debug("Aguardando jitter", slog.String("schedule", e.Name), slog.Duration("delay", delay))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+		}
+
+		// Backups do mesmo banco não rodam em paralelo (ex: log durante o full)
+		select {
+		case lock <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		defer func() { <-lock }()
+
+		start := time.Now()
+		s.Logger.Info("Iniciando backup agendado",
+			slog.String("schedule", e.Name), slog.String("database", e.Database), slog.String("type", e.Type))
+		if err := s.Job(ctx, e); err != nil {
+			s.Logger.Error("Backup agendado falhou", slog.String("schedule", e.Name), slog.Duration("duration", time.Since(start)), slog.Any("error", err))
+		} else {
+			s.Logger.Info("Backup agendado concluído", slog.String("schedule", e.Name), slog.Duration("duration", time.Since(start)))
+		}
+
+		// Um backup interrompido pelo encerramento do serviço não conta como
+		// executado e é recuperado na próxima inicialização
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.State.Record(e.Name, scheduled); err != nil {
+			s.Logger.Error("Erro ao gravar estado do agendador", slog.Any("error", err))
+		}
+	}()
+}
+
+// jitter sorteia o atraso de uma execução de e.
+func (s *Scheduler) jitter(e Entry) time.Duration {
+	limit := s.Jitter
+	if e.jitter > 0 {
+		limit = e.jitter
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(limit)))
+}
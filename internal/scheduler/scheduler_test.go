@@ -0,0 +1,199 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// every dispara a cada intervalo fixo, abaixo da resolução de 1s do cron.
+type every time.Duration
+
+func (d every) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(d)).Add(time.Duration(d))
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestParseEntries(t *testing.T) {
+	entries, err := ParseEntries([]byte(`[
+		{"database": "SCM", "schedule": "0 2 * * *"},
+		{"database": "SCM", "type": "differential", "schedule": "@hourly", "jitter": "5m"},
+		{"name": "scm-log", "database": "SCM", "type": "log", "schedule": "*/15 * * * *"}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, "SCM-full", entries[0].Name)
+	assert.Equal(t, "full", entries[0].Type)
+	assert.Equal(t, "SCM-differential", entries[1].Name)
+	assert.Equal(t, 5*time.Minute, entries[1].jitter)
+	assert.Equal(t, "scm-log", entries[2].Name)
+
+	base := time.Date(2025, 4, 7, 16, 50, 0, 0, time.Local)
+	assert.Equal(t, time.Date(2025, 4, 8, 2, 0, 0, 0, time.Local), entries[0].Next(base))
+	assert.Equal(t, time.Date(2025, 4, 7, 17, 0, 0, 0, time.Local), entries[2].Next(base))
+}
+
+func TestParseEntries_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"vazio":         `[]`,
+		"sem banco":     `[{"schedule": "@daily"}]`,
+		"tipo inválido": `[{"database": "SCM", "type": "copy", "schedule": "@daily"}]`,
+		"cron inválido": `[{"database": "SCM", "schedule": "61 * * * *"}]`,
+		"jitter":        `[{"database": "SCM", "schedule": "@daily", "jitter": "-1m"}]`,
+		"nome repetido": `[{"database": "SCM", "schedule": "@daily"}, {"database": "SCM", "schedule": "@hourly"}]`,
+		"json inválido": `{`,
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseEntries([]byte(input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestState_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := LoadState(path)
+	require.NoError(t, err)
+	_, ok := state.LastRun("SCM-full")
+	assert.False(t, ok)
+
+	scheduled := time.Date(2025, 4, 7, 2, 0, 0, 0, time.UTC)
+	require.NoError(t, state.Record("SCM-full", scheduled))
+
+	state, err = LoadState(path)
+	require.NoError(t, err)
+	last, ok := state.LastRun("SCM-full")
+	require.True(t, ok)
+	assert.True(t, scheduled.Equal(last))
+}
+
+func TestMissed(t *testing.T) {
+	entries, err := ParseEntries([]byte(`[{"database": "SCM", "schedule": "0 2 * * *"}]`))
+	require.NoError(t, err)
+	e := entries[0]
+
+	state, _ := LoadState("")
+	s := &Scheduler{State: state, CatchUp: true}
+	now := time.Date(2025, 4, 10, 9, 0, 0, 0, time.Local)
+
+	// Sem registro anterior não há o que recuperar
+	_, ok := s.missed(e, now)
+	assert.False(t, ok)
+
+	// Serviço parado de 07/04 a 10/04: apenas a execução mais recente é recuperada
+	require.NoError(t, state.Record(e.Name, time.Date(2025, 4, 7, 2, 0, 0, 0, time.Local)))
+	missed, ok := s.missed(e, now)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2025, 4, 10, 2, 0, 0, 0, time.Local), missed)
+
+	// Em dia
+	require.NoError(t, state.Record(e.Name, missed))
+	_, ok = s.missed(e, now)
+	assert.False(t, ok)
+
+	s.CatchUp = false
+	require.NoError(t, state.Record(e.Name, time.Date(2025, 4, 7, 2, 0, 0, 0, time.Local)))
+	_, ok = s.missed(e, now)
+	assert.False(t, ok)
+}
+
+func TestRun_CatchUpAndRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := LoadState(path)
+	require.NoError(t, err)
+
+	entry := Entry{Name: "SCM-full", Database: "SCM", Type: "full", sched: every(time.Hour)}
+	require.NoError(t, state.Record(entry.Name, time.Now().Add(-3*time.Hour).Truncate(time.Hour)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs atomic.Int32
+	s := &Scheduler{
+		Entries: []Entry{entry},
+		State:   state,
+		CatchUp: true,
+		Logger:  testLogger(),
+		Job: func(ctx context.Context, e Entry) error {
+			runs.Add(1)
+			cancel()
+			return nil
+		},
+	}
+	require.NoError(t, s.Run(ctx))
+
+	assert.Equal(t, int32(1), runs.Load())
+	// Encerrado durante o backup: não é registrado e será recuperado novamente
+	state, err = LoadState(path)
+	require.NoError(t, err)
+	last, _ := state.LastRun(entry.Name)
+	assert.True(t, last.Before(time.Now().Add(-2*time.Hour)))
+}
+
+func TestRun_SkipsOverlapAndSerializesDatabase(t *testing.T) {
+	entries := []Entry{
+		{Name: "SCM-full", Database: "SCM", Type: "full", sched: every(50 * time.Millisecond)},
+		{Name: "SCM-log", Database: "SCM", Type: "log", sched: every(50 * time.Millisecond)},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
+	defer cancel()
+
+	var mu sync.Mutex
+	active := map[string]int{}
+	var maxPerEntry, maxPerDatabase int
+	var runs atomic.Int32
+	s := &Scheduler{
+		Entries: entries,
+		Logger:  testLogger(),
+		Job: func(ctx context.Context, e Entry) error {
+			runs.Add(1)
+			mu.Lock()
+			active[e.Name]++
+			active[e.Database]++
+			maxPerEntry = max(maxPerEntry, active[e.Name])
+			maxPerDatabase = max(maxPerDatabase, active[e.Database])
+			mu.Unlock()
+
+			// Mais longo que o intervalo do agendamento
+			select {
+			case <-ctx.Done():
+			case <-time.After(120 * time.Millisecond):
+			}
+
+			mu.Lock()
+			active[e.Name]--
+			active[e.Database]--
+			mu.Unlock()
+			return nil
+		},
+	}
+	require.NoError(t, s.Run(ctx))
+
+	assert.GreaterOrEqual(t, runs.Load(), int32(2))
+	assert.Equal(t, 1, maxPerEntry, "execuções do mesmo agendamento não podem se sobrepor")
+	assert.Equal(t, 1, maxPerDatabase, "backups do mesmo banco devem ser serializados")
+}
+
+func TestJitter(t *testing.T) {
+	s := &Scheduler{Jitter: time.Minute}
+	for range 100 {
+		d := s.jitter(Entry{})
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		assert.Less(t, d, time.Minute)
+	}
+	assert.Less(t, s.jitter(Entry{jitter: time.Second}), time.Second)
+	assert.Zero(t, (&Scheduler{}).jitter(Entry{}))
+}
@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// State guarda o horário agendado da última execução concluída de cada
+// agendamento, para recuperar execuções perdidas após uma parada do serviço.
+type State struct {
+	path string
+
+	mu      sync.Mutex
+	lastRun map[string]time.Time
+}
+
+// LoadState lê o estado gravado em path. Um arquivo inexistente resulta em
+// estado vazio (primeira execução do serviço).
+func LoadState(path string) (*State, error) {
+	s := &State{path: path, lastRun: map[string]time.Time{}}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ler estado do agendador %s falhou: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &s.lastRun); err != nil {
+		return nil, fmt.Errorf("decodificar estado do agendador %s falhou: %w", path, err)
+	}
+	return s, nil
+}
+
+// LastRun retorna o horário agendado da última execução de name.
+func (s *State) LastRun(name string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.lastRun[name]
+	return t, ok
+}
+
+// Record registra a execução de name e grava o estado de forma atômica
+// (arquivo temporário + rename). Sem path o estado fica apenas em memória.
+func (s *State) Record(name string, scheduled time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRun[name] = scheduled
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.lastRun, "", "  ")
+	if err != nil {
+		return fmt.Errorf("codificar estado do agendador falhou: %w", err)
+	}
+	tempPath := s.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0640); err != nil {
+		return fmt.Errorf("gravar estado do agendador %s falhou: %w", tempPath, err)
+	}
+	if err := os.Rename(tempPath, s.path); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("renomear estado do agendador para %s falhou: %w", s.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// FromFile lê um segredo do arquivo path, descartando espaços e quebras de
+// linha no final. Retorna também um aviso se o arquivo puder ser lido por
+// outros usuários (somente em sistemas Unix).
+func FromFile(path string) (value string, warning string, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", fmt.Errorf("acessar arquivo de senha %s falhou: %w", path, err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		warning = fmt.Sprintf("arquivo de senha %s tem permissões %s; recomenda-se 0600", path, info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("ler arquivo de senha %s falhou: %w", path, err)
+	}
+	value = strings.TrimRight(string(data), "\r\n\t ")
+	if value == "" {
+		return "", "", fmt.Errorf("arquivo de senha %s está vazio", path)
+	}
+	return value, warning, nil
+}
+
+// FromEnv lê um segredo da variável de ambiente name.
+func FromEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("variável de ambiente %s não foi definida", name)
+	}
+	return value, nil
+}
+
+// FromKeyring lê um segredo do chaveiro do sistema operacional (Windows
+// Credential Manager, macOS Keychain ou Secret Service no Linux).
+func FromKeyring(service, user string) (string, error) {
+	value, err := keyring.Get(service, user)
+	if err != nil {
+		return "", fmt.Errorf("ler senha de %s/%s no chaveiro do sistema falhou: %w", service, user, err)
+	}
+	return value, nil
+}
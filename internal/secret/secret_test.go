@@ -0,0 +1,53 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "senha")
+	require.NoError(t, os.WriteFile(path, []byte("s3gr3d0;x\n"), 0600))
+
+	value, warning, err := FromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "s3gr3d0;x", value)
+	assert.Empty(t, warning)
+}
+
+func TestFromFile_LoosePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissões Unix não se aplicam no Windows")
+	}
+	path := filepath.Join(t.TempDir(), "senha")
+	require.NoError(t, os.WriteFile(path, []byte("segredo"), 0644))
+
+	value, warning, err := FromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "segredo", value)
+	assert.Contains(t, warning, "0600")
+}
+
+func TestFromFile_Empty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "senha")
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0600))
+
+	_, _, err := FromFile(path)
+	assert.Error(t, err)
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("DBBACKUP_TEST_PASSWORD", "segredo")
+
+	value, err := FromEnv("DBBACKUP_TEST_PASSWORD")
+	require.NoError(t, err)
+	assert.Equal(t, "segredo", value)
+
+	_, err = FromEnv("DBBACKUP_TEST_PASSWORD_AUSENTE")
+	assert.Error(t, err)
+}
@@ -0,0 +1,191 @@
+// Package sqlite copia bancos SQLite em uso sem interromper a aplicação: a
+// cópia é consistente pela API de backup online ou por VACUUM INTO, com o
+// driver em Go puro (modernc.org/sqlite), sem CGO.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	driver "modernc.org/sqlite"
+)
+
+// Métodos de cópia (flag -sqlite-method).
+const (
+	MethodBackup = "backup" // API de backup online: cópia página a página
+	MethodVacuum = "vacuum" // VACUUM INTO: cópia compactada, sem páginas livres
+)
+
+// Methods lista os métodos aceitos.
+var Methods = []string{MethodBackup, MethodVacuum}
+
+// busyTimeout é a espera por locks de escrita de outras conexões.
+const busyTimeout = 10 * time.Second
+
+// stepPages é a quantidade de páginas copiadas por passo da API de backup.
+// Entre os passos o contexto é verificado e as escritas de outras conexões
+// podem prosseguir.
+const stepPages = 1024
+
+// URI retorna a URI de path usada pelo SQLite, com os parâmetros informados.
+func URI(path string, params url.Values) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	abs = filepath.ToSlash(abs)
+	if !strings.HasPrefix(abs, "/") {
+		// Windows: file:///C:/dados/app.db
+		abs = "/" + abs
+	}
+	u := &url.URL{Scheme: "file", Path: abs, RawQuery: params.Encode()}
+	return u.String(), nil
+}
+
+// Open abre path somente para leitura. O arquivo precisa existir.
+func Open(path string) (*sql.DB, error) {
+	uri, err := URI(path, url.Values{
+		"mode":    {"ro"},
+		"_pragma": {fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds())},
+	})
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", uri)
+	if err != nil {
+		return nil, fmt.Errorf("abrir %s falhou: %w", path, err)
+	}
+	return db, nil
+}
+
+// Version retorna a versão da biblioteca SQLite.
+func Version(ctx context.Context, db *sql.DB) (string, error) {
+	var v string
+	if err := db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&v); err != nil {
+		return "", fmt.Errorf("consultar versão do SQLite falhou: %w", err)
+	}
+	return v, nil
+}
+
+// Size retorna o tamanho do banco (páginas × tamanho da página).
+func Size(ctx context.Context, db *sql.DB) (int64, error) {
+	var pages, pageSize int64
+	if err := db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pages); err != nil {
+		return 0, fmt.Errorf("consultar tamanho do banco falhou: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("consultar tamanho do banco falhou: %w", err)
+	}
+	return pages * pageSize, nil
+}
+
+// Snapshot grava em dst uma cópia consistente do banco aberto em db. dst não
+// pode existir.
+func Snapshot(ctx context.Context, db *sql.DB, dst, method string) error {
+	if method == MethodVacuum {
+		if _, err := db.ExecContext(ctx, "VACUUM INTO ?", dst); err != nil {
+			return fmt.Errorf("VACUUM INTO falhou: %w", err)
+		}
+		return nil
+	}
+
+	dstURI, err := URI(dst, nil)
+	if err != nil {
+		return err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(dc any) error {
+		src, ok := dc.(interface {
+			NewBackup(dstURI string) (*driver.Backup, error)
+		})
+		if !ok {
+			return fmt.Errorf("driver SQLite sem suporte à API de backup")
+		}
+		b, err := src.NewBackup(dstURI)
+		if err != nil {
+			return fmt.Errorf("iniciar backup online falhou: %w", err)
+		}
+		err = step(ctx, b)
+		if finishErr := b.Finish(); err == nil && finishErr != nil {
+			err = fmt.Errorf("finalizar backup online falhou: %w", finishErr)
+		}
+		return err
+	})
+}
+
+// step copia as páginas até o fim. Com o banco ocupado por outra conexão, o
+// passo é repetido após uma pausa; uma escrita de outra conexão faz o SQLite
+// reiniciar a cópia, que termina quando não houver escrita durante ela.
+func step(ctx context.Context, b *driver.Backup) error {
+	for {
+		more, err := b.Step(stepPages)
+		if err != nil && !busy(err) {
+			return fmt.Errorf("backup online falhou: %w", err)
+		}
+		if err == nil && !more {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+}
+
+// busy indica os erros SQLITE_BUSY e SQLITE_LOCKED, que são temporários.
+func busy(err error) bool {
+	var sqliteErr *driver.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code() & 0xff
+	return code == 5 || code == 6
+}
+
+// IntegrityCheck executa PRAGMA integrity_check no banco em path.
+func IntegrityCheck(ctx context.Context, path string) error {
+	db, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("integrity_check falhou: %w", err)
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return fmt.Errorf("integrity_check falhou: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("integrity_check falhou: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity_check encontrou %d problemas: %s", len(problems), strings.Join(problems[:min(len(problems), 5)], " | "))
+	}
+	return nil
+}
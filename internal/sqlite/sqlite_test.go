@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDatabase cria um banco com uma tabela e n linhas.
+func newDatabase(t *testing.T, n int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.db")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE pacientes (id INTEGER PRIMARY KEY, nome TEXT)")
+	require.NoError(t, err)
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	for i := range n {
+		_, err = tx.Exec("INSERT INTO pacientes (nome) VALUES (?)", strings.Repeat("x", 100)+string(rune('a'+i%26)))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit())
+	return path
+}
+
+func countRows(t *testing.T, path string) int {
+	t.Helper()
+	db, err := Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM pacientes").Scan(&n))
+	return n
+}
+
+func TestURI(t *testing.T) {
+	uri, err := URI("/dados/clínica app.db", url.Values{"mode": {"ro"}})
+	require.NoError(t, err)
+	if filepath.Separator == '/' {
+		assert.Equal(t, "file:///dados/cl%C3%ADnica%20app.db?mode=ro", uri)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	src := newDatabase(t, 5000)
+
+	for _, method := range Methods {
+		t.Run(method, func(t *testing.T) {
+			db, err := Open(src)
+			require.NoError(t, err)
+			defer db.Close()
+
+			size, err := Size(context.Background(), db)
+			require.NoError(t, err)
+			assert.Greater(t, size, int64(500*1000))
+			version, err := Version(context.Background(), db)
+			require.NoError(t, err)
+			assert.NotEmpty(t, version)
+
+			dst := filepath.Join(t.TempDir(), "snapshot.db")
+			require.NoError(t, Snapshot(context.Background(), db, dst, method))
+			assert.Equal(t, 5000, countRows(t, dst))
+			require.NoError(t, IntegrityCheck(context.Background(), dst))
+		})
+	}
+}
+
+func TestSnapshot_Canceled(t *testing.T) {
+	db, err := Open(newDatabase(t, 5000))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, Snapshot(ctx, db, filepath.Join(t.TempDir(), "snapshot.db"), MethodBackup))
+}
+
+func TestOpen_Missing(t *testing.T) {
+	// Somente leitura: um arquivo inexistente não é criado
+	path := filepath.Join(t.TempDir(), "inexistente.db")
+	db, err := Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Error(t, db.Ping())
+	assert.NoFileExists(t, path)
+}
+
+func TestIntegrityCheck_Corrupt(t *testing.T) {
+	path := newDatabase(t, 5000)
+	// Sobrescreve páginas de dados (a partir da terceira)
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte(strings.Repeat("\xff", 8192)), 3*4096)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assert.Error(t, IntegrityCheck(context.Background(), path))
+}
@@ -0,0 +1,5 @@
+package version
+
+// Version é a versão das ferramentas, definida no build via
+// -ldflags "-X github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/version.Version=v1.2.3".
+var Version = "dev"
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package watcher
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileID retorna o inode do arquivo.
+func fileID(info fs.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
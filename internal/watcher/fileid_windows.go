@@ -0,0 +1,12 @@
+//go:build windows
+
+package watcher
+
+import "io/fs"
+
+// fileID retorna 0: no Windows, o índice do arquivo só é obtido abrindo cada
+// arquivo, o que pesaria a cada listagem de um compartilhamento de rede. O
+// modo poll usa o tamanho e a data de modificação.
+func fileID(info fs.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,85 @@
+package watcher
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/diskspace"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/filetree"
+)
+
+// Filter seleciona arquivos pelos padrões de filetree (glob, ou expressão
+// regular com o prefixo "re:"), comparados com o caminho relativo, e pelo
+// tamanho do backup. Um diretório excluído exclui todos os arquivos dentro dele.
+type Filter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	MinSize string   `json:"min_size,omitempty"` // ex: 1M; vazio = sem mínimo
+	MaxSize string   `json:"max_size,omitempty"` // ex: 50G; vazio = sem máximo
+
+	matcher  *filetree.Matcher
+	min, max int64
+}
+
+// Compile compila os padrões e interpreta os tamanhos. Deve ser chamado antes
+// de Match e MatchSize; um Filter não compilado seleciona todos os arquivos.
+func (f *Filter) Compile() error {
+	m, err := filetree.NewMatcher(f.Include, f.Exclude)
+	if err != nil {
+		return err
+	}
+	f.matcher = m
+
+	if f.MinSize != "" {
+		if f.min, err = diskspace.ParseSize(f.MinSize); err != nil {
+			return fmt.Errorf("min_size: %w", err)
+		}
+	}
+	if f.MaxSize != "" {
+		if f.max, err = diskspace.ParseSize(f.MaxSize); err != nil {
+			return fmt.Errorf("max_size: %w", err)
+		}
+	}
+	if f.max > 0 && f.min > f.max {
+		return fmt.Errorf("min_size (%s) maior que max_size (%s)", f.MinSize, f.MaxSize)
+	}
+	return nil
+}
+
+// Excluded indica se o caminho relativo rel, ou um dos diretórios acima dele,
+// é excluído.
+func (f *Filter) Excluded(rel string) bool {
+	if f.matcher == nil {
+		return false
+	}
+	for p := rel; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		if f.matcher.Excluded(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match indica se o arquivo de caminho relativo rel é selecionado pelos padrões.
+func (f *Filter) Match(rel string) bool {
+	if f.matcher == nil {
+		return true
+	}
+	return !f.Excluded(rel) && f.matcher.Match(rel)
+}
+
+// MatchSize indica se size está dentro dos limites do filtro.
+func (f *Filter) MatchSize(size int64) bool {
+	return size >= f.min && (f.max == 0 || size <= f.max)
+}
+
+// patternPath retorna o caminho comparado com os padrões: para o manifesto de
+// um conjunto de volumes, o do arquivo lógico (ex: DB.zip em vez de
+// DB.zip.volumes.json), para que *.zip também selecione backups divididos.
+func patternPath(rel string) string {
+	if archive.IsVolumeManifest(rel) {
+		return rel[:len(rel)-len(archive.VolumeManifestSuffix)]
+	}
+	return rel
+}
@@ -0,0 +1,60 @@
+package watcher
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelected_Default(t *testing.T) {
+	fw := NewFolderWatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, t.TempDir(), Options{})
+
+	assert.True(t, fw.selected("SCM_20250407_164500.zip"))
+	assert.True(t, fw.selected("clinicaA/SCM.bak.zst"))
+	assert.True(t, fw.selected("SCM.zip.volumes.json"))
+	assert.False(t, fw.selected("SCM.zip.001"))
+	assert.False(t, fw.selected("SCM.zip.manifest.json"))
+	assert.False(t, fw.selected("SCM.7z"))
+	assert.False(t, fw.selected("dbbackup-SCM.lock"))
+}
+
+func TestSelected_Patterns(t *testing.T) {
+	filter := Filter{Include: []string{"*.zip", "*.7z", "*.bak.zst"}, Exclude: []string{"*.tmp", "~$*", "rascunhos"}}
+	require.NoError(t, filter.Compile())
+	fw := NewFolderWatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, t.TempDir(), Options{Filter: filter})
+
+	assert.True(t, fw.selected("SCM.7z"))
+	assert.True(t, fw.selected("a/SCM.bak.zst"))
+	assert.True(t, fw.selected("SCM.zip.volumes.json"), "conjunto de volumes de um .zip")
+	assert.False(t, fw.selected("SCM.tar.gz"))
+	assert.False(t, fw.selected("~$SCM.zip"))
+	assert.False(t, fw.selected("rascunhos/2025/SCM.zip"), "diretório excluído")
+	assert.False(t, fw.selected("SCM.zip.001"))
+	assert.False(t, fw.selected("dbbackup-SCM.lock.break"), "arquivos de lock nunca são enviados")
+}
+
+func TestSelected_DefaultWithExclude(t *testing.T) {
+	filter := Filter{Exclude: []string{`re:(^|/)teste_[^/]*$`}}
+	require.NoError(t, filter.Compile())
+	fw := NewFolderWatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, t.TempDir(), Options{Filter: filter})
+
+	assert.True(t, fw.selected("SCM.zip"))
+	assert.False(t, fw.selected("a/teste_SCM.zip"))
+	assert.False(t, fw.selected("notas.txt"), "sem -include, apenas as extensões do dbbackup")
+}
+
+func TestFilter_MatchSize(t *testing.T) {
+	filter := Filter{MinSize: "1K", MaxSize: "1M"}
+	require.NoError(t, filter.Compile())
+	assert.False(t, filter.MatchSize(1023))
+	assert.True(t, filter.MatchSize(1024))
+	assert.True(t, filter.MatchSize(1<<20))
+	assert.False(t, filter.MatchSize(1<<20+1))
+
+	var none Filter
+	assert.True(t, none.MatchSize(0))
+	assert.True(t, none.Match("qualquer/arquivo"))
+}
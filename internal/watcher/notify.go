@@ -0,0 +1,150 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// notifySource recebe os eventos de criação do sistema operacional
+// (inotify, ReadDirectoryChangesW, kqueue) pelo fsnotify.
+type notifySource struct {
+	logger    *slog.Logger
+	dir       string
+	recursive bool
+}
+
+// Run adiciona os watches e informa os arquivos criados até ctx ser cancelado.
+func (ns *notifySource) Run(ctx context.Context, emit func(path string)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		ns.logger.Error("Falha ao criar watcher fsnotify", slog.Any("error", err))
+		return fmt.Errorf("criar watcher falhou: %w", err)
+	}
+	defer func() {
+		ns.logger.Debug("Fechando watcher fsnotify...")
+		if err := watcher.Close(); err != nil {
+			ns.logger.Error("Erro ao fechar watcher fsnotify", slog.Any("error", err))
+		} else {
+			ns.logger.Info("Watcher fsnotify fechado.")
+		}
+	}()
+
+	eventLoopDone := make(chan struct{})
+	go func() {
+		defer close(eventLoopDone)
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					ns.logger.Info("Canal de eventos do watcher fechado.")
+					return
+				}
+				// Usar event.Has() é mais robusto para operações combinadas
+				// Vamos focar na CRIAÇÃO de arquivos de backup compactados
+				if event.Has(fsnotify.Create) { // Verificar evento de CRIAÇÃO
+					if ns.recursive && isDir(event.Name) {
+						ns.watchNewDir(watcher, event.Name, emit)
+						continue
+					}
+					emit(event.Name)
+				} else {
+					ns.logger.Debug("Evento fsnotify ignorado (não é Create)", slog.String("path", event.Name), slog.String("op", event.Op.String()))
+				}
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					ns.logger.Info("Canal de erros do watcher fechado.")
+					return
+				}
+				ns.logger.Error("Erro no watcher fsnotify", slog.Any("error", err))
+				// Poderia ter lógica para tentar re-adicionar o watch ou parar dependendo do erro
+
+			case <-ctx.Done():
+				ns.logger.Info("Recebido sinal de cancelamento. Encerrando loop de eventos do watcher.")
+				return
+			}
+		}
+	}()
+
+	ns.logger.Debug("Adicionando diretório ao watcher", slog.String("directory", ns.dir))
+	err = watcher.Add(ns.dir)
+	if err != nil {
+		ns.logger.Error("Falha ao adicionar diretório ao watcher", slog.String("directory", ns.dir), slog.Any("error", err))
+		// Não precisa cancelar o contexto aqui, o retorno do erro fará main sair
+		return fmt.Errorf("adicionar %s ao watcher falhou: %w", ns.dir, err)
+	}
+	if ns.recursive {
+		// Os arquivos já existentes não são enviados, como no diretório raiz
+		ns.addSubdirs(watcher, ns.dir)
+	}
+	ns.logger.Info("Monitoramento iniciado com sucesso.", slog.String("directory", ns.dir), slog.Bool("recursive", ns.recursive))
+
+	// Aguarda o contexto ser cancelado (shutdown) ou o loop de eventos terminar
+	select {
+	case <-ctx.Done():
+		ns.logger.Info("Sinal de shutdown recebido. Aguardando loop de eventos terminar...")
+	case <-eventLoopDone:
+		// Isso não deveria acontecer a menos que os canais do watcher fechem inesperadamente
+		ns.logger.Warn("Loop de eventos do watcher terminou inesperadamente.")
+	}
+
+	// Espera a goroutine do loop realmente terminar antes de retornar
+	<-eventLoopDone
+	ns.logger.Info("Processo de monitoramento finalizado.")
+	// Retorna o erro do contexto se foi cancelado, ou nil se saiu de outra forma (improvável)
+	return ctx.Err()
+}
+
+// addSubdirs adiciona ao watcher os subdiretórios de dir, em qualquer nível, e
+// retorna os arquivos encontrados neles. Falhas em um subdiretório são
+// registradas sem interromper o monitoramento dos demais.
+func (ns *notifySource) addSubdirs(w *fsnotify.Watcher, dir string) []string {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			ns.logger.Warn("Falha ao percorrer subdiretório", slog.String("directory", p), slog.Any("error", err))
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			files = append(files, p)
+			return nil
+		}
+		if p == dir && dir == ns.dir {
+			return nil // Já adicionado por Run
+		}
+		if err := w.Add(p); err != nil {
+			ns.logger.Warn("Falha ao adicionar subdiretório ao watcher", slog.String("directory", p), slog.Any("error", err))
+			return filepath.SkipDir
+		}
+		ns.logger.Debug("Subdiretório adicionado ao watcher", slog.String("directory", p))
+		return nil
+	})
+	if err != nil {
+		ns.logger.Warn("Falha ao percorrer subdiretórios", slog.String("directory", dir), slog.Any("error", err))
+	}
+	return files
+}
+
+// watchNewDir passa a monitorar um diretório criado (ou movido) dentro de
+// WatchDir. Os arquivos gravados nele antes do watch ser adicionado não geram
+// eventos, então são informados aqui.
+func (ns *notifySource) watchNewDir(w *fsnotify.Watcher, dir string, emit func(path string)) {
+	ns.logger.Info("Novo subdiretório detectado", slog.String("directory", dir))
+	for _, f := range ns.addSubdirs(w, dir) {
+		emit(f)
+	}
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,152 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"time"
+)
+
+// DefaultPollInterval é o intervalo entre as listagens no modo poll.
+const DefaultPollInterval = 30 * time.Second
+
+// pollSource lista o diretório periodicamente. É usado em compartilhamentos de
+// rede (SMB/NFS), onde o fsnotify não recebe os eventos dos arquivos gravados
+// por outras máquinas. Um arquivo é informado quando aparece ou muda (tamanho,
+// data de modificação ou inode) e depois fica uma listagem inteira sem mudar,
+// ou seja, quando terminou de ser gravado.
+type pollSource struct {
+	logger    *slog.Logger
+	dir       string
+	recursive bool
+	interval  time.Duration
+	files     map[string]*pollState
+}
+
+// fileSig identifica uma versão de um arquivo entre duas listagens.
+type fileSig struct {
+	size  int64
+	mtime int64
+	id    uint64 // Inode; 0 onde não está disponível (Windows)
+}
+
+type pollState struct {
+	sig     fileSig
+	emitted bool
+}
+
+func newPollSource(logger *slog.Logger, dir string, recursive bool, interval time.Duration) *pollSource {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	return &pollSource{
+		logger:    logger,
+		dir:       dir,
+		recursive: recursive,
+		interval:  interval,
+		files:     make(map[string]*pollState),
+	}
+}
+
+// Run lista o diretório a cada intervalo até ctx ser cancelado. A primeira
+// listagem só registra os arquivos existentes.
+func (ps *pollSource) Run(ctx context.Context, emit func(path string)) error {
+	if err := ps.baseline(); err != nil {
+		ps.logger.Error("Falha ao listar diretório monitorado", slog.String("directory", ps.dir), slog.Any("error", err))
+		return err
+	}
+	ps.logger.Info("Monitoramento por listagem iniciado com sucesso.",
+		slog.String("directory", ps.dir),
+		slog.Duration("interval", ps.interval),
+		slog.Bool("recursive", ps.recursive),
+		slog.Int("files", len(ps.files)))
+
+	ticker := time.NewTicker(ps.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			ps.logger.Info("Processo de monitoramento finalizado.")
+			return ctx.Err()
+		case <-ticker.C:
+			ps.poll(emit)
+		}
+	}
+}
+
+// baseline registra os arquivos existentes, que não são informados.
+func (ps *pollSource) baseline() error {
+	files, err := ps.scan()
+	if err != nil {
+		return fmt.Errorf("listar %s falhou: %w", ps.dir, err)
+	}
+	for p, sig := range files {
+		ps.files[p] = &pollState{sig: sig, emitted: true}
+	}
+	return nil
+}
+
+// poll compara uma nova listagem com a anterior e informa os arquivos novos
+// ou alterados que não mudaram desde a listagem anterior.
+func (ps *pollSource) poll(emit func(path string)) {
+	files, err := ps.scan()
+	if err != nil {
+		// Compartilhamento indisponível: mantém o estado, para não tratar
+		// todos os arquivos como novos quando ele voltar
+		ps.logger.Warn("Falha ao listar diretório monitorado; nova tentativa na próxima listagem", slog.String("directory", ps.dir), slog.Any("error", err))
+		return
+	}
+
+	for p := range ps.files {
+		if _, ok := files[p]; !ok {
+			delete(ps.files, p)
+		}
+	}
+	for p, sig := range files {
+		st, ok := ps.files[p]
+		switch {
+		case !ok || st.sig != sig:
+			ps.logger.Debug("Arquivo novo ou alterado; aguardando a próxima listagem", slog.String("path", p), slog.Int64("size", sig.size))
+			ps.files[p] = &pollState{sig: sig}
+		case !st.emitted:
+			st.emitted = true
+			emit(p)
+		}
+	}
+}
+
+// scan lista os arquivos regulares do diretório (e dos subdiretórios, se
+// recursivo).
+func (ps *pollSource) scan() (map[string]fileSig, error) {
+	files := make(map[string]fileSig)
+	err := filepath.WalkDir(ps.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p != ps.dir && errors.Is(err, fs.ErrNotExist) {
+				return nil // Removido durante a listagem
+			}
+			return err
+		}
+		if d.IsDir() {
+			if p != ps.dir && !ps.recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		files[p] = fileSig{size: info.Size(), mtime: info.ModTime().UnixNano(), id: fileID(info)}
+		return nil
+	})
+	return files, err
+}
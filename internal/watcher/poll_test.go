@@ -0,0 +1,94 @@
+package watcher
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollSource(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "antigo.zip")
+	require.NoError(t, os.WriteFile(existing, []byte("zip"), 0640))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0750))
+
+	ps := newPollSource(slog.New(slog.NewTextHandler(io.Discard, nil)), dir, false, time.Second)
+	require.NoError(t, ps.baseline())
+
+	var emitted []string
+	poll := func() []string {
+		emitted = nil
+		ps.poll(func(path string) { emitted = append(emitted, path) })
+		return emitted
+	}
+
+	// Arquivos existentes no início não são informados
+	assert.Empty(t, poll())
+
+	// Novo arquivo: informado depois de uma listagem sem mudanças
+	file := filepath.Join(dir, "SCM.zip")
+	require.NoError(t, os.WriteFile(file, []byte("parte"), 0640))
+	assert.Empty(t, poll())
+	require.NoError(t, os.WriteFile(file, []byte("parte e resto"), 0640))
+	assert.Empty(t, poll(), "ainda em gravação")
+	assert.Equal(t, []string{file}, poll())
+	assert.Empty(t, poll(), "informado uma única vez")
+
+	// Substituído por outro arquivo: alteração detectada
+	replacement := filepath.Join(dir, "novo.tmp")
+	require.NoError(t, os.WriteFile(replacement, []byte("outro conteúdo"), 0640))
+	require.NoError(t, os.Rename(replacement, file))
+	assert.Empty(t, poll())
+	assert.Equal(t, []string{file}, poll())
+
+	// Sem -recursive, subdiretórios são ignorados
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "B.zip"), []byte("zip"), 0640))
+	assert.Empty(t, poll())
+	assert.Empty(t, poll())
+}
+
+func TestPollSource_Recursive(t *testing.T) {
+	dir := t.TempDir()
+	ps := newPollSource(slog.New(slog.NewTextHandler(io.Discard, nil)), dir, true, time.Second)
+	require.NoError(t, ps.baseline())
+
+	file := filepath.Join(dir, "clinicaA", "2025", "SCM.zip")
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0750))
+	require.NoError(t, os.WriteFile(file, []byte("zip"), 0640))
+
+	var emitted []string
+	emit := func(path string) { emitted = append(emitted, path) }
+	ps.poll(emit)
+	ps.poll(emit)
+	assert.Equal(t, []string{file}, emitted)
+}
+
+func TestPollSource_ListFailureKeepsState(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "compartilhamento")
+	require.NoError(t, os.Mkdir(dir, 0750))
+	file := filepath.Join(dir, "SCM.zip")
+	require.NoError(t, os.WriteFile(file, []byte("zip"), 0640))
+
+	ps := newPollSource(slog.New(slog.NewTextHandler(io.Discard, nil)), dir, false, time.Second)
+	require.NoError(t, ps.baseline())
+
+	// Compartilhamento indisponível e depois de volta: o arquivo não é tratado como novo
+	hidden := filepath.Join(parent, "desmontado")
+	require.NoError(t, os.Rename(dir, hidden))
+	var emitted []string
+	emit := func(path string) { emitted = append(emitted, path) }
+	ps.poll(emit)
+	require.NoError(t, os.Rename(hidden, dir))
+	ps.poll(emit)
+	ps.poll(emit)
+	assert.Empty(t, emitted)
+
+	assert.Error(t, newPollSource(slog.New(slog.NewTextHandler(io.Discard, nil)), filepath.Join(parent, "inexistente"), false, 0).baseline())
+}
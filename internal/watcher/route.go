@@ -0,0 +1,110 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Route direciona os backups de um subdiretório de WatchDir, ou os
+// selecionados pelo filtro da rota, para um destino próprio, permitindo que um
+// uploader atenda a vários produtores de backup.
+type Route struct {
+	Dir             string `json:"dir,omitempty"` // Subdiretório relativo a WatchDir (ex: clinicaA ou regional/clinicaB); vazio = todos
+	Filter                 // Padrões (relativos a Dir) e tamanhos dos backups da rota; vazio = todos
+	Folder          string `json:"folder,omitempty"`           // Pasta do Google Drive que recebe as pastas diárias; padrão: raiz
+	CredentialsFile string `json:"credentials_file,omitempty"` // Outra conta do Google Drive; padrão: -credentials-file
+	TokenFile       string `json:"token_file,omitempty"`       // Token da outra conta; obrigatório com credentials_file
+
+	// Uploader é o destino da rota, criado a partir dos campos acima pela main.
+	Uploader Uploader `json:"-"`
+}
+
+// String identifica a rota nos logs e nos erros.
+func (r *Route) String() string {
+	if r.Dir == "" {
+		return "."
+	}
+	return r.Dir
+}
+
+// validate normaliza Dir, compila o filtro e confere os campos da rota.
+func (r *Route) validate() error {
+	dir := strings.TrimSpace(filepath.ToSlash(r.Dir))
+	if path.IsAbs(dir) || filepath.IsAbs(r.Dir) {
+		return fmt.Errorf("diretório '%s' deve ser relativo ao diretório monitorado", r.Dir)
+	}
+	if dir = path.Clean(dir); dir == "." {
+		dir = ""
+	}
+	if dir == ".." || strings.HasPrefix(dir, "../") {
+		return fmt.Errorf("diretório '%s' fora do diretório monitorado", r.Dir)
+	}
+	r.Dir = dir
+
+	if err := r.Filter.Compile(); err != nil {
+		return fmt.Errorf("rota '%s': %w", r, err)
+	}
+	if r.CredentialsFile != "" && r.TokenFile == "" {
+		return fmt.Errorf("rota '%s': token_file é obrigatório com credentials_file", r)
+	}
+	return nil
+}
+
+// ParseRoutes decodifica e valida a lista de rotas em JSON.
+func ParseRoutes(data []byte) ([]Route, error) {
+	var routes []Route
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("decodificar rotas falhou: %w", err)
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("nenhuma rota definida")
+	}
+
+	for i := range routes {
+		if err := routes[i].validate(); err != nil {
+			return nil, fmt.Errorf("rota %d: %w", i+1, err)
+		}
+	}
+	return routes, nil
+}
+
+// LoadRoutes lê as rotas do arquivo JSON em path.
+func LoadRoutes(path string) ([]Route, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ler arquivo de rotas %s falhou: %w", path, err)
+	}
+	routes, err := ParseRoutes(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return routes, nil
+}
+
+// matchRoute retorna a rota do backup de caminho relativo rel (separado por
+// "/") e tamanho size, ou nil se nenhuma rota o seleciona. Entre as rotas que
+// o selecionam vence a de diretório mais específico e, entre estas, a primeira.
+func matchRoute(routes []Route, rel string, size int64) *Route {
+	var best *Route
+	for i := range routes {
+		r := &routes[i]
+		sub := rel
+		if r.Dir != "" {
+			var ok bool
+			if sub, ok = strings.CutPrefix(rel, r.Dir+"/"); !ok {
+				continue
+			}
+		}
+		if !r.Filter.Match(patternPath(sub)) || !r.Filter.MatchSize(size) {
+			continue
+		}
+		if best == nil || len(r.Dir) > len(best.Dir) {
+			best = r
+		}
+	}
+	return best
+}
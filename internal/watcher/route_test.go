@@ -0,0 +1,80 @@
+package watcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes([]byte(`[
+		{"dir": "clinicaA/", "folder": "Clínica A"},
+		{"dir": "./regional/clinicaB", "credentials_file": "b.json", "token_file": "b-token.json"},
+		{"include": ["*.bak.zst", "re:^sql/"], "exclude": ["~$*"], "min_size": "1K", "max_size": "2G", "folder": "SQL"}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, routes, 3)
+	assert.Equal(t, "clinicaA", routes[0].Dir)
+	assert.Equal(t, "Clínica A", routes[0].Folder)
+	assert.Equal(t, "regional/clinicaB", routes[1].Dir)
+	assert.Equal(t, "", routes[2].Dir)
+	assert.Equal(t, ".", routes[2].String())
+	assert.Equal(t, []string{"*.bak.zst", "re:^sql/"}, routes[2].Include)
+	assert.Equal(t, int64(1<<10), routes[2].min)
+	assert.Equal(t, int64(2<<30), routes[2].max)
+}
+
+func TestParseRoutes_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"json":      `{`,
+		"vazio":     `[]`,
+		"absoluto":  `[{"dir": "/srv/backups"}]`,
+		"fora":      `[{"dir": "../outro"}]`,
+		"padrão":    `[{"include": ["[abc"]}]`,
+		"regex":     `[{"exclude": ["re:(abc"]}]`,
+		"tamanho":   `[{"min_size": "muito"}]`,
+		"mín > máx": `[{"min_size": "2G", "max_size": "1G"}]`,
+		"sem token": `[{"dir": "a", "credentials_file": "a.json"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseRoutes([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	routes := []Route{{Dir: "clinicaA"}, {Dir: "clinicaA/laudos"}, {Dir: "clinicaB"}}
+
+	assert.Equal(t, "clinicaA", matchRoute(routes, "clinicaA/SCM.zip", 0).Dir)
+	assert.Equal(t, "clinicaA", matchRoute(routes, "clinicaA/2025/SCM.zip", 0).Dir)
+	assert.Equal(t, "clinicaA/laudos", matchRoute(routes, "clinicaA/laudos/L.zip", 0).Dir)
+	assert.Nil(t, matchRoute(routes, "SCM.zip", 0))
+	assert.Nil(t, matchRoute(routes, "clinicaAB/SCM.zip", 0))
+}
+
+func TestMatchRoute_Filters(t *testing.T) {
+	routes, err := ParseRoutes([]byte(`[
+		{"include": ["*.bak.zst"], "folder": "sql"},
+		{"include": ["*.zip"], "min_size": "1M", "folder": "grandes"},
+		{"dir": "clinicaA", "include": ["docs/*"], "folder": "docs"},
+		{"dir": "clinicaA", "folder": "clinicaA"}
+	]`))
+	require.NoError(t, err)
+	folder := func(rel string, size int64) string {
+		if r := matchRoute(routes, rel, size); r != nil {
+			return r.Folder
+		}
+		return ""
+	}
+
+	assert.Equal(t, "sql", folder("SCM.bak.zst", 0))
+	assert.Equal(t, "sql", folder("outros/SCM.bak.zst", 0))
+	assert.Equal(t, "grandes", folder("SCM.zip", 2<<20))
+	assert.Equal(t, "grandes", folder("SCM.zip.volumes.json", 2<<20), "manifesto de volumes comparado como o arquivo lógico")
+	assert.Equal(t, "", folder("SCM.zip", 10))
+	// Diretório mais específico vence; padrões relativos ao diretório da rota
+	assert.Equal(t, "docs", folder("clinicaA/docs/L.zip", 0))
+	assert.Equal(t, "clinicaA", folder("clinicaA/SCM.bak.zst", 0))
+}
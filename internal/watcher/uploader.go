@@ -7,3 +7,9 @@ import "context"
 type Uploader interface {
 	UploadFile(ctx context.Context, filePath string) error
 }
+
+// SpaceChecker é implementado por uploaders capazes de verificar, antes do
+// envio, se o destino comporta size bytes.
+type SpaceChecker interface {
+	CheckSpace(ctx context.Context, size int64) error
+}
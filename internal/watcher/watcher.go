@@ -6,118 +6,246 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/manifest"
 )
 
-// FolderWatcher monitora um diretório por eventos de criação de arquivos.
+// Modos de monitoramento (flag -watch-mode).
+const (
+	ModeNotify = "notify" // Eventos do sistema operacional (fsnotify)
+	ModePoll   = "poll"   // Listagem periódica, para compartilhamentos de rede (SMB/NFS)
+)
+
+// Modes lista os modos aceitos.
+var Modes = []string{ModeNotify, ModePoll}
+
+// Source é a origem dos eventos do FolderWatcher.
+type Source interface {
+	// Run monitora o diretório até ctx ser cancelado, chamando emit com o
+	// caminho de cada arquivo novo (ou alterado, no modo poll). Os arquivos já
+	// existentes no início não são informados.
+	Run(ctx context.Context, emit func(path string)) error
+}
+
+// Options configura o FolderWatcher.
+type Options struct {
+	Mode         string        // notify (padrão) ou poll
+	PollInterval time.Duration // Intervalo entre as listagens no modo poll
+	Recursive    bool          // Monitora também os subdiretórios de WatchDir, inclusive os criados depois do início
+	Routes       []Route       // Destinos por subdiretório e padrão; os arquivos fora das rotas vão para o uploader padrão
+	Filter       Filter        // Arquivos enviados (já compilado); sem Include, os arquivos com as extensões do dbbackup
+}
+
+// FolderWatcher monitora um diretório, pelos eventos do sistema ou por
+// listagem periódica (Source), e envia os backups criados nele.
 type FolderWatcher struct {
 	logger   *slog.Logger
 	uploader Uploader // Depende da interface, não da implementação concreta
 	watchDir string
+	root     string // watchDir absoluto, base dos caminhos relativos das rotas
+	opts     Options
+	source   Source
+	settle   time.Duration // Espera antes do envio, para o arquivo terminar de ser gravado
+
+	mu      sync.Mutex
+	pending map[string]bool // Arquivos com envio em andamento
 }
 
 // NewFolderWatcher cria uma nova instância do monitor de pastas.
-func NewFolderWatcher(logger *slog.Logger, uploader Uploader, watchDir string) *FolderWatcher {
+func NewFolderWatcher(logger *slog.Logger, uploader Uploader, watchDir string, opts Options) *FolderWatcher {
+	root, err := filepath.Abs(watchDir)
+	if err != nil {
+		root = filepath.Clean(watchDir)
+	}
+	if opts.Mode == "" {
+		opts.Mode = ModeNotify
+	}
+	logger = logger.With(slog.String("component", "FolderWatcher"))
+
+	var source Source
+	if opts.Mode == ModePoll {
+		source = newPollSource(logger, watchDir, opts.Recursive, opts.PollInterval)
+	} else {
+		source = &notifySource{logger: logger, dir: watchDir, recursive: opts.Recursive}
+	}
+
 	return &FolderWatcher{
-		logger:   logger.With(slog.String("component", "FolderWatcher")),
+		logger:   logger,
 		uploader: uploader,
 		watchDir: watchDir,
+		root:     root,
+		opts:     opts,
+		source:   source,
+		settle:   2 * time.Second,
+		pending:  make(map[string]bool),
 	}
 }
 
 // Run inicia o processo de monitoramento e bloqueia até que o contexto seja cancelado.
 func (fw *FolderWatcher) Run(ctx context.Context) error {
-	fw.logger.Info("Iniciando monitoramento", slog.String("directory", fw.watchDir))
+	fw.logger.Info("Iniciando monitoramento", slog.String("directory", fw.watchDir), slog.String("mode", fw.opts.Mode))
+	return fw.source.Run(ctx, func(path string) {
+		fw.dispatch(ctx, path)
+	})
+}
 
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		fw.logger.Error("Falha ao criar watcher fsnotify", slog.Any("error", err))
-		return fmt.Errorf("criar watcher falhou: %w", err)
+// dispatch inicia em uma goroutine o envio de filePath, se for um arquivo de
+// backup selecionado pelo filtro que ainda não está sendo enviado.
+func (fw *FolderWatcher) dispatch(ctx context.Context, filePath string) {
+	cleanPath, absErr := filepath.Abs(filepath.Clean(filePath))
+	if absErr != nil {
+		fw.logger.Error("Erro ao obter caminho absoluto", slog.String("raw_path", filePath), slog.Any("error", absErr))
+		cleanPath = filePath // Tenta continuar mesmo assim
 	}
-	defer func() {
-		fw.logger.Debug("Fechando watcher fsnotify...")
-		if err := watcher.Close(); err != nil {
-			fw.logger.Error("Erro ao fechar watcher fsnotify", slog.Any("error", err))
-		} else {
-			fw.logger.Info("Watcher fsnotify fechado.")
-		}
-	}()
 
-	eventLoopDone := make(chan struct{})
+	if !fw.selected(fw.rel(cleanPath)) {
+		fw.logger.Debug("Evento de criação ignorado (não selecionado pelo filtro)", slog.String("path", filePath), slog.String("ext", filepath.Ext(filePath)))
+		return
+	}
+
+	fw.mu.Lock()
+	if fw.pending[cleanPath] {
+		fw.mu.Unlock()
+		fw.logger.Debug("Envio já em andamento", slog.String("path", cleanPath))
+		return
+	}
+	fw.pending[cleanPath] = true
+	fw.mu.Unlock()
+
+	fw.logger.Info("Novo arquivo de backup detectado", slog.String("path", filePath))
+	// Lança upload em goroutine separada
 	go func() {
-		defer close(eventLoopDone)
-		for {
-			select {
-			case event, ok := <-watcher.Events:
-				if !ok {
-					fw.logger.Info("Canal de eventos do watcher fechado.")
-					return
-				}
-				// Usar event.Has() é mais robusto para operações combinadas
-				// Vamos focar na CRIAÇÃO de arquivos .zip
-				if event.Has(fsnotify.Create) { // Verificar evento de CRIAÇÃO
-					filePath := event.Name
-					fileExt := filepath.Ext(filePath)
-
-					// Processar SOMENTE se for um arquivo .zip
-					if fileExt == ".zip" {
-						fw.logger.Info("Novo arquivo .zip detectado", slog.String("path", filePath))
-
-						cleanPath, absErr := filepath.Abs(filepath.Clean(filePath))
-						if absErr != nil {
-							fw.logger.Error("Erro ao obter caminho absoluto", slog.String("raw_path", filePath), slog.Any("error", absErr))
-							cleanPath = filePath // Tenta continuar mesmo assim
-						}
-
-						// Lança upload em goroutine separada
-						go fw.handleUpload(ctx, cleanPath)
-					} else {
-						fw.logger.Debug("Evento de criação ignorado (não é .zip)", slog.String("path", filePath), slog.String("ext", fileExt))
-					}
-				} else {
-					fw.logger.Debug("Evento fsnotify ignorado (não é Create)", slog.String("path", event.Name), slog.String("op", event.Op.String()))
-				}
-
-			case err, ok := <-watcher.Errors:
-				if !ok {
-					fw.logger.Info("Canal de erros do watcher fechado.")
-					return
-				}
-				fw.logger.Error("Erro no watcher fsnotify", slog.Any("error", err))
-				// Poderia ter lógica para tentar re-adicionar o watch ou parar dependendo do erro
-
-			case <-ctx.Done():
-				fw.logger.Info("Recebido sinal de cancelamento. Encerrando loop de eventos do watcher.")
-				return
-			}
-		}
+		defer func() {
+			fw.mu.Lock()
+			delete(fw.pending, cleanPath)
+			fw.mu.Unlock()
+		}()
+		fw.handleUpload(ctx, cleanPath)
 	}()
+}
 
-	fw.logger.Debug("Adicionando diretório ao watcher", slog.String("directory", fw.watchDir))
-	err = watcher.Add(fw.watchDir)
+// rel retorna o caminho de filePath relativo a WatchDir, separado por "/".
+func (fw *FolderWatcher) rel(filePath string) string {
+	rel, err := filepath.Rel(fw.root, filePath)
 	if err != nil {
-		fw.logger.Error("Falha ao adicionar diretório ao watcher", slog.String("directory", fw.watchDir), slog.Any("error", err))
-		// Não precisa cancelar o contexto aqui, o retorno do erro fará main sair
-		return fmt.Errorf("adicionar %s ao watcher falhou: %w", fw.watchDir, err)
+		return filepath.Base(filePath)
 	}
-	fw.logger.Info("Monitoramento iniciado com sucesso.", slog.String("directory", fw.watchDir))
+	return filepath.ToSlash(rel)
+}
 
-	// Aguarda o contexto ser cancelado (shutdown) ou o loop de eventos terminar
-	select {
-	case <-ctx.Done():
-		fw.logger.Info("Sinal de shutdown recebido. Aguardando loop de eventos terminar...")
-	case <-eventLoopDone:
-		// Isso não deveria acontecer a menos que os canais do watcher fechem inesperadamente
-		fw.logger.Warn("Loop de eventos do watcher terminou inesperadamente.")
+// checkContained confere que cada arquivo, com os links simbólicos
+// resolvidos, fica dentro do diretório monitorado.
+func (fw *FolderWatcher) checkContained(files []string) error {
+	root, err := filepath.EvalSymlinks(fw.root)
+	if err != nil {
+		return fmt.Errorf("resolver diretório monitorado %s falhou: %w", fw.root, err)
 	}
+	for _, f := range files {
+		real, err := filepath.EvalSymlinks(f)
+		if err != nil {
+			return fmt.Errorf("resolver %s falhou: %w", f, err)
+		}
+		rel, err := filepath.Rel(root, real)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+			return fmt.Errorf("%s aponta para %s, fora de %s", f, real, fw.root)
+		}
+	}
+	return nil
+}
+
+// selected indica se o arquivo de caminho relativo rel deve ser enviado. Sem
+// padrões de inclusão, são os arquivos gerados pelo dbbackup (.zip, .zst, .gz,
+// .tar, .bak) e os manifestos de volumes. As partes de volumes e os sidecars
+// nunca são selecionados: seguem junto com o backup a que pertencem, e os
+// volumes só quando o manifesto indica que o conjunto está completo.
+func (fw *FolderWatcher) selected(rel string) bool {
+	if archive.IsVolumePart(rel) || manifest.IsSidecar(rel) || isLockFile(rel) {
+		return false
+	}
+	f := &fw.opts.Filter
+	if len(f.Include) == 0 {
+		return (isBackupFile(rel) || archive.IsVolumeManifest(rel)) && !f.Excluded(patternPath(rel))
+	}
+	return f.Match(patternPath(rel))
+}
 
-	// Espera a goroutine do loop realmente terminar antes de retornar
-	<-eventLoopDone
-	fw.logger.Info("Processo de monitoramento finalizado.")
-	// Retorna o erro do contexto se foi cancelado, ou nil se saiu de outra forma (improvável)
-	return ctx.Err()
+// isLockFile indica se rel é um arquivo de lock do dbbackup (-lock-dir
+// apontando para o diretório monitorado), que nunca é enviado.
+func isLockFile(rel string) bool {
+	return strings.HasSuffix(rel, ".lock") || strings.HasSuffix(rel, ".lock.break")
+}
+
+// backupSize retorna o tamanho do backup formado por files, sem os sidecars.
+func backupSize(files []string) (int64, error) {
+	var total int64
+	for _, f := range files {
+		if manifest.IsSidecar(f) {
+			continue
+		}
+		info, err := os.Stat(f)
+		if err != nil {
+			return 0, fmt.Errorf("consultar tamanho de %s falhou: %w", f, err)
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// isBackupFile verifica se o arquivo tem uma das extensões produzidas pelo dbbackup.
+func isBackupFile(filePath string) bool {
+	return slices.Contains(archive.Extensions, strings.ToLower(filepath.Ext(filePath)))
+}
+
+// backupFiles retorna os arquivos que compõem o backup lógico de filePath. Para
+// um manifesto de volumes são todos os volumes do conjunto seguidos do próprio
+// manifesto; para os demais arquivos, apenas o próprio arquivo. Em ambos os
+// casos o manifesto sidecar (se existir) é enviado por último.
+func backupFiles(filePath string) ([]string, error) {
+	files := []string{filePath}
+	if archive.IsVolumeManifest(filePath) {
+		set, err := archive.ReadVolumeManifest(filePath)
+		if err != nil {
+			return nil, err
+		}
+		paths, err := archive.VolumePaths(filePath, set)
+		if err != nil {
+			return nil, err
+		}
+		files = append(paths, filePath)
+	}
+
+	if sidecar := manifest.SidecarFor(filePath); fileExists(sidecar) {
+		files = append(files, sidecar)
+	}
+	return files, nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// checkSpace verifica se o destino comporta files, quando o uploader oferece
+// essa verificação.
+func checkSpace(ctx context.Context, uploader Uploader, files []string) error {
+	checker, ok := uploader.(SpaceChecker)
+	if !ok {
+		return nil
+	}
+	var total int64
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return fmt.Errorf("consultar tamanho de %s falhou: %w", f, err)
+		}
+		total += info.Size()
+	}
+	return checker.CheckSpace(ctx, total)
 }
 
 // handleUpload é chamado em uma goroutine separada para fazer upload de um arquivo.
@@ -127,7 +255,7 @@ func (fw *FolderWatcher) handleUpload(ctx context.Context, filePath string) {
 	uploadLogger.Debug("Iniciando processamento de upload")
 
 	// Delay opcional - útil se arquivos são criados e escritos em etapas
-	time.Sleep(2 * time.Second)
+	time.Sleep(fw.settle)
 
 	// Verifica se o contexto já foi cancelado antes de tentar o upload
 	if err := ctx.Err(); err != nil {
@@ -135,7 +263,45 @@ func (fw *FolderWatcher) handleUpload(ctx context.Context, filePath string) {
 		return
 	}
 
-	err := fw.uploader.UploadFile(ctx, filePath)
+	files, err := backupFiles(filePath)
+	if err != nil {
+		uploadLogger.Error("Conjunto de volumes inválido ou incompleto", slog.Any("error", err))
+		return
+	}
+	// Os arquivos são enviados e depois removidos: nunca fora de WatchDir
+	if err := fw.checkContained(files); err != nil {
+		uploadLogger.Error("Arquivo fora do diretório monitorado; upload não iniciado e arquivos mantidos", slog.Any("error", err))
+		return
+	}
+
+	// O tamanho só é conhecido depois que o arquivo termina de ser gravado
+	size, err := backupSize(files)
+	if err != nil {
+		uploadLogger.Error("Falha ao consultar tamanho do backup", slog.Any("error", err))
+		return
+	}
+	if !fw.opts.Filter.MatchSize(size) {
+		uploadLogger.Info("Backup ignorado pelo filtro de tamanho; arquivos mantidos", slog.Int64("size", size))
+		return
+	}
+
+	uploader := fw.uploader
+	if r := matchRoute(fw.opts.Routes, fw.rel(filePath), size); r != nil && r.Uploader != nil {
+		uploader = r.Uploader
+		uploadLogger = uploadLogger.With(slog.String("route", r.String()))
+	}
+
+	// Falha antes do envio, e não no meio de um conjunto de volumes
+	if err := checkSpace(ctx, uploader, files); err != nil {
+		uploadLogger.Error("Espaço insuficiente no destino; upload não iniciado e arquivos mantidos", slog.Any("error", err))
+		return
+	}
+
+	for _, f := range files {
+		if err = uploader.UploadFile(ctx, f); err != nil {
+			break
+		}
+	}
 	if err != nil {
 		// Erro já logado dentro de UploadFile (ou será logado se for de contexto)
 		// Apenas loga a falha geral aqui
@@ -144,50 +310,28 @@ func (fw *FolderWatcher) handleUpload(ctx context.Context, filePath string) {
 	} else {
 		uploadLogger.Info("Upload concluído com sucesso")
 
-		// Obter o diretório onde o arquivo está
-		dirPath := filepath.Dir(filePath)
-		uploadLogger.Info("Iniciando limpeza pós-upload", slog.String("directory_to_clean", dirPath))
-
-		// Chamar a função auxiliar para deletar os arquivos nesse diretório
-		// Passa o logger principal ou o uploadLogger, dependendo do nível de detalhe desejado nos logs de exclusão
-		deleteAllFilesInDir(dirPath, fw.logger)
+		// Remove apenas os arquivos enviados (backup, volumes e manifestos): os
+		// demais arquivos do diretório (excluídos pelos filtros, rejeitados
+		// pelo tamanho ou pela cota, volumes ainda em gravação) são mantidos
+		uploadLogger.Info("Iniciando limpeza pós-upload", slog.Int("files", len(files)))
+		deleteFiles(files, uploadLogger)
 	}
 }
 
-// deleteAllFilesInDir é uma função auxiliar para limpar os arquivos de um diretório.
-func deleteAllFilesInDir(dirPath string, logger *slog.Logger) {
-	logger = logger.With(slog.String("directory", dirPath)) // Adiciona contexto do diretório ao logger
-	logger.Info("Iniciando tentativa de exclusão de todos os arquivos no diretório")
-
-	// Lê todas as entradas (arquivos e subdiretórios) no diretório especificado
-	entries, err := os.ReadDir(dirPath)
-	if err != nil {
-		logger.Error("Falha ao listar conteúdo do diretório para exclusão", slog.Any("error", err))
-		return // Não podemos prosseguir se não conseguirmos ler o diretório
-	}
-
+// deleteFiles remove os arquivos enviados.
+func deleteFiles(files []string, logger *slog.Logger) {
 	deletedCount := 0
 	errorCount := 0
 
-	for _, entry := range entries {
-		// Constrói o caminho completo para a entrada atual
-		fullPath := filepath.Join(dirPath, entry.Name())
-
-		// Verifica se a entrada NÃO é um diretório (ou seja, é um arquivo)
-		if !entry.IsDir() {
-			logger.Debug("Tentando excluir arquivo", slog.String("file", fullPath))
-			err := os.Remove(fullPath)
-			if err != nil {
-				// Loga o erro mas continua tentando excluir outros arquivos
-				logger.Warn("Falha ao excluir arquivo", slog.String("file", fullPath), slog.Any("error", err))
-				errorCount++
-			} else {
-				logger.Info("Arquivo excluído com sucesso", slog.String("file", fullPath))
-				deletedCount++
-			}
+	for _, f := range files {
+		logger.Debug("Tentando excluir arquivo", slog.String("file", f))
+		if err := os.Remove(f); err != nil {
+			// Loga o erro mas continua tentando excluir outros arquivos
+			logger.Warn("Falha ao excluir arquivo", slog.String("file", f), slog.Any("error", err))
+			errorCount++
 		} else {
-			// Apenas loga que está pulando um subdiretório
-			logger.Debug("Ignorando subdiretório durante a exclusão", slog.String("subdir", fullPath))
+			logger.Info("Arquivo excluído com sucesso", slog.String("file", f))
+			deletedCount++
 		}
 	}
 
@@ -0,0 +1,259 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/V1ctorW1ll1an/MaisSaudeBackup/internal/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsBackupFile(t *testing.T) {
+	assert.True(t, isBackupFile("/backups/SCM_20250407_164500.zip"))
+	assert.True(t, isBackupFile("/backups/SCM_20250407_164500.bak.zst"))
+	assert.True(t, isBackupFile("/backups/SCM_20250407_164500.tar.gz"))
+	assert.True(t, isBackupFile("/backups/SCM_20250407_164500.BAK"))
+	assert.False(t, isBackupFile("/backups/SCM_20250407_164500.tmp"))
+	assert.False(t, isBackupFile("/backups/SCM_20250407_164500.zip.001"))
+}
+
+func TestBackupFiles_VolumeSet(t *testing.T) {
+	dir := t.TempDir()
+
+	vw, err := archive.NewVolumeWriter(dir, "SCM.zip", 4)
+	require.NoError(t, err)
+	_, err = vw.Write([]byte("abcdefghij"))
+	require.NoError(t, err)
+	set, err := vw.Close()
+	require.NoError(t, err)
+	manifestPath, err := archive.WriteVolumeManifest(dir, set)
+	require.NoError(t, err)
+
+	files, err := backupFiles(manifestPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "SCM.zip.001"),
+		filepath.Join(dir, "SCM.zip.002"),
+		filepath.Join(dir, "SCM.zip.003"),
+		manifestPath,
+	}, files)
+
+	// Conjunto incompleto não deve ser enviado
+	require.NoError(t, os.Remove(filepath.Join(dir, "SCM.zip.002")))
+	_, err = backupFiles(manifestPath)
+	assert.Error(t, err)
+}
+
+func TestBackupFiles_SingleFile(t *testing.T) {
+	files, err := backupFiles("/backups/SCM.zip")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/backups/SCM.zip"}, files)
+}
+
+func TestBackupFiles_IncludesSidecar(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "SCM.zip")
+	sidecarPath := filepath.Join(dir, "SCM.zip.manifest.json")
+	require.NoError(t, os.WriteFile(archivePath, []byte("zip"), 0640))
+	require.NoError(t, os.WriteFile(sidecarPath, []byte("{}"), 0640))
+
+	files, err := backupFiles(archivePath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{archivePath, sidecarPath}, files)
+}
+
+type quotaUploader struct {
+	free    int64
+	checked int64
+}
+
+func (u *quotaUploader) UploadFile(ctx context.Context, filePath string) error { return nil }
+
+func (u *quotaUploader) CheckSpace(ctx context.Context, size int64) error {
+	u.checked = size
+	if size > u.free {
+		return errors.New("cota excedida")
+	}
+	return nil
+}
+
+func TestCheckSpace(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "SCM.zip"), filepath.Join(dir, "SCM.zip.manifest.json")}
+	require.NoError(t, os.WriteFile(files[0], []byte("0123456789"), 0640))
+	require.NoError(t, os.WriteFile(files[1], []byte("{}"), 0640))
+
+	uploader := &quotaUploader{free: 12}
+	require.NoError(t, checkSpace(context.Background(), uploader, files))
+	assert.Equal(t, int64(12), uploader.checked)
+
+	uploader.free = 11
+	assert.Error(t, checkSpace(context.Background(), uploader, files))
+}
+
+// recordingUploader registra os arquivos enviados.
+type recordingUploader struct {
+	uploaded chan string
+}
+
+func (u *recordingUploader) UploadFile(ctx context.Context, filePath string) error {
+	u.uploaded <- filePath
+	return nil
+}
+
+func waitUpload(t *testing.T, u *recordingUploader) string {
+	t.Helper()
+	select {
+	case f := <-u.uploaded:
+		return f
+	case <-time.After(5 * time.Second):
+		t.Fatal("nenhum arquivo enviado")
+		return ""
+	}
+}
+
+func TestRun_RecursiveRoutes(t *testing.T) {
+	for _, mode := range Modes {
+		t.Run(mode, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.Mkdir(filepath.Join(dir, "existente"), 0750))
+
+			def := &recordingUploader{uploaded: make(chan string, 10)}
+			clinicaA := &recordingUploader{uploaded: make(chan string, 10)}
+			fw := NewFolderWatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), def, dir, Options{
+				Mode:         mode,
+				PollInterval: 20 * time.Millisecond,
+				Recursive:    true,
+				Routes:       []Route{{Dir: "clinicaA", Uploader: clinicaA}},
+			})
+			fw.settle = 0
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- fw.Run(ctx) }()
+			defer func() {
+				cancel()
+				<-done
+			}()
+			time.Sleep(200 * time.Millisecond) // Watches adicionados ou primeira listagem feita
+
+			// Subdiretório existente no início
+			existing := filepath.Join(dir, "existente", "SCM.zip")
+			require.NoError(t, os.WriteFile(existing, []byte("zip"), 0640))
+			assert.Equal(t, existing, waitUpload(t, def))
+
+			// Subdiretórios criados depois do início, em vários níveis, vão para a rota
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "clinicaA", "2025"), 0750))
+			time.Sleep(200 * time.Millisecond)
+			routed := filepath.Join(dir, "clinicaA", "2025", "APP.tar.zst")
+			require.NoError(t, os.WriteFile(routed, []byte("zst"), 0640))
+			assert.Equal(t, routed, waitUpload(t, clinicaA))
+			assert.Empty(t, def.uploaded)
+		})
+	}
+}
+
+func TestHandleUpload_SizeFilter(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "SCM.zip")
+	require.NoError(t, os.WriteFile(small, []byte("zip"), 0640))
+
+	filter := Filter{MinSize: "1K"}
+	require.NoError(t, filter.Compile())
+	u := &recordingUploader{uploaded: make(chan string, 1)}
+	fw := NewFolderWatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), u, dir, Options{Filter: filter})
+	fw.settle = 0
+
+	fw.handleUpload(context.Background(), small)
+	assert.Empty(t, u.uploaded)
+	assert.FileExists(t, small, "arquivo ignorado é mantido")
+
+	require.NoError(t, os.WriteFile(small, make([]byte, 1024), 0640))
+	fw.handleUpload(context.Background(), small)
+	assert.Equal(t, small, waitUpload(t, u))
+}
+
+func TestHandleUpload_DeletesOnlyUploaded(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) string {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(p, make([]byte, size), 0640))
+		return p
+	}
+	uploaded := write("SCM.zip", 2048)
+	sidecar := write("SCM.zip.manifest.json", 2)
+	excluded := write("SCM.zip.tmp", 2048)
+	undersized := write("APP.zip", 10)
+	inProgress := write("OUTRO.zip.001", 2048)
+
+	filter := Filter{Include: []string{"*.zip"}, Exclude: []string{"*.tmp"}, MinSize: "1K"}
+	require.NoError(t, filter.Compile())
+	u := &recordingUploader{uploaded: make(chan string, 10)}
+	fw := NewFolderWatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), u, dir, Options{Filter: filter})
+	fw.settle = 0
+
+	fw.handleUpload(context.Background(), undersized)
+	fw.handleUpload(context.Background(), uploaded)
+	assert.Equal(t, uploaded, waitUpload(t, u))
+	assert.Equal(t, sidecar, waitUpload(t, u))
+	assert.Empty(t, u.uploaded)
+
+	assert.NoFileExists(t, uploaded)
+	assert.NoFileExists(t, sidecar)
+	assert.FileExists(t, excluded, "arquivo excluído pelo filtro é mantido")
+	assert.FileExists(t, undersized, "backup abaixo de -min-size é mantido")
+	assert.FileExists(t, inProgress, "volume de conjunto em gravação é mantido")
+}
+
+func TestHandleUpload_QuotaRejectedKept(t *testing.T) {
+	dir := t.TempDir()
+	large := filepath.Join(dir, "SCM.zip")
+	small := filepath.Join(dir, "APP.zip")
+	require.NoError(t, os.WriteFile(large, make([]byte, 100), 0640))
+	require.NoError(t, os.WriteFile(small, make([]byte, 5), 0640))
+
+	uploader := &quotaUploader{free: 10}
+	fw := NewFolderWatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), uploader, dir, Options{})
+	fw.settle = 0
+
+	fw.handleUpload(context.Background(), large)
+	fw.handleUpload(context.Background(), small)
+
+	assert.NoFileExists(t, small)
+	assert.FileExists(t, large, "backup recusado pela cota não é removido pela limpeza de outro envio")
+}
+
+func TestHandleUpload_OutsideWatchDir(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "segredo.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("abcd"), 0640))
+
+	// Volume que é um link para um arquivo fora do diretório monitorado
+	vw, err := archive.NewVolumeWriter(dir, "SCM.zip", 4)
+	require.NoError(t, err)
+	_, err = vw.Write([]byte("abcd"))
+	require.NoError(t, err)
+	set, err := vw.Close()
+	require.NoError(t, err)
+	manifestPath, err := archive.WriteVolumeManifest(dir, set)
+	require.NoError(t, err)
+	volume := filepath.Join(dir, "SCM.zip.001")
+	require.NoError(t, os.Remove(volume))
+	require.NoError(t, os.Symlink(outside, volume))
+
+	u := &recordingUploader{uploaded: make(chan string, 10)}
+	fw := NewFolderWatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), u, dir, Options{})
+	fw.settle = 0
+	fw.handleUpload(context.Background(), manifestPath)
+
+	assert.Empty(t, u.uploaded)
+	assert.FileExists(t, outside)
+	assert.FileExists(t, manifestPath)
+}